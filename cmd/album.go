@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(albumCmd)
+	albumCmd.AddCommand(albumSaveCmd)
+	albumCmd.AddCommand(albumUnsaveCmd)
+}
+
+var albumCmd = &cobra.Command{
+	Use:   "album",
+	Short: "Saves and unsaves albums",
+	Long:  `Saves albums to your library or removes them, the current track's album unless one is given`,
+}
+
+var albumSaveCmd = &cobra.Command{
+	Use:   "save [album id/uri/url]",
+	Short: "Saves the current track's album",
+	Long:  `Saves the current track's album, or the given album, to your library`,
+	Args:  cobra.MatchAll(cobra.MaximumNArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		album := ""
+		if len(args) > 0 {
+			album = args[0]
+		}
+		return commands.SaveAlbum(ctx, album, true)
+	},
+}
+
+var albumUnsaveCmd = &cobra.Command{
+	Use:   "unsave [album id/uri/url]",
+	Short: "Removes the current track's album",
+	Long:  `Removes the current track's album, or the given album, from your library`,
+	Args:  cobra.MatchAll(cobra.MaximumNArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		album := ""
+		if len(args) > 0 {
+			album = args[0]
+		}
+		return commands.SaveAlbum(ctx, album, false)
+	},
+}
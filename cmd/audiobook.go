@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(audiobookCmd)
+	audiobookCmd.AddCommand(audiobookListCmd)
+	audiobookCmd.AddCommand(audiobookChaptersCmd)
+	audiobookCmd.AddCommand(audiobookResumeCmd)
+}
+
+var audiobookCmd = &cobra.Command{
+	Use:     "audiobook",
+	Aliases: []string{"book"},
+	Short:   "Audiobooks and chapters",
+	Long:    `Lists saved audiobooks and their chapters and resumes them`,
+}
+
+var audiobookListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists your saved audiobooks",
+	Long:  `Lists your saved audiobooks with their authors and uri`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return commands.PrintAudiobooks(ctx)
+	},
+}
+
+var audiobookChaptersCmd = &cobra.Command{
+	Use:   "chapters {audiobook id/uri/url}",
+	Short: "Lists the chapters of an audiobook",
+	Long:  `Lists the chapters of an audiobook with their length and how much is left`,
+	Args:  cobra.MatchAll(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return commands.PrintChapters(ctx, args[0])
+	},
+}
+
+var audiobookResumeCmd = &cobra.Command{
+	Use:   "resume {audiobook or chapter id/uri/url}",
+	Short: "Continues an audiobook where you left off",
+	Long:  `Plays a chapter from its stored position, or the first chapter of an audiobook that was not finished`,
+	Args:  cobra.MatchAll(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return commands.ResumeAudiobook(ctx, args[0])
+	},
+}
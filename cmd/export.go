@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	cmds "git.asdf.cafe/abs3nt/gospt/src/commands"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportFormat string
+	exportOutput string
+)
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringVarP(&exportFormat, "format", "f", "", "json, csv, m3u or xspf, defaults to the output file extension")
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "file to write to, defaults to stdout")
+}
+
+var exportCmd = &cobra.Command{
+	Use:       "export {library/albums/artists/playlist} [playlist name/id/url]",
+	Short:     "Exports your library or a playlist",
+	Long:      `Exports saved tracks, saved albums, followed artists or a playlist to json, csv, m3u or xspf including isrc, duration and uri`,
+	Args:      cobra.MatchAll(cobra.RangeArgs(1, 2)),
+	ValidArgs: []string{"library", "albums", "artists", "playlist"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		playlist := ""
+		if len(args) > 1 {
+			playlist = args[1]
+		}
+		return commands.Export(ctx, cmds.ExportKind(args[0]), playlist, exportFormat, exportOutput)
+	},
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	cmds "git.asdf.cafe/abs3nt/gospt/src/commands"
+
+	"github.com/spf13/cobra"
+)
+
+var importOpts cmds.ImportOptions
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.Flags().StringVarP(&importOpts.Format, "format", "f", "", "json, csv, m3u or xspf, defaults to the file extension")
+	importCmd.Flags().StringVar(&importOpts.Name, "name", "", "name of the playlist to create, defaults to the name in the file")
+	importCmd.Flags().StringVar(&importOpts.Report, "report", "", "write tracks that couldn't be matched to this csv file")
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import {file}",
+	Short: "Imports a playlist from a file",
+	Long:  `Recreates a playlist from a json, csv, m3u or xspf export, matching tracks by uri, then isrc, then artist and title. Album and artist exports are saved to your library and followed instead`,
+	Args:  cobra.MatchAll(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return commands.Import(ctx, args[0], importOpts)
+	},
+}
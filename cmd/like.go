@@ -4,16 +4,22 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var likeToggle bool
+
 func init() {
 	rootCmd.AddCommand(likeCmd)
+	likeCmd.Flags().BoolVarP(&likeToggle, "toggle", "t", false, "unlike what is already liked")
 }
 
 var likeCmd = &cobra.Command{
-	Use:     "like",
+	Use:     "like [track/album/episode/show uri/url...]",
 	Aliases: []string{"l"},
 	Short:   "Likes song",
-	Long:    `Likes song`,
-	Run: func(cmd *cobra.Command, args []string) {
-		commands.Like(ctx)
+	Long:    `Likes the current song, or the given tracks, albums, episodes and shows. With --toggle liked items are unliked instead`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if likeToggle {
+			return commands.ToggleLike(ctx, args...)
+		}
+		return commands.Like(ctx, args...)
 	},
 }
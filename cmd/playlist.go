@@ -0,0 +1,16 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(playlistCmd)
+}
+
+var playlistCmd = &cobra.Command{
+	Use:     "playlist",
+	Aliases: []string{"pl"},
+	Short:   "Manage playlists",
+	Long:    `Tools to manage and clean up your playlists`,
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	cmds "git.asdf.cafe/abs3nt/gospt/src/commands"
+
+	"github.com/spf13/cobra"
+)
+
+var dedupeOpts cmds.DedupeOptions
+
+func init() {
+	playlistCmd.AddCommand(playlistDedupeCmd)
+	playlistDedupeCmd.Flags().BoolVar(&dedupeOpts.ISRC, "isrc", true, "remove the same recording released on different albums")
+	playlistDedupeCmd.Flags().BoolVar(&dedupeOpts.Local, "local", true, "remove local files")
+	playlistDedupeCmd.Flags().BoolVar(&dedupeOpts.Unplayable, "unplayable", true, "remove tracks that can't be played")
+	playlistDedupeCmd.Flags().BoolVarP(&dedupeOpts.DryRun, "dry-run", "n", false, "only show what would be removed")
+	playlistDedupeCmd.Flags().BoolVarP(&dedupeOpts.Yes, "yes", "y", false, "remove without asking for confirmation")
+}
+
+var playlistDedupeCmd = &cobra.Command{
+	Use:   "dedupe {playlist name/id/url}",
+	Short: "Removes duplicate and unplayable tracks from a playlist",
+	Long:  `Finds exact duplicates, the same recording on different albums (by ISRC), local files and unplayable items in a playlist, shows what would be removed and removes the entries you pick, by position, range or reason`,
+	Args:  cobra.MatchAll(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return commands.DedupePlaylist(ctx, args[0], dedupeOpts)
+	},
+}
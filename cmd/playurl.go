@@ -4,16 +4,19 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var playurlQueue bool
+
 func init() {
 	rootCmd.AddCommand(playurlCmd)
+	playurlCmd.Flags().BoolVarP(&playurlQueue, "queue", "q", false, "add to the queue instead of playing")
 }
 
 var playurlCmd = &cobra.Command{
-	Use:   "playurl",
-	Short: "Plays song from provided url",
+	Use:   "playurl {url/uri}",
+	Short: "Plays the track, album, playlist, artist or episode at the provided url",
 	Args:  cobra.MatchAll(cobra.ExactArgs(1)),
-	Long:  `Plays song from provided url`,
-	Run: func(cmd *cobra.Command, args []string) {
-		commands.PlayUrl(ctx, args)
+	Long:  `Plays albums, playlists, artists, shows and audiobooks from the start and tracks and episodes right away. Accepts open.spotify.com and spotify.link urls and spotify: uris`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return commands.PlayUrl(ctx, args[0], playurlQueue)
 	},
 }
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	cmds "git.asdf.cafe/abs3nt/gospt/src/commands"
+
+	"github.com/spf13/cobra"
+	"github.com/zmb3/spotify/v2"
+)
+
+var (
+	queueNext     bool
+	queueSpotify  bool
+	queueInterval time.Duration
+)
+
+func init() {
+	rootCmd.AddCommand(queueCmd)
+	queueCmd.AddCommand(queueAddCmd)
+	queueCmd.AddCommand(queueRemoveCmd)
+	queueCmd.AddCommand(queueMoveCmd)
+	queueCmd.AddCommand(queueClearCmd)
+	queueCmd.AddCommand(queueRunCmd)
+	queueAddCmd.Flags().BoolVarP(&queueNext, "next", "n", false, "play next instead of after the rest of the gospt queue")
+	queueAddCmd.Flags().BoolVarP(&queueSpotify, "spotify", "s", false, "add to the end of spotify's queue directly")
+	queueRunCmd.Flags().DurationVarP(&queueInterval, "interval", "i", 2*time.Second, "how often to poll the current playback")
+}
+
+var queueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Shows and manages the queue",
+	Long:  `Shows the current queue. gospt keeps its own queue that can be reordered, it is handed to spotify one track at a time while the TUI, gospt watch or gospt queue run is running`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return commands.PrintQueue(ctx)
+	},
+}
+
+var queueAddCmd = &cobra.Command{
+	Use:       "add {track/album/playlist/artist} {id/uri/url}",
+	Short:     "Queues a track, album, playlist or an artist's top tracks",
+	Long:      `Adds a track, every track of an album or playlist, or an artist's top tracks to the gospt queue, or with --spotify to spotify's queue`,
+	Args:      cobra.MatchAll(cobra.ExactArgs(2)),
+	ValidArgs: []string{"track", "album", "playlist", "artist"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pos := cmds.QueueLast
+		switch {
+		case queueSpotify && queueNext:
+			return fmt.Errorf("spotify's queue can only be appended to, --next needs the gospt queue")
+		case queueSpotify:
+			pos = cmds.QueueSpotify
+		case queueNext:
+			pos = cmds.QueueNext
+		}
+		count, err := commands.QueueCollection(ctx, args[0], args[1], pos)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Queued %d tracks\n", count)
+		return nil
+	},
+}
+
+func positionArgs(args []string) ([]int, error) {
+	positions := []int{}
+	for _, arg := range args {
+		pos, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid position %q", arg)
+		}
+		positions = append(positions, pos)
+	}
+	return positions, nil
+}
+
+var queueRemoveCmd = &cobra.Command{
+	Use:     "remove {position...}",
+	Aliases: []string{"rm"},
+	Short:   "Removes tracks from the gospt queue",
+	Long:    `Removes the tracks at the given positions as shown by gospt queue from the gospt queue`,
+	Args:    cobra.MatchAll(cobra.MinimumNArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		positions, err := positionArgs(args)
+		if err != nil {
+			return err
+		}
+		return commands.RemoveFromQueue(positions)
+	},
+}
+
+var queueMoveCmd = &cobra.Command{
+	Use:     "move {from} {to}",
+	Aliases: []string{"mv"},
+	Short:   "Moves a track in the gospt queue",
+	Long:    `Moves the track at one position of the gospt queue to another`,
+	Args:    cobra.MatchAll(cobra.ExactArgs(2)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		positions, err := positionArgs(args)
+		if err != nil {
+			return err
+		}
+		return commands.MoveInQueue(positions[0], positions[1])
+	},
+}
+
+var queueClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Empties the gospt queue",
+	Long:  `Removes every track from the gospt queue, spotify's queue is left alone`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return commands.ClearQueue()
+	},
+}
+
+var queueRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Feeds the gospt queue to spotify until interrupted",
+	Long:  `Hands the next track of the gospt queue to spotify shortly before the current track ends`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		feeder := commands.NewQueueFeeder(feederLead(queueInterval))
+		defer feeder.Close()
+		return commands.Watch(ctx, queueInterval, func(state *spotify.PlayerState) {
+			feeder.Update(ctx, state)
+		})
+	},
+}
+
+// feederLead gives the queue feeder at least two polls before a track ends.
+func feederLead(interval time.Duration) time.Duration {
+	return max(10*time.Second, 2*interval+time.Second)
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 
 	cmds "git.asdf.cafe/abs3nt/gospt/src/commands"
 
@@ -58,7 +60,15 @@ func init() {
 			zlog.SetGlobalLevel(zlog.TraceLevel)
 		}
 	})
-	ctx = gctx.NewContext(context.Background())
+	// commands that run until interrupted return when the context is
+	// cancelled so their cleanup runs, a second interrupt kills gospt as
+	// usual, which also ends prompts that do not watch the context
+	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signalCtx.Done()
+		stop()
+	}()
+	ctx = gctx.NewContext(signalCtx)
 	commands = &cmds.Commands{Context: ctx}
 	cobra.OnInitialize(initConfig)
 }
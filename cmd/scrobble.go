@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+
+	"git.asdf.cafe/abs3nt/gospt/src/config"
+	"git.asdf.cafe/abs3nt/gospt/src/scrobble"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(scrobbleCmd)
+	scrobbleCmd.AddCommand(scrobbleFlushCmd)
+	scrobbleCmd.AddCommand(scrobbleLastFMLoginCmd)
+}
+
+var scrobbleCmd = &cobra.Command{
+	Use:   "scrobble",
+	Short: "Submit listens to ListenBrainz or Last.fm",
+	Long:  `Submit listens to ListenBrainz or Last.fm compatible servers configured in the scrobble section of client.yml. The TUI and gospt watch scrobble automatically while they run`,
+}
+
+var scrobbleFlushCmd = &cobra.Command{
+	Use:   "flush",
+	Short: "Retries queued scrobbles",
+	Long:  `Resubmits listens that failed to be submitted earlier`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		scrobbler := scrobble.New(scrobble.FromConfig(), scrobble.DefaultQueuePath())
+		defer scrobbler.Close()
+		err := scrobbler.Flush(ctx)
+		fmt.Printf("%d listens still queued\n", scrobbler.Pending())
+		return err
+	},
+}
+
+var scrobbleLastFMLoginCmd = &cobra.Command{
+	Use:   "lastfm-login",
+	Short: "Gets a Last.fm session key",
+	Long:  `Authorizes gospt with Last.fm using lastfm_api_key and lastfm_secret and prints the session key to add to client.yml`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := config.Values.Scrobble
+		if cfg.LastFMAPIKey == "" || cfg.LastFMSecret == "" {
+			return fmt.Errorf("set lastfm_api_key and lastfm_secret in the scrobble section of %s first", cfgFile)
+		}
+		lastfm := &scrobble.LastFM{URL: cfg.LastFMURL, APIKey: cfg.LastFMAPIKey, Secret: cfg.LastFMSecret, HTTP: http.DefaultClient}
+		token, url, err := lastfm.Token(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Open this url, allow access and press enter:")
+		fmt.Println(url)
+		bufio.NewReader(os.Stdin).ReadString('\n')
+		key, err := lastfm.Session(ctx, token)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Add this to the scrobble section of %s:\n\n  lastfm_session_key: %q\n", cfgFile, key)
+		return nil
+	},
+}
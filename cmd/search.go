@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"strings"
+
+	cmds "git.asdf.cafe/abs3nt/gospt/src/commands"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	searchTypes   []string
+	searchJSON    bool
+	searchLimit   int
+	searchOffset  int
+	searchFilters cmds.SearchFilters
+)
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+	searchCmd.Flags().StringSliceVarP(&searchTypes, "type", "t", nil, "track, album, artist, playlist, show or episode, defaults to all")
+	searchCmd.Flags().BoolVar(&searchJSON, "json", false, "print the results as json")
+	searchCmd.Flags().IntVarP(&searchLimit, "limit", "l", 10, "results per type, at most 50")
+	searchCmd.Flags().IntVar(&searchOffset, "offset", 0, "skip this many results per type")
+	searchCmd.Flags().StringVar(&searchFilters.Artist, "artist", "", "only match this artist")
+	searchCmd.Flags().StringVar(&searchFilters.Album, "album", "", "only match this album")
+	searchCmd.Flags().StringVar(&searchFilters.Track, "track", "", "only match this track name")
+	searchCmd.Flags().StringVar(&searchFilters.Year, "year", "", "release year or range like 1990-1999")
+	searchCmd.Flags().StringVar(&searchFilters.Genre, "genre", "", "only match this genre")
+	searchCmd.Flags().StringVar(&searchFilters.ISRC, "isrc", "", "find a track by isrc")
+	searchCmd.Flags().StringVar(&searchFilters.UPC, "upc", "", "find an album by upc")
+	searchCmd.Flags().BoolVar(&searchFilters.New, "new", false, "only albums released in the last two weeks")
+	searchCmd.Flags().BoolVar(&searchFilters.Hipster, "hipster", false, "only albums with the lowest 10% popularity")
+}
+
+var searchCmd = &cobra.Command{
+	Use:   "search [query...]",
+	Short: "Searches spotify",
+	Long:  `Searches tracks, albums, artists, playlists, shows and episodes. The query can use spotify's field filters like artist:, year:, genre: and tag:new, and type:track,album to pick the types`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		types, err := cmds.ParseSearchTypes(searchTypes)
+		if err != nil {
+			return err
+		}
+		query, types, err := cmds.ParseSearch(searchFilters.Apply(strings.Join(args, " ")), types)
+		if err != nil {
+			return err
+		}
+		return commands.PrintSearch(ctx, query, types, searchLimit, searchOffset, searchJSON)
+	},
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/zmb3/spotify/v2"
+)
+
+var (
+	showEpisodeLimit int
+	showUnplayed     bool
+)
+
+func init() {
+	rootCmd.AddCommand(showCmd)
+	showCmd.AddCommand(showListCmd)
+	showCmd.AddCommand(showEpisodesCmd)
+	showCmd.AddCommand(showResumeCmd)
+	showCmd.AddCommand(showMarkCmd)
+	showEpisodesCmd.Flags().IntVarP(&showEpisodeLimit, "limit", "l", 20, "number of episodes to list")
+	showMarkCmd.Flags().BoolVarP(&showUnplayed, "unplayed", "u", false, "mark as unplayed instead")
+}
+
+var showCmd = &cobra.Command{
+	Use:     "show",
+	Aliases: []string{"podcast"},
+	Short:   "Podcasts and episodes",
+	Long:    `Lists saved shows and their episodes, resumes episodes and marks them as played`,
+}
+
+var showListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists your saved shows",
+	Long:  `Lists your saved shows with their publisher and uri`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return commands.PrintShows(ctx)
+	},
+}
+
+var showEpisodesCmd = &cobra.Command{
+	Use:   "episodes {show id/uri/url}",
+	Short: "Lists the latest episodes of a show",
+	Long:  `Lists the latest episodes of a show with their release date, length and how much is left`,
+	Args:  cobra.MatchAll(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return commands.PrintEpisodes(ctx, args[0], showEpisodeLimit)
+	},
+}
+
+var showResumeCmd = &cobra.Command{
+	Use:   "resume {episode id/uri/url}",
+	Short: "Plays an episode from where you left off",
+	Long:  `Plays an episode from its stored position, or from the start if it was played`,
+	Args:  cobra.MatchAll(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		episode, err := commands.ResolveEpisode(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		return commands.ResumeEpisode(ctx, episode)
+	},
+}
+
+var showMarkCmd = &cobra.Command{
+	Use:   "mark {episode id/uri/url...}",
+	Short: "Marks episodes as played",
+	Long:  `Marks episodes as played in gospt, played episodes are resumed from the start. Spotify's api can not mark episodes played on your account`,
+	Args:  cobra.MatchAll(cobra.MinimumNArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ids := []spotify.ID{}
+		for _, arg := range args {
+			episode, err := commands.ResolveEpisode(ctx, arg)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, episode.ID)
+		}
+		return commands.MarkPlayed(ids, !showUnplayed)
+	},
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(smartCmd)
+	smartCmd.AddCommand(smartSyncCmd)
+	smartCmd.AddCommand(smartListCmd)
+}
+
+var smartCmd = &cobra.Command{
+	Use:   "smart",
+	Short: "Manage rule based smart playlists",
+	Long:  `Manage rule based smart playlists defined in smart.yml next to client.yml`,
+}
+
+var smartSyncCmd = &cobra.Command{
+	Use:   "sync [name...]",
+	Short: "Writes smart playlists to spotify",
+	Long:  `Evaluates the rules of every smart playlist, or only the named ones, and replaces the contents of the matching spotify playlists`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return commands.SmartSync(ctx, args)
+	},
+}
+
+var smartListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists smart playlists",
+	Long:  `Lists the defined smart playlists and the spotify playlists they are synced to`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return commands.SmartList(ctx)
+	},
+}
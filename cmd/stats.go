@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var (
+	statsFrom  string
+	statsTo    string
+	statsSince string
+	statsLimit int
+	statsJSON  bool
+)
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+	statsCmd.Flags().StringVar(&statsFrom, "from", "", "first day of the report, YYYY-MM-DD")
+	statsCmd.Flags().StringVar(&statsTo, "to", "", "last day of the report, YYYY-MM-DD, defaults to today")
+	statsCmd.Flags().StringVarP(&statsSince, "since", "s", "", "report the last 7d, 4w, 12h etc. instead of --from")
+	statsCmd.Flags().IntVarP(&statsLimit, "limit", "l", 10, "number of top tracks, artists and albums")
+	statsCmd.Flags().BoolVar(&statsJSON, "json", false, "print the report as json")
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Shows statistics from your listening history",
+	Long:  `Shows top tracks, artists and albums, listening time per day and skip rate from the local listening history recorded by the TUI and gospt watch. Defaults to the last 30 days`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return commands.Stats(ctx, statsFrom, statsTo, statsSince, statsLimit, statsJSON)
+	},
+}
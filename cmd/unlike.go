@@ -9,11 +9,11 @@ func init() {
 }
 
 var unlikeCmd = &cobra.Command{
-	Use:     "unlike",
+	Use:     "unlike [track/album/episode/show uri/url...]",
 	Aliases: []string{"u"},
 	Short:   "unlikes song",
-	Long:    `unlikes song`,
-	Run: func(cmd *cobra.Command, args []string) {
-		commands.Unlike(ctx)
+	Long:    `unlikes the current song, or the given tracks, albums, episodes and shows`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return commands.Unlike(ctx, args...)
 	},
 }
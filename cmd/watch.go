@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"time"
+
+	"git.asdf.cafe/abs3nt/gospt/src/history"
+	"git.asdf.cafe/abs3nt/gospt/src/scrobble"
+
+	"github.com/spf13/cobra"
+	"github.com/zmb3/spotify/v2"
+)
+
+var watchInterval time.Duration
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+	watchCmd.Flags().DurationVarP(&watchInterval, "interval", "i", 5*time.Second, "how often to poll the current playback")
+}
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Records the current playback until interrupted",
+	Long:  `Polls the current playback, records every play to the local listening history, feeds the gospt queue and scrobbles it if a scrobble service is configured`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recorder, err := history.NewRecorder(history.DefaultPath())
+		if err != nil {
+			return err
+		}
+		defer recorder.Close()
+		scrobbler := scrobble.New(scrobble.FromConfig(), scrobble.DefaultQueuePath())
+		defer scrobbler.Close()
+		feeder := commands.NewQueueFeeder(feederLead(watchInterval))
+		defer feeder.Close()
+		return commands.Watch(ctx, watchInterval, func(state *spotify.PlayerState) {
+			recorder.Update(state)
+			feeder.Update(ctx, state)
+			if state == nil {
+				scrobbler.Update(ctx, nil)
+				return
+			}
+			scrobbler.Update(ctx, &state.CurrentlyPlaying)
+		})
+	},
+}
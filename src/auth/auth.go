@@ -62,6 +62,7 @@ func GetClient(ctx *gctx.Context) (*spotify.Client, error) {
 			spotifyauth.ScopeUserReadRecentlyPlayed,
 			spotifyauth.ScopeUserTopRead,
 			spotifyauth.ScopeStreaming,
+			"user-read-playback-position",
 		),
 	)
 	if _, err := os.Stat(filepath.Join(configDir, "gospt/auth.json")); err == nil {
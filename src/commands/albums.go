@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+
+	"git.asdf.cafe/abs3nt/gospt/src/gctx"
+)
+
+// AlbumTrack is a track of an album with whether it can be played in the
+// user's market.
+type AlbumTrack struct {
+	spotify.SimpleTrack
+	IsPlayable *bool `json:"is_playable"`
+}
+
+// AlbumDetails is a full album with its label and the playability of its
+// tracks, which the spotify library leaves out.
+type AlbumDetails struct {
+	spotify.FullAlbum
+	Label  string         `json:"label"`
+	Tracks AlbumTrackPage `json:"tracks"`
+}
+
+type AlbumTrackPage struct {
+	Tracks []AlbumTrack    `json:"items"`
+	Total  spotify.Numeric `json:"total"`
+}
+
+// Album gets an album with every one of its tracks.
+func (c *Commands) Album(ctx *gctx.Context, id spotify.ID) (*AlbumDetails, error) {
+	album := &AlbumDetails{}
+	query := url.Values{"market": {c.Market()}}
+	if err := c.api(ctx, http.MethodGet, "albums/"+string(id), query, nil, album); err != nil {
+		return nil, err
+	}
+	for page := 2; len(album.Tracks.Tracks) < int(album.Tracks.Total); page++ {
+		tracks := AlbumTrackPage{}
+		err := c.api(ctx, http.MethodGet, "albums/"+string(id)+"/tracks", c.pageQuery(page), nil, &tracks)
+		if err != nil {
+			return nil, err
+		}
+		if len(tracks.Tracks) == 0 {
+			break
+		}
+		album.Tracks.Tracks = append(album.Tracks.Tracks, tracks.Tracks...)
+	}
+	return album, nil
+}
+
+// Duration adds up the length of the album's tracks.
+func (album *AlbumDetails) Duration() time.Duration {
+	total := time.Duration(0)
+	for _, track := range album.Tracks.Tracks {
+		total += track.TimeDuration()
+	}
+	return total
+}
+
+// Summary describes the album's release for its page.
+func (album *AlbumDetails) Summary() string {
+	parts := []string{"released " + album.ReleaseDate}
+	if album.Label != "" {
+		parts = append(parts, album.Label)
+	}
+	parts = append(parts,
+		fmt.Sprintf("%d tracks", len(album.Tracks.Tracks)),
+		album.Duration().Round(time.Second).String(),
+		fmt.Sprintf("popularity %d", album.Popularity),
+	)
+	return strings.Join(parts, ", ")
+}
+
+// CopyrightText joins the album's copyright lines.
+func (album *AlbumDetails) CopyrightText() string {
+	lines := []string{}
+	for _, copyright := range album.Copyrights {
+		text := copyright.Text
+		switch {
+		case copyright.Type == "C" && !strings.ContainsAny(text, "©"):
+			text = "© " + text
+		case copyright.Type == "P" && !strings.ContainsAny(text, "℗"):
+			text = "℗ " + text
+		}
+		lines = append(lines, text)
+	}
+	return strings.Join(lines, " ")
+}
+
+func (c *Commands) AlbumSaved(ctx *gctx.Context, id spotify.ID) (bool, error) {
+	saved, err := c.Client().UserHasAlbums(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	return len(saved) > 0 && saved[0], nil
+}
+
+func (c *Commands) SetAlbumSaved(ctx *gctx.Context, id spotify.ID, saved bool) error {
+	if saved {
+		return c.Client().AddAlbumsToLibrary(ctx, id)
+	}
+	return c.Client().RemoveAlbumsFromLibrary(ctx, id)
+}
+
+// SaveAlbum saves or unsaves an album given by id, uri or url, or the album
+// of the current track when query is empty.
+func (c *Commands) SaveAlbum(ctx *gctx.Context, query string, saved bool) error {
+	id, name := uriID(query, "album"), query
+	if query == "" {
+		playing, err := c.CurrentlyPlaying(ctx)
+		if err != nil {
+			return err
+		}
+		if playing.Item == nil || IsEpisode(playing.Item) || IsChapter(playing.Item) {
+			return fmt.Errorf("no album is playing")
+		}
+		id, name = playing.Item.Album.ID, playing.Item.Album.Name
+	} else if id == "" {
+		id = spotify.ID(query)
+	}
+	err := c.SetAlbumSaved(ctx, id, saved)
+	if err != nil {
+		return err
+	}
+	if saved {
+		fmt.Println("Saved", name)
+	} else {
+		fmt.Println("Unsaved", name)
+	}
+	return nil
+}
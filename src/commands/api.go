@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/zmb3/spotify/v2"
+
+	"git.asdf.cafe/abs3nt/gospt/src/gctx"
+)
+
+const apiURL = "https://api.spotify.com/v1/"
+
+// api calls web api endpoints the spotify library does not cover. body and
+// out are encoded and decoded as json when they are not nil.
+func (c *Commands) api(ctx *gctx.Context, method, path string, query url.Values, body, out any) error {
+	token, err := c.Client().Token()
+	if err != nil {
+		return err
+	}
+	endpoint := apiURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	token.SetAuthHeader(req)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		apiErr := struct {
+			Error spotify.Error `json:"error"`
+		}{}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		if json.Unmarshal(data, &apiErr) != nil || apiErr.Error.Message == "" {
+			apiErr.Error.Message = resp.Status
+		}
+		apiErr.Error.Status = resp.StatusCode
+		return apiErr.Error
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/zmb3/spotify/v2"
+
+	"git.asdf.cafe/abs3nt/gospt/src/gctx"
+)
+
+// ArtistTopTracks gets an artist's top tracks in the user's market, the
+// spotify library only takes a country code.
+func (c *Commands) ArtistTopTracks(ctx *gctx.Context, artist spotify.ID) ([]spotify.FullTrack, error) {
+	result := struct {
+		Tracks []spotify.FullTrack `json:"tracks"`
+	}{}
+	query := url.Values{"market": {c.Market()}}
+	err := c.api(ctx, http.MethodGet, "artists/"+string(artist)+"/top-tracks", query, nil, &result)
+	return result.Tracks, err
+}
+
+// ArtistAlbumsOfType lists one section of an artist's discography, like only
+// the singles or only the albums the artist appears on.
+func (c *Commands) ArtistAlbumsOfType(ctx *gctx.Context, artist spotify.ID, albumType spotify.AlbumType, page int) (*spotify.SimpleAlbumPage, error) {
+	return c.Client().
+		GetArtistAlbums(ctx, artist, []spotify.AlbumType{albumType}, c.market(), spotify.Limit(50), spotify.Offset((page-1)*50))
+}
+
+func (c *Commands) RelatedArtists(ctx *gctx.Context, artist spotify.ID) ([]spotify.FullArtist, error) {
+	return c.Client().GetRelatedArtists(ctx, artist)
+}
+
+func (c *Commands) FollowsArtist(ctx *gctx.Context, artist spotify.ID) (bool, error) {
+	follows, err := c.Client().CurrentUserFollows(ctx, "artist", artist)
+	if err != nil {
+		return false, err
+	}
+	return len(follows) > 0 && follows[0], nil
+}
+
+// ToggleFollowArtist follows or unfollows an artist and reports whether the
+// artist is followed now.
+func (c *Commands) ToggleFollowArtist(ctx *gctx.Context, artist spotify.ID) (bool, error) {
+	follows, err := c.FollowsArtist(ctx, artist)
+	if err != nil {
+		return false, err
+	}
+	if follows {
+		return false, c.Client().UnfollowArtist(ctx, artist)
+	}
+	return true, c.Client().FollowArtist(ctx, artist)
+}
+
+// RadioFromAlbums starts a radio seeded with the first track of up to five
+// albums.
+func (c *Commands) RadioFromAlbums(ctx *gctx.Context, albums []spotify.SimpleAlbum, name string) error {
+	seeds := []spotify.ID{}
+	for _, album := range albums {
+		if len(seeds) == 5 {
+			break
+		}
+		tracks, err := c.AlbumTracks(ctx, album.ID, 1)
+		if err != nil {
+			return err
+		}
+		if len(tracks.Tracks) > 0 {
+			seeds = append(seeds, tracks.Tracks[0].ID)
+		}
+	}
+	if len(seeds) == 0 {
+		return fmt.Errorf("no tracks to start a radio from")
+	}
+	return c.RadioGivenList(ctx, seeds, name)
+}
+
+// RadioFromArtists starts a radio seeded with the top track of up to five
+// artists.
+func (c *Commands) RadioFromArtists(ctx *gctx.Context, artists []spotify.SimpleArtist, name string) error {
+	seeds := []spotify.ID{}
+	for _, artist := range artists {
+		if len(seeds) == 5 {
+			break
+		}
+		tracks, err := c.ArtistTopTracks(ctx, artist.ID)
+		if err != nil {
+			return err
+		}
+		if len(tracks) > 0 {
+			seeds = append(seeds, tracks[0].ID)
+		}
+	}
+	if len(seeds) == 0 {
+		return fmt.Errorf("no tracks to start a radio from")
+	}
+	return c.RadioGivenList(ctx, seeds, name)
+}
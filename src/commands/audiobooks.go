@@ -0,0 +1,269 @@
+package commands
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+
+	"git.asdf.cafe/abs3nt/gospt/src/gctx"
+)
+
+// The spotify library does not cover audiobooks yet, these types follow the
+// web api objects.
+
+type Author struct {
+	Name string `json:"name"`
+}
+
+type SimpleAudiobook struct {
+	ID            spotify.ID        `json:"id"`
+	Name          string            `json:"name"`
+	URI           spotify.URI       `json:"uri"`
+	Authors       []Author          `json:"authors"`
+	Narrators     []Author          `json:"narrators"`
+	Publisher     string            `json:"publisher"`
+	Description   string            `json:"description"`
+	Images        []spotify.Image   `json:"images"`
+	TotalChapters int               `json:"total_chapters"`
+	ExternalURLs  map[string]string `json:"external_urls"`
+}
+
+// AuthorNames joins the names of the authors of an audiobook.
+func (a SimpleAudiobook) AuthorNames() string {
+	names := []string{}
+	for _, author := range a.Authors {
+		names = append(names, author.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// savedAudiobook accepts saved audiobooks both wrapped with the date they were
+// saved and as plain audiobooks.
+type savedAudiobook struct {
+	Audiobook *SimpleAudiobook `json:"audiobook"`
+	SimpleAudiobook
+}
+
+type Chapter struct {
+	ID            spotify.ID                `json:"id"`
+	Name          string                    `json:"name"`
+	URI           spotify.URI               `json:"uri"`
+	ChapterNumber int                       `json:"chapter_number"`
+	DurationMs    int                       `json:"duration_ms"`
+	ReleaseDate   string                    `json:"release_date"`
+	Images        []spotify.Image           `json:"images"`
+	ResumePoint   spotify.ResumePointObject `json:"resume_point"`
+	ExternalURLs  map[string]string         `json:"external_urls"`
+	Audiobook     *SimpleAudiobook          `json:"audiobook"`
+}
+
+func (c *Commands) pageQuery(page int) url.Values {
+	return url.Values{
+		"limit":  {"50"},
+		"offset": {strconv.Itoa((page - 1) * 50)},
+		"market": {c.Market()},
+	}
+}
+
+func (c *Commands) UserAudiobooks(ctx *gctx.Context, page int) ([]SimpleAudiobook, int, error) {
+	result := struct {
+		Items []savedAudiobook `json:"items"`
+		Total int              `json:"total"`
+	}{}
+	if err := c.api(ctx, http.MethodGet, "me/audiobooks", c.pageQuery(page), nil, &result); err != nil {
+		return nil, 0, err
+	}
+	books := []SimpleAudiobook{}
+	for _, item := range result.Items {
+		if item.Audiobook != nil {
+			books = append(books, *item.Audiobook)
+		} else if item.ID != "" {
+			books = append(books, item.SimpleAudiobook)
+		}
+	}
+	return books, result.Total, nil
+}
+
+func (c *Commands) Audiobook(ctx *gctx.Context, id spotify.ID) (*SimpleAudiobook, error) {
+	book := &SimpleAudiobook{}
+	query := url.Values{"market": {c.Market()}}
+	return book, c.api(ctx, http.MethodGet, "audiobooks/"+string(id), query, nil, book)
+}
+
+func (c *Commands) AudiobookChapters(ctx *gctx.Context, id spotify.ID, page int) ([]Chapter, error) {
+	result := struct {
+		Items []Chapter `json:"items"`
+	}{}
+	if err := c.api(ctx, http.MethodGet, "audiobooks/"+string(id)+"/chapters", c.pageQuery(page), nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
+
+func (c *Commands) Chapter(ctx *gctx.Context, id spotify.ID) (*Chapter, error) {
+	chapter := &Chapter{}
+	query := url.Values{"market": {c.Market()}}
+	return chapter, c.api(ctx, http.MethodGet, "chapters/"+string(id), query, nil, chapter)
+}
+
+var (
+	// chapters caches the chapters the player reports, like episodes.
+	chapters       = newLookupCache[*Chapter]()
+	missedChapters = newMisses()
+)
+
+// playingChapter is Chapter for the player, cached since it is asked about on
+// every poll.
+func (c *Commands) playingChapter(ctx *gctx.Context, id spotify.ID) (*Chapter, error) {
+	if chapter, ok := chapters.get(id); ok {
+		return chapter, nil
+	}
+	if missedChapters.recent(id) {
+		return nil, fmt.Errorf("chapter %s was not found", id)
+	}
+	chapter, err := c.Chapter(ctx, id)
+	if err != nil {
+		missedChapters.add(id)
+		return nil, err
+	}
+	chapters.put(id, chapter)
+	return chapter, nil
+}
+
+func IsChapter(item *spotify.FullTrack) bool {
+	return item != nil && item.Type == "chapter"
+}
+
+// fillChapter puts the audiobook in place of the album and its authors in
+// place of the artists, like fillEpisode does for shows.
+func (c *Commands) fillChapter(ctx *gctx.Context, item *spotify.FullTrack) bool {
+	chapter, err := c.playingChapter(ctx, item.ID)
+	if err != nil || chapter.Audiobook == nil {
+		ctx.Debug.Trace().Err(err).Msg("failed to get chapter")
+		return false
+	}
+	book := chapter.Audiobook
+	item.Type = "chapter"
+	item.Artists = []spotify.SimpleArtist{}
+	for _, author := range book.Authors {
+		item.Artists = append(item.Artists, spotify.SimpleArtist{Name: author.Name})
+	}
+	item.Album = spotify.SimpleAlbum{Name: book.Name, ID: book.ID, URI: book.URI, Images: chapter.Images, ExternalURLs: book.ExternalURLs}
+	if len(item.Album.Images) == 0 {
+		item.Album.Images = book.Images
+	}
+	if item.Duration == 0 {
+		item.Duration = spotify.Numeric(chapter.DurationMs)
+	}
+	return true
+}
+
+// ResumeChapter plays a chapter in the context of its audiobook from where it
+// was left off.
+func (c *Commands) ResumeChapter(ctx *gctx.Context, chapter Chapter, book spotify.URI) error {
+	position := 0
+	if !chapter.ResumePoint.FullyPlayed {
+		position = int(chapter.ResumePoint.ResumePositionMs)
+	}
+	return c.withDevice(ctx, func(opt *spotify.PlayOptions) error {
+		opt.PositionMs = spotify.Numeric(position)
+		if book != "" {
+			opt.PlaybackContext = &book
+			opt.PlaybackOffset = &spotify.PlaybackOffset{URI: chapter.URI}
+		} else {
+			opt.URIs = []spotify.URI{chapter.URI}
+		}
+		return c.Client().PlayOpt(ctx, opt)
+	})
+}
+
+// ResumeAudiobook resumes a chapter, or the first chapter of an audiobook that
+// was not played to the end.
+func (c *Commands) ResumeAudiobook(ctx *gctx.Context, query string) error {
+	if id := uriID(query, "chapter"); id != "" {
+		chapter, err := c.Chapter(ctx, id)
+		if err != nil {
+			return err
+		}
+		book := spotify.URI("")
+		if chapter.Audiobook != nil {
+			book = chapter.Audiobook.URI
+		}
+		return c.ResumeChapter(ctx, *chapter, book)
+	}
+	id := uriID(query, "audiobook")
+	if id == "" {
+		id = spotify.ID(query)
+	}
+	book, err := c.Audiobook(ctx, id)
+	if err != nil {
+		return err
+	}
+	for page := 1; ; page++ {
+		chapters, err := c.AudiobookChapters(ctx, id, page)
+		if err != nil {
+			return err
+		}
+		for _, chapter := range chapters {
+			if !chapter.ResumePoint.FullyPlayed {
+				return c.ResumeChapter(ctx, chapter, book.URI)
+			}
+		}
+		if len(chapters) < 50 {
+			return fmt.Errorf("every chapter of %s was played", book.Name)
+		}
+	}
+}
+
+// FormatChapter describes a chapter for listings.
+func FormatChapter(chapter Chapter) string {
+	duration := (time.Duration(chapter.DurationMs) * time.Millisecond).Round(time.Minute)
+	state := ""
+	switch {
+	case chapter.ResumePoint.FullyPlayed:
+		state = ", played"
+	case chapter.ResumePoint.ResumePositionMs > 0:
+		left := time.Duration(chapter.DurationMs-int(chapter.ResumePoint.ResumePositionMs)) * time.Millisecond
+		state = fmt.Sprintf(", %s left", left.Round(time.Minute))
+	}
+	return fmt.Sprintf("Chapter %d - %s%s", chapter.ChapterNumber+1, duration, state)
+}
+
+func (c *Commands) PrintAudiobooks(ctx *gctx.Context) error {
+	for page := 1; ; page++ {
+		books, _, err := c.UserAudiobooks(ctx, page)
+		if err != nil {
+			return err
+		}
+		for _, book := range books {
+			fmt.Printf("%s - %s (%s)\n", book.Name, book.AuthorNames(), book.URI)
+		}
+		if len(books) < 50 {
+			return nil
+		}
+	}
+}
+
+func (c *Commands) PrintChapters(ctx *gctx.Context, query string) error {
+	id := uriID(query, "audiobook")
+	if id == "" {
+		id = spotify.ID(query)
+	}
+	for page := 1; ; page++ {
+		chapters, err := c.AudiobookChapters(ctx, id, page)
+		if err != nil {
+			return err
+		}
+		for _, chapter := range chapters {
+			fmt.Printf("%s\n  %s (%s)\n", chapter.Name, FormatChapter(chapter), chapter.URI)
+		}
+		if len(chapters) < 50 {
+			return nil
+		}
+	}
+}
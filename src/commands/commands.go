@@ -8,6 +8,7 @@ import (
 	"io"
 	"math"
 	"math/rand"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -21,6 +22,7 @@ import (
 	"git.asdf.cafe/abs3nt/gospt/src/auth"
 	"git.asdf.cafe/abs3nt/gospt/src/cache"
 	"git.asdf.cafe/abs3nt/gospt/src/gctx"
+	"git.asdf.cafe/abs3nt/gospt/src/spotifyurl"
 	"git.asdf.cafe/abs3nt/gospt/src/youtube"
 )
 
@@ -29,7 +31,19 @@ type Commands struct {
 	cl      *spotify.Client
 	mu      sync.RWMutex
 
-	user string
+	user    string
+	country string
+
+	// played caches played.json, it is read once and kept up to date by
+	// MarkPlayed
+	playedMu sync.Mutex
+	played   map[spotify.ID]bool
+}
+
+// NewWithClient is a Commands that talks to spotify through client instead
+// of logging in, the user and country stay unknown.
+func NewWithClient(ctx *gctx.Context, client *spotify.Client) *Commands {
+	return &Commands{Context: ctx, cl: client}
 }
 
 func (c *Commands) Client() *spotify.Client {
@@ -59,6 +73,7 @@ func (c *Commands) connectClient() *spotify.Client {
 		panic(err)
 	}
 	c.user = currentUser.ID
+	c.country = currentUser.Country
 	return client
 }
 
@@ -139,8 +154,15 @@ func (c *Commands) ActiveDeviceExists(ctx *gctx.Context) bool {
 	return false
 }
 
-func (c *Commands) UserArtists(ctx *gctx.Context, page int) (*spotify.FullArtistCursorPage, error) {
-	artists, err := c.Client().CurrentUsersFollowedArtists(ctx, spotify.Limit(50), spotify.Offset((page-1)*50))
+// UserArtists lists the followed artists after the artist id after, or from
+// the start when it is empty. Followed artists come in cursor pages, which
+// ignore offsets.
+func (c *Commands) UserArtists(ctx *gctx.Context, after spotify.ID) (*spotify.FullArtistCursorPage, error) {
+	opts := []spotify.RequestOption{spotify.Limit(50)}
+	if after != "" {
+		opts = append(opts, spotify.After(string(after)))
+	}
+	artists, err := c.Client().CurrentUsersFollowedArtists(ctx, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -149,25 +171,16 @@ func (c *Commands) UserArtists(ctx *gctx.Context, page int) (*spotify.FullArtist
 
 func (c *Commands) ArtistAlbums(ctx *gctx.Context, artist spotify.ID, page int) (*spotify.SimpleAlbumPage, error) {
 	albums, err := c.Client().
-		GetArtistAlbums(ctx, artist, []spotify.AlbumType{1, 2, 3, 4}, spotify.Market(spotify.CountryUSA), spotify.Limit(50), spotify.Offset((page-1)*50))
+		GetArtistAlbums(ctx, artist, []spotify.AlbumType{1, 2, 3, 4}, c.market(), spotify.Limit(50), spotify.Offset((page-1)*50))
 	if err != nil {
 		return nil, err
 	}
 	return albums, nil
 }
 
-func (c *Commands) Search(ctx *gctx.Context, search string, page int) (*spotify.SearchResult, error) {
-	result, err := c.Client().
-		Search(ctx, search, spotify.SearchTypeAlbum|spotify.SearchTypeArtist|spotify.SearchTypeTrack|spotify.SearchTypePlaylist, spotify.Limit(50), spotify.Offset((page-1)*50))
-	if err != nil {
-		return nil, err
-	}
-	return result, nil
-}
-
 func (c *Commands) AlbumTracks(ctx *gctx.Context, album spotify.ID, page int) (*spotify.SimpleTrackPage, error) {
 	tracks, err := c.Client().
-		GetAlbumTracks(ctx, album, spotify.Limit(50), spotify.Offset((page-1)*50), spotify.Market(spotify.CountryUSA))
+		GetAlbumTracks(ctx, album, spotify.Limit(50), spotify.Offset((page-1)*50), c.market())
 	if err != nil {
 		return nil, err
 	}
@@ -182,41 +195,67 @@ func (c *Commands) UserQueue(ctx *gctx.Context) (*spotify.Queue, error) {
 	return c.Client().GetQueue(ctx)
 }
 
-func (c *Commands) PlayUrl(ctx *gctx.Context, args []string) error {
-	url, err := url.Parse(args[0])
+// PlayUrl plays the album, playlist, artist, show or audiobook a spotify url
+// or uri points at, or plays a track or episode right away. With queue the
+// target is added to the queue instead.
+func (c *Commands) PlayUrl(ctx *gctx.Context, link string, queue bool) error {
+	target, err := spotifyurl.Resolve(ctx, http.DefaultClient, link)
 	if err != nil {
 		return err
 	}
-	track_id := strings.Split(url.Path, "/")[2]
-	err = c.Client().QueueSong(ctx, spotify.ID(track_id))
-	if err != nil {
-		if isNoActiveError(err) {
-			deviceID, err := c.activateDevice(ctx)
-			if err != nil {
-				return err
-			}
-			err = c.Client().QueueSongOpt(ctx, spotify.ID(track_id), &spotify.PlayOptions{
-				DeviceID: &deviceID,
-			})
-			if err != nil {
-				return err
-			}
-			err = c.Client().NextOpt(ctx, &spotify.PlayOptions{
-				DeviceID: &deviceID,
+	if queue {
+		switch target.Kind {
+		case spotifyurl.Track, spotifyurl.Episode, spotifyurl.Chapter:
+			return c.withDevice(ctx, func(opt *spotify.PlayOptions) error {
+				return c.queueURI(ctx, target.URI(), opt)
 			})
-			if err != nil {
-				return err
-			}
-			return nil
-		} else {
+		case spotifyurl.Album, spotifyurl.Playlist, spotifyurl.Artist:
+			_, err := c.QueueCollection(ctx, string(target.Kind), string(target.URI()), QueueSpotify)
 			return err
 		}
+		return fmt.Errorf("cannot queue a %s", target.Kind)
+	}
+	if target.IsContext() {
+		uri := target.URI()
+		return c.withDevice(ctx, func(opt *spotify.PlayOptions) error {
+			opt.PlaybackContext = &uri
+			return c.Client().PlayOpt(ctx, opt)
+		})
+	}
+	switch target.Kind {
+	case spotifyurl.Track, spotifyurl.Episode, spotifyurl.Chapter:
+		// queue and skip to it so the current context keeps playing afterwards
+		return c.withDevice(ctx, func(opt *spotify.PlayOptions) error {
+			if err := c.queueURI(ctx, target.URI(), opt); err != nil {
+				return err
+			}
+			return c.Client().NextOpt(ctx, opt)
+		})
 	}
-	err = c.Client().Next(ctx)
+	return fmt.Errorf("cannot play a %s", target.Kind)
+}
+
+// withDevice runs fn and retries it on the default device if there is no
+// active device.
+func (c *Commands) withDevice(ctx *gctx.Context, fn func(opt *spotify.PlayOptions) error) error {
+	err := fn(&spotify.PlayOptions{})
+	if err == nil || !isNoActiveError(err) {
+		return err
+	}
+	deviceID, err := c.activateDevice(ctx)
 	if err != nil {
 		return err
 	}
-	return nil
+	return fn(&spotify.PlayOptions{DeviceID: &deviceID})
+}
+
+// queueURI queues any playable uri, the spotify library only queues tracks.
+func (c *Commands) queueURI(ctx *gctx.Context, uri spotify.URI, opt *spotify.PlayOptions) error {
+	query := url.Values{"uri": {string(uri)}}
+	if opt != nil && opt.DeviceID != nil {
+		query.Set("device_id", string(*opt.DeviceID))
+	}
+	return c.api(ctx, http.MethodPost, "me/player/queue", query, nil, nil)
 }
 
 func (c *Commands) QueueSong(ctx *gctx.Context, id spotify.ID) error {
@@ -241,6 +280,16 @@ func (c *Commands) QueueSong(ctx *gctx.Context, id spotify.ID) error {
 	return nil
 }
 
+// PlayInContext plays a context, like an album, starting at one of its
+// tracks.
+func (c *Commands) PlayInContext(ctx *gctx.Context, context spotify.URI, track spotify.URI) error {
+	return c.withDevice(ctx, func(opt *spotify.PlayOptions) error {
+		opt.PlaybackContext = &context
+		opt.PlaybackOffset = &spotify.PlaybackOffset{URI: track}
+		return c.Client().PlayOpt(ctx, opt)
+	})
+}
+
 func (c *Commands) PlaySongInPlaylist(ctx *gctx.Context, context *spotify.URI, offset *int) error {
 	e := c.Client().PlayOpt(ctx, &spotify.PlayOptions{
 		PlaybackOffset:  &spotify.PlaybackOffset{Position: offset},
@@ -524,6 +573,28 @@ func (c *Commands) DeleteTracksFromPlaylist(ctx *gctx.Context, tracks []spotify.
 	return nil
 }
 
+// AddTracksToPlaylist adds any number of tracks, split into the batches of 100
+// that the api accepts.
+func (c *Commands) AddTracksToPlaylist(ctx *gctx.Context, playlist spotify.ID, tracks []spotify.ID) error {
+	for start := 0; start < len(tracks); start += 100 {
+		_, err := c.Client().AddTracksToPlaylist(ctx, playlist, tracks[start:min(start+100, len(tracks))]...)
+		if err != nil {
+			return fmt.Errorf("add tracks: %w", err)
+		}
+	}
+	return nil
+}
+
+// ReplacePlaylistTracks overwrites the contents of a playlist with tracks.
+func (c *Commands) ReplacePlaylistTracks(ctx *gctx.Context, playlist spotify.ID, tracks []spotify.ID) error {
+	first := tracks[:min(100, len(tracks))]
+	err := c.Client().ReplacePlaylistTracks(ctx, playlist, first...)
+	if err != nil {
+		return fmt.Errorf("replace tracks: %w", err)
+	}
+	return c.AddTracksToPlaylist(ctx, playlist, tracks[len(first):])
+}
+
 func (c *Commands) SongExists(db *sql.DB, song spotify.ID) (bool, error) {
 	song_id := string(song)
 	sqlStmt := `SELECT id FROM radio WHERE id = ?`
@@ -765,30 +836,6 @@ func (c *Commands) TogglePlay(ctx *gctx.Context) error {
 	return c.Pause(ctx)
 }
 
-func (c *Commands) Like(ctx *gctx.Context) error {
-	playing, err := c.Client().PlayerCurrentlyPlaying(ctx)
-	if err != nil {
-		return err
-	}
-	err = c.Client().AddTracksToLibrary(ctx, playing.Item.ID)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func (c *Commands) Unlike(ctx *gctx.Context) error {
-	playing, err := c.Client().PlayerCurrentlyPlaying(ctx)
-	if err != nil {
-		return err
-	}
-	err = c.Client().RemoveTracksFromLibrary(ctx, playing.Item.ID)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
 func (c *Commands) Next(ctx *gctx.Context, amt int, inqueue bool) error {
 	if inqueue {
 		for i := 0; i < amt; i++ {
@@ -834,7 +881,7 @@ func (c *Commands) Next(ctx *gctx.Context, amt int, inqueue bool) error {
 				return err
 			}
 			for idx, track := range playlist.Items {
-				if track.Track.Track.ID == current.Item.ID {
+				if track.Track.Track != nil && track.Track.Track.ID == current.Item.ID {
 					currentTrackIndex = idx + (50 * (page - 1))
 					found = true
 					break
@@ -895,7 +942,7 @@ func (c *Commands) Previous(ctx *gctx.Context) error {
 
 func (c *Commands) Status(ctx *gctx.Context) error {
 	state, err := cache.DefaultCache().GetOrDo("state", func() (string, error) {
-		state, err := c.Client().PlayerState(ctx)
+		state, err := c.PlayerState(ctx)
 		if err != nil {
 			return "", err
 		}
@@ -914,7 +961,7 @@ func (c *Commands) Status(ctx *gctx.Context) error {
 
 func (c *Commands) DownloadCover(ctx *gctx.Context, args []string) error {
 	destinationPath := filepath.Clean(args[0])
-	state, err := c.Client().PlayerState(ctx)
+	state, err := c.PlayerState(ctx)
 	if err != nil {
 		return err
 	}
@@ -922,6 +969,9 @@ func (c *Commands) DownloadCover(ctx *gctx.Context, args []string) error {
 	if err != nil {
 		return err
 	}
+	if state.Item == nil || len(state.Item.Album.Images) == 0 {
+		return fmt.Errorf("nothing with a cover is playing")
+	}
 	err = state.Item.Album.Images[0].Download(f)
 	if err != nil {
 		return err
@@ -930,7 +980,7 @@ func (c *Commands) DownloadCover(ctx *gctx.Context, args []string) error {
 }
 
 func (c *Commands) Link(ctx *gctx.Context) (string, error) {
-	state, err := c.Client().PlayerState(ctx)
+	state, err := c.PlayerState(ctx)
 	if err != nil {
 		return "", err
 	}
@@ -938,7 +988,7 @@ func (c *Commands) Link(ctx *gctx.Context) (string, error) {
 }
 
 func (c *Commands) YoutubeLink(ctx *gctx.Context) (string, error) {
-	state, err := c.Client().PlayerState(ctx)
+	state, err := c.PlayerState(ctx)
 	if err != nil {
 		return "", err
 	}
@@ -957,7 +1007,7 @@ func (c *Commands) LinkContext(ctx *gctx.Context) (string, error) {
 func (c *Commands) NowPlaying(ctx *gctx.Context, args []string) error {
 	if len(args) > 0 {
 		if args[0] == "force" {
-			current, err := c.Client().PlayerCurrentlyPlaying(ctx)
+			current, err := c.CurrentlyPlaying(ctx)
 			if err != nil {
 				return err
 			}
@@ -967,7 +1017,7 @@ func (c *Commands) NowPlaying(ctx *gctx.Context, args []string) error {
 		}
 	}
 	song, err := cache.DefaultCache().GetOrDo("now_playing", func() (string, error) {
-		current, err := c.Client().PlayerCurrentlyPlaying(ctx)
+		current, err := c.CurrentlyPlaying(ctx)
 		if err != nil {
 			return "", err
 		}
@@ -989,7 +1039,13 @@ func FormatSong(current *spotify.CurrentlyPlaying) string {
 	if current != nil {
 		if current.Item != nil {
 			out += fmt.Sprintf(" %s", current.Item.Name)
-			if len(current.Item.Artists) > 0 {
+			switch {
+			case IsChapter(current.Item):
+				out += fmt.Sprintf(" - %s", current.Item.Album.Name)
+				if len(current.Item.Artists) > 0 {
+					out += fmt.Sprintf(" by %s", current.Item.Artists[0].Name)
+				}
+			case len(current.Item.Artists) > 0:
 				out += fmt.Sprintf(" - %s", current.Item.Artists[0].Name)
 			}
 		}
@@ -1029,7 +1085,7 @@ func (c *Commands) Repeat(ctx *gctx.Context) error {
 }
 
 func (c *Commands) TrackList(ctx *gctx.Context, page int) (*spotify.SavedTrackPage, error) {
-	return c.Client().CurrentUsersTracks(ctx, spotify.Limit(50), spotify.Offset((page-1)*50))
+	return c.Client().CurrentUsersTracks(ctx, spotify.Limit(50), spotify.Offset((page-1)*50), c.market())
 }
 
 func (c *Commands) Playlists(ctx *gctx.Context, page int) (*spotify.SimplePlaylistPage, error) {
@@ -1037,12 +1093,14 @@ func (c *Commands) Playlists(ctx *gctx.Context, page int) (*spotify.SimplePlayli
 }
 
 func (c *Commands) PlaylistTracks(ctx *gctx.Context, playlist spotify.ID, page int) (*spotify.PlaylistItemPage, error) {
-	return c.Client().GetPlaylistItems(ctx, playlist, spotify.Limit(50), spotify.Offset((page-1)*50))
+	return c.Client().GetPlaylistItems(ctx, playlist, spotify.Limit(50), spotify.Offset((page-1)*50), c.market())
 }
 
 func (c *Commands) FormatState(state *spotify.PlayerState) (string, error) {
-	state.Item.AvailableMarkets = []string{}
-	state.Item.Album.AvailableMarkets = []string{}
+	if state.Item != nil {
+		state.Item.AvailableMarkets = []string{}
+		state.Item.Album.AvailableMarkets = []string{}
+	}
 	out, err := json.MarshalIndent(state, "", " ")
 	if err != nil {
 		return "", err
@@ -1051,11 +1109,7 @@ func (c *Commands) FormatState(state *spotify.PlayerState) (string, error) {
 }
 
 func (c *Commands) PrintPlaying(current *spotify.CurrentlyPlaying) error {
-	icon := "▶"
-	if !current.Playing {
-		icon = "⏸"
-	}
-	fmt.Printf("%s %s - %s\n", icon, current.Item.Name, current.Item.Artists[0].Name)
+	fmt.Println(FormatSong(current))
 	return nil
 }
 
@@ -1110,13 +1164,17 @@ func (c *Commands) RadioFromPlaylist(ctx *gctx.Context, playlist spotify.SimpleP
 		seedCount = len(pageSongs)
 	}
 	seedIds := []spotify.ID{}
-	for idx, song := range pageSongs {
-		if idx >= seedCount {
-			break
+	for _, song := range pageSongs {
+		// episodes in the playlist can't seed a radio
+		if len(seedIds) >= seedCount || song.Track.Track == nil {
+			continue
 		}
 		seedIds = append(seedIds, song.Track.Track.ID)
 	}
-	return c.RadioGivenList(ctx, seedIds[:seedCount], playlist.Name)
+	if len(seedIds) == 0 {
+		return fmt.Errorf("this playlist has no tracks to start a radio from")
+	}
+	return c.RadioGivenList(ctx, seedIds, playlist.Name)
 }
 
 func (c *Commands) RadioFromAlbum(ctx *gctx.Context, album spotify.SimpleAlbum) error {
@@ -0,0 +1,314 @@
+package commands
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/zmb3/spotify/v2"
+
+	"git.asdf.cafe/abs3nt/gospt/src/gctx"
+)
+
+type ExportKind string
+
+const (
+	ExportLibrary  ExportKind = "library"
+	ExportAlbums   ExportKind = "albums"
+	ExportArtists  ExportKind = "artists"
+	ExportPlaylist ExportKind = "playlist"
+)
+
+type ExportTrack struct {
+	URI        string   `json:"uri"`
+	Name       string   `json:"name"`
+	Artists    []string `json:"artists"`
+	Album      string   `json:"album"`
+	ISRC       string   `json:"isrc"`
+	DurationMs int      `json:"duration_ms"`
+	AddedAt    string   `json:"added_at,omitempty"`
+}
+
+type ExportAlbum struct {
+	URI         string   `json:"uri"`
+	Name        string   `json:"name"`
+	Artists     []string `json:"artists"`
+	ReleaseDate string   `json:"release_date"`
+	UPC         string   `json:"upc"`
+	TotalTracks int      `json:"total_tracks"`
+	AddedAt     string   `json:"added_at,omitempty"`
+}
+
+type ExportArtist struct {
+	URI    string   `json:"uri"`
+	Name   string   `json:"name"`
+	Genres []string `json:"genres"`
+}
+
+// Export is the portable representation of a library section or playlist
+// written by `gospt export` and read back by `gospt import`.
+type Export struct {
+	Name    string         `json:"name"`
+	Kind    ExportKind     `json:"kind"`
+	Tracks  []ExportTrack  `json:"tracks,omitempty"`
+	Albums  []ExportAlbum  `json:"albums,omitempty"`
+	Artists []ExportArtist `json:"artists,omitempty"`
+}
+
+func exportTrack(track *spotify.FullTrack, addedAt string) ExportTrack {
+	artists := []string{}
+	for _, artist := range track.Artists {
+		artists = append(artists, artist.Name)
+	}
+	return ExportTrack{
+		URI:        string(track.URI),
+		Name:       track.Name,
+		Artists:    artists,
+		Album:      track.Album.Name,
+		ISRC:       track.ExternalIDs["isrc"],
+		DurationMs: int(track.Duration),
+		AddedAt:    addedAt,
+	}
+}
+
+func (c *Commands) ExportLibrary(ctx *gctx.Context, kind ExportKind, playlist string) (*Export, error) {
+	switch kind {
+	case ExportLibrary:
+		out := &Export{Name: "Saved Tracks", Kind: kind}
+		for page := 1; ; page++ {
+			tracks, err := c.TrackList(ctx, page)
+			if err != nil {
+				return nil, err
+			}
+			for _, track := range tracks.Tracks {
+				track := track
+				out.Tracks = append(out.Tracks, exportTrack(&track.FullTrack, track.AddedAt))
+			}
+			if len(tracks.Tracks) < 50 {
+				break
+			}
+		}
+		return out, nil
+	case ExportAlbums:
+		out := &Export{Name: "Saved Albums", Kind: kind}
+		for page := 1; ; page++ {
+			albums, err := c.UserAlbums(ctx, page)
+			if err != nil {
+				return nil, err
+			}
+			for _, album := range albums.Albums {
+				artists := []string{}
+				for _, artist := range album.Artists {
+					artists = append(artists, artist.Name)
+				}
+				out.Albums = append(out.Albums, ExportAlbum{
+					URI:         string(album.URI),
+					Name:        album.Name,
+					Artists:     artists,
+					ReleaseDate: album.ReleaseDate,
+					UPC:         album.ExternalIDs["upc"],
+					TotalTracks: int(album.Tracks.Total),
+					AddedAt:     album.AddedAt,
+				})
+			}
+			if len(albums.Albums) < 50 {
+				break
+			}
+		}
+		return out, nil
+	case ExportArtists:
+		out := &Export{Name: "Followed Artists", Kind: kind}
+		after := ""
+		for {
+			opts := []spotify.RequestOption{spotify.Limit(50)}
+			if after != "" {
+				opts = append(opts, spotify.After(after))
+			}
+			artists, err := c.Client().CurrentUsersFollowedArtists(ctx, opts...)
+			if err != nil {
+				return nil, err
+			}
+			for _, artist := range artists.Artists {
+				out.Artists = append(out.Artists, ExportArtist{
+					URI:    string(artist.URI),
+					Name:   artist.Name,
+					Genres: artist.Genres,
+				})
+			}
+			after = artists.Cursor.After
+			if after == "" || len(artists.Artists) == 0 {
+				break
+			}
+		}
+		return out, nil
+	case ExportPlaylist:
+		found, err := c.FindPlaylist(ctx, playlist)
+		if err != nil {
+			return nil, err
+		}
+		items, err := c.AllPlaylistItems(ctx, found.ID)
+		if err != nil {
+			return nil, err
+		}
+		out := &Export{Name: found.Name, Kind: kind}
+		for _, item := range items {
+			if item.Track.Track == nil {
+				continue
+			}
+			out.Tracks = append(out.Tracks, exportTrack(item.Track.Track, item.AddedAt))
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unknown export %q, expected library, albums, artists or playlist", kind)
+}
+
+// ExportFormat returns the format for a file, preferring the explicit format
+// and falling back to the file extension.
+func ExportFormat(format, path string) string {
+	if format != "" {
+		return strings.ToLower(format)
+	}
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	if ext == "m3u8" {
+		return "m3u"
+	}
+	if ext == "" {
+		return "json"
+	}
+	return ext
+}
+
+func (c *Commands) Export(ctx *gctx.Context, kind ExportKind, playlist, format, path string) error {
+	out, err := c.ExportLibrary(ctx, kind, playlist)
+	if err != nil {
+		return err
+	}
+	w := io.Writer(os.Stdout)
+	if path != "" && path != "-" {
+		f, err := os.Create(filepath.Clean(path))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return WriteExport(w, out, ExportFormat(format, path))
+}
+
+func WriteExport(w io.Writer, out *Export, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", " ")
+		return enc.Encode(out)
+	case "csv":
+		return writeCSV(w, out)
+	case "m3u":
+		if out.Kind == ExportAlbums || out.Kind == ExportArtists {
+			return fmt.Errorf("m3u can only hold tracks, use json or csv for %s", out.Kind)
+		}
+		return writeM3U(w, out)
+	case "xspf":
+		if out.Kind == ExportAlbums || out.Kind == ExportArtists {
+			return fmt.Errorf("xspf can only hold tracks, use json or csv for %s", out.Kind)
+		}
+		return writeXSPF(w, out)
+	}
+	return fmt.Errorf("unknown format %q, expected json, csv, m3u or xspf", format)
+}
+
+var csvTrackHeader = []string{"uri", "name", "artists", "album", "isrc", "duration_ms", "added_at"}
+
+func writeCSV(w io.Writer, out *Export) error {
+	cw := csv.NewWriter(w)
+	switch out.Kind {
+	case ExportAlbums:
+		cw.Write([]string{"uri", "name", "artists", "release_date", "upc", "total_tracks", "added_at"})
+		for _, album := range out.Albums {
+			cw.Write([]string{album.URI, album.Name, strings.Join(album.Artists, ";"), album.ReleaseDate, album.UPC, strconv.Itoa(album.TotalTracks), album.AddedAt})
+		}
+	case ExportArtists:
+		cw.Write([]string{"uri", "name", "genres"})
+		for _, artist := range out.Artists {
+			cw.Write([]string{artist.URI, artist.Name, strings.Join(artist.Genres, ";")})
+		}
+	default:
+		cw.Write(csvTrackHeader)
+		for _, track := range out.Tracks {
+			cw.Write([]string{track.URI, track.Name, strings.Join(track.Artists, ";"), track.Album, track.ISRC, strconv.Itoa(track.DurationMs), track.AddedAt})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeM3U(w io.Writer, out *Export) error {
+	fmt.Fprintln(w, "#EXTM3U")
+	fmt.Fprintf(w, "#PLAYLIST:%s\n", out.Name)
+	for _, track := range out.Tracks {
+		fmt.Fprintf(w, "#EXTINF:%d,%s - %s\n", track.DurationMs/1000, strings.Join(track.Artists, ", "), track.Name)
+		if track.Album != "" {
+			fmt.Fprintf(w, "#EXTALB:%s\n", track.Album)
+		}
+		if track.ISRC != "" {
+			fmt.Fprintf(w, "#EXTISRC:%s\n", track.ISRC)
+		}
+		if _, err := fmt.Fprintln(w, track.URI); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"playlist"`
+	Version string      `xml:"version,attr"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	Title   string      `xml:"title"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location   string `xml:"location"`
+	Identifier string `xml:"identifier,omitempty"`
+	Title      string `xml:"title"`
+	Creator    string `xml:"creator"`
+	Album      string `xml:"album,omitempty"`
+	Duration   int    `xml:"duration"`
+}
+
+func writeXSPF(w io.Writer, out *Export) error {
+	playlist := xspfPlaylist{
+		Version: "1",
+		XMLNS:   "http://xspf.org/ns/0/",
+		Title:   out.Name,
+	}
+	for _, track := range out.Tracks {
+		identifier := ""
+		if track.ISRC != "" {
+			identifier = "isrc:" + track.ISRC
+		}
+		playlist.Tracks = append(playlist.Tracks, xspfTrack{
+			Location:   track.URI,
+			Identifier: identifier,
+			Title:      track.Name,
+			Creator:    strings.Join(track.Artists, ", "),
+			Album:      track.Album,
+			Duration:   track.DurationMs,
+		})
+	}
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", " ")
+	if err := enc.Encode(playlist); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
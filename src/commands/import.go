@@ -0,0 +1,379 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/zmb3/spotify/v2"
+
+	"git.asdf.cafe/abs3nt/gospt/src/gctx"
+	"git.asdf.cafe/abs3nt/gospt/src/spotifyurl"
+)
+
+type ImportOptions struct {
+	Format string
+	Name   string
+	Report string
+}
+
+// ReadExport parses a file written by `gospt export`. Csv, m3u and xspf files
+// from other tools are accepted as long as they carry a title and artist.
+func ReadExport(path, format string) (*Export, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	switch ExportFormat(format, path) {
+	case "json":
+		out := &Export{}
+		if err := json.NewDecoder(f).Decode(out); err != nil {
+			return nil, err
+		}
+		if out.Kind == "" {
+			out.Kind = ExportPlaylist
+		}
+		return out, nil
+	case "csv":
+		return readCSV(f, name)
+	case "m3u":
+		return readM3U(f, name)
+	case "xspf":
+		return readXSPF(f, name)
+	}
+	return nil, fmt.Errorf("unknown format for %s, expected json, csv, m3u or xspf", path)
+}
+
+func readCSV(r io.Reader, name string) (*Export, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	out := &Export{Name: name, Kind: ExportPlaylist}
+	if len(records) == 0 {
+		return out, nil
+	}
+	cols := map[string]int{}
+	for idx, col := range records[0] {
+		cols[strings.ToLower(strings.TrimSpace(col))] = idx
+	}
+	get := func(record []string, col string) string {
+		idx, ok := cols[col]
+		if !ok || idx >= len(record) {
+			return ""
+		}
+		return record[idx]
+	}
+	_, hasGenres := cols["genres"]
+	_, hasUPC := cols["upc"]
+	switch {
+	case hasGenres:
+		out.Kind = ExportArtists
+	case hasUPC:
+		out.Kind = ExportAlbums
+	}
+	for _, record := range records[1:] {
+		switch out.Kind {
+		case ExportArtists:
+			out.Artists = append(out.Artists, ExportArtist{URI: get(record, "uri"), Name: get(record, "name")})
+		case ExportAlbums:
+			out.Albums = append(out.Albums, ExportAlbum{URI: get(record, "uri"), Name: get(record, "name"), Artists: splitArtists(get(record, "artists"))})
+		default:
+			duration, _ := strconv.Atoi(get(record, "duration_ms"))
+			out.Tracks = append(out.Tracks, ExportTrack{
+				URI:        get(record, "uri"),
+				Name:       get(record, "name"),
+				Artists:    splitArtists(get(record, "artists")),
+				Album:      get(record, "album"),
+				ISRC:       get(record, "isrc"),
+				DurationMs: duration,
+			})
+		}
+	}
+	return out, nil
+}
+
+func splitArtists(artists string) []string {
+	out := []string{}
+	for _, artist := range strings.FieldsFunc(artists, func(r rune) bool { return r == ';' }) {
+		if artist = strings.TrimSpace(artist); artist != "" {
+			out = append(out, artist)
+		}
+	}
+	return out
+}
+
+func readM3U(r io.Reader, name string) (*Export, error) {
+	out := &Export{Name: name, Kind: ExportPlaylist}
+	track := ExportTrack{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || line == "#EXTM3U":
+		case strings.HasPrefix(line, "#PLAYLIST:"):
+			out.Name = strings.TrimPrefix(line, "#PLAYLIST:")
+		case strings.HasPrefix(line, "#EXTINF:"):
+			info := strings.SplitN(strings.TrimPrefix(line, "#EXTINF:"), ",", 2)
+			secs, _ := strconv.Atoi(info[0])
+			track.DurationMs = secs * 1000
+			if len(info) > 1 {
+				artist, title, found := strings.Cut(info[1], " - ")
+				if found {
+					track.Artists = []string{artist}
+					track.Name = title
+				} else {
+					track.Name = info[1]
+				}
+			}
+		case strings.HasPrefix(line, "#EXTALB:"):
+			track.Album = strings.TrimPrefix(line, "#EXTALB:")
+		case strings.HasPrefix(line, "#EXTISRC:"):
+			track.ISRC = strings.TrimPrefix(line, "#EXTISRC:")
+		case strings.HasPrefix(line, "#"):
+		default:
+			track.URI = line
+			out.Tracks = append(out.Tracks, track)
+			track = ExportTrack{}
+		}
+	}
+	return out, scanner.Err()
+}
+
+func readXSPF(r io.Reader, name string) (*Export, error) {
+	playlist := xspfPlaylist{}
+	if err := xml.NewDecoder(r).Decode(&playlist); err != nil {
+		return nil, err
+	}
+	out := &Export{Name: playlist.Title, Kind: ExportPlaylist}
+	if out.Name == "" {
+		out.Name = name
+	}
+	for _, track := range playlist.Tracks {
+		out.Tracks = append(out.Tracks, ExportTrack{
+			URI:        track.Location,
+			Name:       track.Title,
+			Artists:    []string{track.Creator},
+			Album:      track.Album,
+			ISRC:       strings.TrimPrefix(track.Identifier, "isrc:"),
+			DurationMs: track.Duration,
+		})
+	}
+	return out, nil
+}
+
+func (c *Commands) Import(ctx *gctx.Context, path string, opts ImportOptions) error {
+	in, err := ReadExport(path, opts.Format)
+	if err != nil {
+		return err
+	}
+	switch in.Kind {
+	case ExportAlbums:
+		ids := []spotify.ID{}
+		for _, album := range in.Albums {
+			if id := uriID(album.URI, "album"); id != "" {
+				ids = append(ids, id)
+			}
+		}
+		for start := 0; start < len(ids); start += 50 {
+			err := c.Client().AddAlbumsToLibrary(ctx, ids[start:min(start+50, len(ids))]...)
+			if err != nil {
+				return err
+			}
+		}
+		fmt.Printf("Saved %d of %d albums\n", len(ids), len(in.Albums))
+		return nil
+	case ExportArtists:
+		ids := []spotify.ID{}
+		for _, artist := range in.Artists {
+			if id := uriID(artist.URI, "artist"); id != "" {
+				ids = append(ids, id)
+			}
+		}
+		for start := 0; start < len(ids); start += 50 {
+			err := c.Client().FollowArtist(ctx, ids[start:min(start+50, len(ids))]...)
+			if err != nil {
+				return err
+			}
+		}
+		fmt.Printf("Followed %d of %d artists\n", len(ids), len(in.Artists))
+		return nil
+	}
+	matched, unmatched, err := c.MatchTracks(ctx, in.Tracks)
+	if err != nil {
+		return err
+	}
+	name := opts.Name
+	if name == "" {
+		name = in.Name
+	}
+	if len(matched) > 0 {
+		playlist, err := c.Client().CreatePlaylistForUser(ctx, c.User(), name, "Imported by gospt", false, false)
+		if err != nil {
+			return err
+		}
+		err = c.AddTracksToPlaylist(ctx, playlist.ID, matched)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported %d of %d tracks into %s\n", len(matched), len(in.Tracks), name)
+	} else {
+		fmt.Printf("No tracks matched, %s was not created\n", name)
+	}
+	if len(unmatched) > 0 {
+		fmt.Printf("Could not match %d tracks:\n", len(unmatched))
+		for _, track := range unmatched {
+			fmt.Printf("  %s - %s\n", strings.Join(track.Artists, ", "), track.Name)
+		}
+	}
+	if len(unmatched) > 0 && opts.Report != "" {
+		f, err := os.Create(filepath.Clean(opts.Report))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := writeCSV(f, &Export{Name: name, Kind: ExportPlaylist, Tracks: unmatched}); err != nil {
+			return err
+		}
+	}
+	if len(matched) == 0 {
+		return fmt.Errorf("none of the %d tracks matched, no playlist was created", len(in.Tracks))
+	}
+	return nil
+}
+
+// MatchTracks resolves exported tracks to spotify ids by uri, then isrc, then
+// a fuzzy artist and title search. The order of the input is kept.
+func (c *Commands) MatchTracks(ctx *gctx.Context, tracks []ExportTrack) ([]spotify.ID, []ExportTrack, error) {
+	found := make([]spotify.ID, len(tracks))
+	byURI := []int{}
+	for idx, track := range tracks {
+		if uriID(track.URI, "track") != "" {
+			byURI = append(byURI, idx)
+		}
+	}
+	for start := 0; start < len(byURI); start += 50 {
+		batch := byURI[start:min(start+50, len(byURI))]
+		ids := []spotify.ID{}
+		for _, idx := range batch {
+			ids = append(ids, uriID(tracks[idx].URI, "track"))
+		}
+		full, err := c.Client().GetTracks(ctx, ids)
+		if err != nil {
+			// a single unknown or malformed id fails the whole batch, so
+			// the tracks are looked up one by one and the ones that still
+			// fail are left to the isrc and fuzzy search below
+			full = make([]*spotify.FullTrack, len(ids))
+			for pos, id := range ids {
+				if ctx.Err() != nil {
+					return nil, nil, ctx.Err()
+				}
+				full[pos], _ = c.Client().GetTrack(ctx, id)
+			}
+		}
+		for pos, track := range full {
+			if track != nil && pos < len(batch) {
+				found[batch[pos]] = track.ID
+			}
+		}
+	}
+	matched := []spotify.ID{}
+	unmatched := []ExportTrack{}
+	for idx, track := range tracks {
+		if found[idx] == "" && track.ISRC != "" {
+			result, err := c.Client().Search(ctx, "isrc:"+track.ISRC, spotify.SearchTypeTrack, spotify.Limit(1))
+			if err == nil && result.Tracks != nil && len(result.Tracks.Tracks) > 0 {
+				found[idx] = result.Tracks.Tracks[0].ID
+			}
+		}
+		if found[idx] == "" && track.Name != "" {
+			found[idx] = c.fuzzyMatch(ctx, track)
+		}
+		if found[idx] == "" {
+			unmatched = append(unmatched, track)
+			continue
+		}
+		matched = append(matched, found[idx])
+	}
+	return matched, unmatched, nil
+}
+
+func (c *Commands) fuzzyMatch(ctx *gctx.Context, track ExportTrack) spotify.ID {
+	query := "track:" + strconv.Quote(track.Name)
+	if len(track.Artists) > 0 {
+		query += " artist:" + strconv.Quote(track.Artists[0])
+	}
+	result, err := c.Search(ctx, query, spotify.SearchTypeTrack, 1)
+	if err != nil || result.Tracks == nil {
+		return ""
+	}
+	best := spotify.ID("")
+	bestScore := 2
+	for _, candidate := range result.Tracks.Tracks {
+		score := 0
+		name, want := normalizeTitle(candidate.Name), normalizeTitle(track.Name)
+		if name == want {
+			score += 2
+		} else if strings.Contains(name, want) || strings.Contains(want, name) {
+			score++
+		}
+		artistMatch := false
+		for _, artist := range candidate.Artists {
+			for _, wantArtist := range track.Artists {
+				if normalizeTitle(artist.Name) == normalizeTitle(wantArtist) {
+					score += 2
+					artistMatch = true
+				}
+			}
+		}
+		// a title and duration alone match covers and karaoke versions too
+		if len(track.Artists) > 0 && !artistMatch {
+			continue
+		}
+		if track.DurationMs > 0 {
+			diff := int(candidate.Duration) - track.DurationMs
+			if diff < 3000 && diff > -3000 {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = candidate.ID, score
+		}
+	}
+	return best
+}
+
+// normalizeTitle lowercases a title and drops bracketed parts like
+// "(Remastered 2011)" and punctuation so near identical titles compare equal.
+func normalizeTitle(s string) string {
+	s = strings.ToLower(s)
+	if idx := strings.IndexAny(s, "(["); idx > 0 {
+		s = s[:idx]
+	}
+	if idx := strings.Index(s, " - "); idx > 0 {
+		s = s[:idx]
+	}
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
+
+// uriID returns the id of a spotify uri or open.spotify.com url of the given
+// type, or an empty id.
+func uriID(uri, kind string) spotify.ID {
+	target, err := spotifyurl.Parse(uri)
+	if err != nil || string(target.Kind) != kind {
+		return ""
+	}
+	return target.ID
+}
@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zmb3/spotify/v2"
+
+	"git.asdf.cafe/abs3nt/gospt/src/gctx"
+)
+
+func TestMatchTracks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/tracks":
+			// a batch with an unknown id fails as a whole
+			if strings.Contains(r.URL.Query().Get("ids"), "bad") {
+				http.Error(w, `{"error":{"status":400,"message":"invalid id"}}`, http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `{"tracks":[{"id":"good","name":"Song"}]}`)
+		case r.URL.Path == "/tracks/good":
+			fmt.Fprint(w, `{"id":"good","name":"Song"}`)
+		case r.URL.Path == "/tracks/bad":
+			http.Error(w, `{"error":{"status":400,"message":"invalid id"}}`, http.StatusBadRequest)
+		case r.URL.Path == "/search":
+			// only a cover with the same title and length is found
+			fmt.Fprint(w, `{"tracks":{"items":[{"id":"cover","name":"Song","duration_ms":200000,"artists":[{"name":"Someone Else"}]}]}}`)
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+		}
+	}))
+	defer server.Close()
+	ctx := gctx.NewContext(context.Background())
+	c := NewWithClient(ctx, spotify.New(server.Client(), spotify.WithBaseURL(server.URL+"/")))
+
+	tracks := []ExportTrack{
+		{URI: "spotify:track:good", Name: "Song"},
+		{URI: "spotify:track:bad", Name: "Song", Artists: []string{"Band"}, DurationMs: 200000},
+	}
+	matched, unmatched, err := c.MatchTracks(ctx, tracks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(matched, []spotify.ID{"good"}) {
+		t.Errorf("matched = %v", matched)
+	}
+	if len(unmatched) != 1 || unmatched[0].URI != "spotify:track:bad" {
+		t.Errorf("unmatched = %v", unmatched)
+	}
+}
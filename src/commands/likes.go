@@ -0,0 +1,164 @@
+package commands
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/zmb3/spotify/v2"
+
+	"git.asdf.cafe/abs3nt/gospt/src/gctx"
+	"git.asdf.cafe/abs3nt/gospt/src/spotifyurl"
+)
+
+// libraryPaths are the library endpoints for everything that can be liked.
+var libraryPaths = map[spotifyurl.Kind]string{
+	spotifyurl.Track:   "me/tracks",
+	spotifyurl.Album:   "me/albums",
+	spotifyurl.Episode: "me/episodes",
+	spotifyurl.Show:    "me/shows",
+}
+
+// likeTargets resolves the uris or urls to like, or the current track or
+// episode when there are none.
+func (c *Commands) likeTargets(ctx *gctx.Context, queries []string) ([]spotifyurl.Target, error) {
+	if len(queries) == 0 {
+		playing, err := c.CurrentlyPlaying(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if playing.Item == nil {
+			return nil, fmt.Errorf("nothing is playing")
+		}
+		kind := spotifyurl.Track
+		if IsEpisode(playing.Item) {
+			kind = spotifyurl.Episode
+		} else if IsChapter(playing.Item) {
+			return nil, fmt.Errorf("audiobook chapters can not be liked")
+		}
+		return []spotifyurl.Target{{Kind: kind, ID: playing.Item.ID}}, nil
+	}
+	targets := []spotifyurl.Target{}
+	for _, query := range queries {
+		target, err := spotifyurl.Resolve(ctx, http.DefaultClient, query)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := libraryPaths[target.Kind]; !ok {
+			return nil, fmt.Errorf("can not like %s, only tracks, albums, episodes and shows", query)
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+func groupByKind(targets []spotifyurl.Target) map[spotifyurl.Kind][]spotify.ID {
+	kinds := map[spotifyurl.Kind][]spotify.ID{}
+	for _, target := range targets {
+		kinds[target.Kind] = append(kinds[target.Kind], target.ID)
+	}
+	return kinds
+}
+
+func joinIDs(ids []spotify.ID) string {
+	out := []string{}
+	for _, id := range ids {
+		out = append(out, string(id))
+	}
+	return strings.Join(out, ",")
+}
+
+// InLibrary reports which items of one kind are saved in the library.
+func (c *Commands) InLibrary(ctx *gctx.Context, kind spotifyurl.Kind, ids []spotify.ID) (map[spotify.ID]bool, error) {
+	saved := map[spotify.ID]bool{}
+	for start := 0; start < len(ids); start += 50 {
+		batch := ids[start:min(start+50, len(ids))]
+		contains := []bool{}
+		query := url.Values{"ids": {joinIDs(batch)}}
+		err := c.api(ctx, http.MethodGet, libraryPaths[kind]+"/contains", query, nil, &contains)
+		if err != nil {
+			return nil, err
+		}
+		for idx, id := range batch {
+			saved[id] = idx < len(contains) && contains[idx]
+		}
+	}
+	return saved, nil
+}
+
+// SetInLibrary saves or removes items of one kind.
+func (c *Commands) SetInLibrary(ctx *gctx.Context, kind spotifyurl.Kind, ids []spotify.ID, saved bool) error {
+	method := http.MethodPut
+	if !saved {
+		method = http.MethodDelete
+	}
+	for start := 0; start < len(ids); start += 50 {
+		query := url.Values{"ids": {joinIDs(ids[start:min(start+50, len(ids))])}}
+		err := c.api(ctx, method, libraryPaths[kind], query, nil, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Like saves tracks, albums, episodes and shows given by uri or url, or the
+// current track when none are given.
+func (c *Commands) Like(ctx *gctx.Context, queries ...string) error {
+	return c.setLiked(ctx, queries, true)
+}
+
+// Unlike removes tracks, albums, episodes and shows given by uri or url, or
+// the current track when none are given.
+func (c *Commands) Unlike(ctx *gctx.Context, queries ...string) error {
+	return c.setLiked(ctx, queries, false)
+}
+
+func (c *Commands) setLiked(ctx *gctx.Context, queries []string, liked bool) error {
+	targets, err := c.likeTargets(ctx, queries)
+	if err != nil {
+		return err
+	}
+	for kind, ids := range groupByKind(targets) {
+		if err := c.SetInLibrary(ctx, kind, ids, liked); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ToggleLike unlikes what is in the library and likes the rest.
+func (c *Commands) ToggleLike(ctx *gctx.Context, queries ...string) error {
+	targets, err := c.likeTargets(ctx, queries)
+	if err != nil {
+		return err
+	}
+	for kind, ids := range groupByKind(targets) {
+		saved, err := c.InLibrary(ctx, kind, ids)
+		if err != nil {
+			return err
+		}
+		like, unlike := []spotify.ID{}, []spotify.ID{}
+		for _, id := range ids {
+			if saved[id] {
+				unlike = append(unlike, id)
+			} else {
+				like = append(like, id)
+			}
+		}
+		if err := c.SetInLibrary(ctx, kind, like, true); err != nil {
+			return err
+		}
+		if err := c.SetInLibrary(ctx, kind, unlike, false); err != nil {
+			return err
+		}
+		for _, id := range like {
+			fmt.Println("Liked", spotifyurl.Target{Kind: kind, ID: id}.URI())
+		}
+		for _, id := range unlike {
+			fmt.Println("Unliked", spotifyurl.Target{Kind: kind, ID: id}.URI())
+		}
+	}
+	return nil
+}
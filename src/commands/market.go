@@ -0,0 +1,30 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/zmb3/spotify/v2"
+
+	"git.asdf.cafe/abs3nt/gospt/src/config"
+)
+
+// Market is the market tracks and albums are looked up in: the market set in
+// the config, else the country of the account, else the market spotify
+// derives from the token.
+func (c *Commands) Market() string {
+	if market := strings.TrimSpace(config.Values.Market); market != "" {
+		if strings.EqualFold(market, "from_token") {
+			return "from_token"
+		}
+		return strings.ToUpper(market)
+	}
+	c.Client()
+	if c.country != "" {
+		return c.country
+	}
+	return "from_token"
+}
+
+func (c *Commands) market() spotify.RequestOption {
+	return spotify.Market(c.Market())
+}
@@ -0,0 +1,355 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zmb3/spotify/v2"
+
+	"git.asdf.cafe/abs3nt/gospt/src/gctx"
+)
+
+type DedupeReason string
+
+const (
+	DedupeDuplicate  DedupeReason = "duplicate"
+	DedupeISRC       DedupeReason = "isrc duplicate"
+	DedupeLocal      DedupeReason = "local"
+	DedupeUnplayable DedupeReason = "unplayable"
+)
+
+type DedupeOptions struct {
+	ISRC       bool
+	Local      bool
+	Unplayable bool
+	DryRun     bool
+	Yes        bool
+}
+
+// DedupeEntry is a single playlist position that is flagged for removal.
+// Original is the position of the entry that is kept, or -1 when there is none.
+type DedupeEntry struct {
+	Position int
+	URI      spotify.URI
+	Name     string
+	Artist   string
+	Reason   DedupeReason
+	Original int
+}
+
+// FindPlaylist resolves a playlist from an id, uri, url or the name of one of
+// the users playlists.
+func (c *Commands) FindPlaylist(ctx *gctx.Context, query string) (*spotify.SimplePlaylist, error) {
+	if id := uriID(query, "playlist"); id != "" {
+		playlist, err := c.Client().GetPlaylist(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return &playlist.SimplePlaylist, nil
+	}
+	for page := 1; ; page++ {
+		playlists, err := c.Playlists(ctx, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, playlist := range playlists.Playlists {
+			if strings.EqualFold(playlist.Name, query) || string(playlist.ID) == query {
+				playlist := playlist
+				return &playlist, nil
+			}
+		}
+		if len(playlists.Playlists) < 50 {
+			break
+		}
+	}
+	return nil, fmt.Errorf("no playlist found matching %q", query)
+}
+
+// AllPlaylistItems pages through every item of a playlist. Items are requested
+// for the users market so that unplayable tracks are reported as such.
+func (c *Commands) AllPlaylistItems(ctx *gctx.Context, playlist spotify.ID) ([]spotify.PlaylistItem, error) {
+	items := []spotify.PlaylistItem{}
+	for page := 0; ; page++ {
+		tracks, err := c.Client().GetPlaylistItems(ctx, playlist, spotify.Limit(50), spotify.Offset(page*50), c.market())
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, tracks.Items...)
+		if len(tracks.Items) < 50 {
+			break
+		}
+	}
+	return items, nil
+}
+
+// playlistSnapshot is the snapshot id of the current version of a playlist.
+func (c *Commands) playlistSnapshot(ctx *gctx.Context, playlist spotify.ID) (string, error) {
+	found, err := c.Client().GetPlaylist(ctx, playlist, spotify.Fields("snapshot_id"))
+	if err != nil {
+		return "", err
+	}
+	return found.SnapshotID, nil
+}
+
+// ScanPlaylistDuplicates finds exact id duplicates, isrc duplicates and local
+// or unplayable items in a playlist. The first occurrence of a track is kept.
+// The snapshot id is the version of the playlist the positions refer to.
+func (c *Commands) ScanPlaylistDuplicates(ctx *gctx.Context, playlist spotify.ID, opts DedupeOptions) ([]DedupeEntry, string, error) {
+	snapshot, err := c.playlistSnapshot(ctx, playlist)
+	if err != nil {
+		return nil, "", err
+	}
+	items, err := c.AllPlaylistItems(ctx, playlist)
+	if err != nil {
+		return nil, "", err
+	}
+	// the pages only line up when nothing changed while they were read
+	if after, err := c.playlistSnapshot(ctx, playlist); err != nil {
+		return nil, "", err
+	} else if after != snapshot {
+		return nil, "", fmt.Errorf("the playlist changed while it was scanned, try again")
+	}
+	entries := []DedupeEntry{}
+	seenIDs := map[spotify.ID]int{}
+	seenISRC := map[string]int{}
+	for pos, item := range items {
+		entry := DedupeEntry{Position: pos, Original: -1}
+		var id spotify.ID
+		isrc := ""
+		playable := true
+		switch {
+		case item.Track.Track != nil:
+			track := item.Track.Track
+			id = track.ID
+			entry.URI = track.URI
+			entry.Name = track.Name
+			if len(track.Artists) > 0 {
+				entry.Artist = track.Artists[0].Name
+			}
+			isrc = strings.ToUpper(track.ExternalIDs["isrc"])
+			if track.IsPlayable != nil && !*track.IsPlayable {
+				playable = false
+			}
+		case item.Track.Episode != nil:
+			episode := item.Track.Episode
+			id = episode.ID
+			entry.URI = episode.URI
+			entry.Name = episode.Name
+			entry.Artist = episode.Show.Name
+			playable = episode.IsPlayable
+		default:
+			// spotify returns a null track for content that is gone from the market
+			playable = false
+		}
+		switch {
+		case item.IsLocal:
+			if !opts.Local {
+				continue
+			}
+			entry.Reason = DedupeLocal
+		case !playable:
+			if !opts.Unplayable {
+				continue
+			}
+			entry.Reason = DedupeUnplayable
+		case id != "" && hasKey(seenIDs, id):
+			entry.Reason = DedupeDuplicate
+			entry.Original = seenIDs[id]
+		case opts.ISRC && isrc != "" && hasKey(seenISRC, isrc):
+			entry.Reason = DedupeISRC
+			entry.Original = seenISRC[isrc]
+		default:
+			if id != "" {
+				seenIDs[id] = pos
+			}
+			if isrc != "" {
+				seenISRC[isrc] = pos
+			}
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, snapshot, nil
+}
+
+func hasKey[K comparable, V any](m map[K]V, key K) bool {
+	_, ok := m[key]
+	return ok
+}
+
+// RemovePlaylistEntries removes the given positions of a playlist snapshot in
+// batches of 100. Batches are sent from the end of the playlist backwards so
+// earlier positions stay valid between requests, and each one names the
+// snapshot the one before left, so spotify refuses positions of a playlist
+// that was changed in the meantime.
+func (c *Commands) RemovePlaylistEntries(ctx *gctx.Context, playlist spotify.ID, snapshot string, entries []DedupeEntry) (int, error) {
+	removable := []DedupeEntry{}
+	for _, entry := range entries {
+		if entry.URI != "" {
+			removable = append(removable, entry)
+		}
+	}
+	sort.Slice(removable, func(i, j int) bool { return removable[i].Position > removable[j].Position })
+	removed := 0
+	for start := 0; start < len(removable); start += 100 {
+		end := start + 100
+		if end > len(removable) {
+			end = len(removable)
+		}
+		trackGroups := []spotify.TrackToRemove{}
+		for _, entry := range removable[start:end] {
+			trackGroups = append(trackGroups, spotify.TrackToRemove{
+				URI:       string(entry.URI),
+				Positions: []int{entry.Position},
+			})
+		}
+		var err error
+		snapshot, err = c.Client().RemoveTracksFromPlaylistOpt(ctx, playlist, trackGroups, snapshot)
+		if err != nil {
+			return removed, fmt.Errorf("error clearing playlist: %w", err)
+		}
+		removed += len(trackGroups)
+	}
+	return removed, nil
+}
+
+func (c *Commands) DedupePlaylist(ctx *gctx.Context, query string, opts DedupeOptions) error {
+	playlist, err := c.FindPlaylist(ctx, query)
+	if err != nil {
+		return err
+	}
+	entries, snapshot, err := c.ScanPlaylistDuplicates(ctx, playlist.ID, opts)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Printf("%s has nothing to clean up\n", playlist.Name)
+		return nil
+	}
+	PrintDedupe(playlist, entries)
+	if opts.DryRun {
+		return nil
+	}
+	if !opts.Yes {
+		entries = choose(entries)
+		if len(entries) == 0 {
+			return nil
+		}
+	}
+	removed, err := c.RemovePlaylistEntries(ctx, playlist.ID, snapshot, entries)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Removed %d entries from %s\n", removed, playlist.Name)
+	if removed < len(entries) {
+		fmt.Printf("%d unavailable entries have no uri and were left in place\n", len(entries)-removed)
+	}
+	return nil
+}
+
+func PrintDedupe(playlist *spotify.SimplePlaylist, entries []DedupeEntry) {
+	fmt.Printf("--- %s\n", playlist.Name)
+	for _, entry := range entries {
+		name := entry.Name
+		if name == "" {
+			name = "<unavailable>"
+		}
+		if entry.Artist != "" {
+			name += " - " + entry.Artist
+		}
+		reason := string(entry.Reason)
+		if entry.Original >= 0 {
+			reason = fmt.Sprintf("%s of #%d", entry.Reason, entry.Original+1)
+		}
+		fmt.Printf("- #%-5d %s (%s)\n", entry.Position+1, name, reason)
+	}
+}
+
+// dedupeReasons are the words that pick the entries found for a reason.
+var dedupeReasons = map[string]DedupeReason{
+	"duplicate":  DedupeDuplicate,
+	"isrc":       DedupeISRC,
+	"local":      DedupeLocal,
+	"unplayable": DedupeUnplayable,
+}
+
+// choose asks which of the entries to remove until the answer can be read.
+func choose(entries []DedupeEntry) []DedupeEntry {
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Print("Remove which entries? all, positions like 3 5-7, duplicate, isrc, local, unplayable or none [none] ")
+		answer, err := reader.ReadString('\n')
+		if err != nil && answer == "" {
+			return nil
+		}
+		picked, err := PickDedupe(entries, answer)
+		if err == nil {
+			return picked
+		}
+		fmt.Println(err)
+	}
+}
+
+// PickDedupe picks the entries an answer names, by position, range or reason.
+// An empty answer picks none.
+func PickDedupe(entries []DedupeEntry, answer string) ([]DedupeEntry, error) {
+	words := strings.Fields(strings.ToLower(strings.ReplaceAll(answer, ",", " ")))
+	if len(words) == 1 && (words[0] == "all" || words[0] == "y" || words[0] == "yes") {
+		return entries, nil
+	}
+	if len(words) == 0 || len(words) == 1 && (words[0] == "none" || words[0] == "n" || words[0] == "no") {
+		return nil, nil
+	}
+	picked := map[int]bool{}
+	for _, word := range words {
+		if reason, ok := dedupeReasons[word]; ok {
+			for _, entry := range entries {
+				if entry.Reason == reason {
+					picked[entry.Position] = true
+				}
+			}
+			continue
+		}
+		from, to, err := positionRange(strings.TrimPrefix(word, "#"))
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, entry := range entries {
+			if entry.Position+1 >= from && entry.Position+1 <= to {
+				picked[entry.Position], found = true, true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%s is not one of the entries found", word)
+		}
+	}
+	chosen := []DedupeEntry{}
+	for _, entry := range entries {
+		if picked[entry.Position] {
+			chosen = append(chosen, entry)
+		}
+	}
+	return chosen, nil
+}
+
+// positionRange reads a position like 3 or a range like 5-7.
+func positionRange(word string) (int, int, error) {
+	first, last, isRange := strings.Cut(word, "-")
+	from, err := strconv.Atoi(first)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%q is not a position, range or reason", word)
+	}
+	if !isRange {
+		return from, from, nil
+	}
+	to, err := strconv.Atoi(last)
+	if err != nil || to < from {
+		return 0, 0, fmt.Errorf("%q is not a position, range or reason", word)
+	}
+	return from, to, nil
+}
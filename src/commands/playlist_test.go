@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/zmb3/spotify/v2"
+
+	"git.asdf.cafe/abs3nt/gospt/src/gctx"
+)
+
+func TestPickDedupe(t *testing.T) {
+	entries := []DedupeEntry{
+		{Position: 2, Reason: DedupeDuplicate, Original: 0},
+		{Position: 4, Reason: DedupeISRC, Original: 1},
+		{Position: 5, Reason: DedupeDuplicate, Original: 1},
+		{Position: 8, Reason: DedupeLocal, Original: -1},
+	}
+	tests := []struct {
+		answer string
+		want   []int
+		err    bool
+	}{
+		{"", nil, false},
+		{"none", nil, false},
+		{"all", []int{2, 4, 5, 8}, false},
+		{"y", []int{2, 4, 5, 8}, false},
+		{"3", []int{2}, false},
+		{"#9, 5-6", []int{4, 5, 8}, false},
+		{"duplicate", []int{2, 5}, false},
+		{"isrc local", []int{4, 8}, false},
+		{"1", nil, true},
+		{"7-5", nil, true},
+		{"everything", nil, true},
+	}
+	for _, test := range tests {
+		picked, err := PickDedupe(entries, test.answer)
+		if (err != nil) != test.err {
+			t.Errorf("PickDedupe(%q) error = %v, want error %v", test.answer, err, test.err)
+			continue
+		}
+		var got []int
+		for _, entry := range picked {
+			got = append(got, entry.Position)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("PickDedupe(%q) = %v, want %v", test.answer, got, test.want)
+		}
+	}
+}
+
+func TestRemovePlaylistEntriesChainsSnapshots(t *testing.T) {
+	snapshots := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete || r.URL.Path != "/playlists/p1/tracks" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		body := struct {
+			Snapshot string `json:"snapshot_id"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		snapshots = append(snapshots, body.Snapshot)
+		fmt.Fprintf(w, `{"snapshot_id":"s%d"}`, len(snapshots)+1)
+	}))
+	defer server.Close()
+	ctx := gctx.NewContext(context.Background())
+	c := NewWithClient(ctx, spotify.New(server.Client(), spotify.WithBaseURL(server.URL+"/")))
+
+	entries := []DedupeEntry{}
+	for pos := 0; pos < 150; pos++ {
+		entries = append(entries, DedupeEntry{Position: pos, URI: "spotify:track:t"})
+	}
+	removed, err := c.RemovePlaylistEntries(ctx, "p1", "s1", entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 150 || !reflect.DeepEqual(snapshots, []string{"s1", "s2"}) {
+		t.Fatalf("removed = %d, snapshots = %v", removed, snapshots)
+	}
+}
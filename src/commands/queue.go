@@ -0,0 +1,340 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+
+	"git.asdf.cafe/abs3nt/gospt/src/gctx"
+	"git.asdf.cafe/abs3nt/gospt/src/lock"
+)
+
+// QueueItem is a track in the gospt managed queue. Spotify's own queue can only
+// be appended to, so gospt keeps its own queue that can be reordered and hands
+// the first track to spotify just before the current one ends.
+type QueueItem struct {
+	ID       spotify.ID `json:"id"`
+	Name     string     `json:"name"`
+	Artist   string     `json:"artist"`
+	Duration int        `json:"duration_ms"`
+}
+
+type QueuePosition string
+
+const (
+	// QueueNext puts tracks at the front of the gospt queue.
+	QueueNext QueuePosition = "next"
+	// QueueLast puts tracks at the end of the gospt queue.
+	QueueLast QueuePosition = "last"
+	// QueueSpotify adds tracks straight to the end of spotify's queue.
+	QueueSpotify QueuePosition = "spotify"
+)
+
+var queueMu sync.Mutex
+
+func queuePath() string {
+	configDir, _ := os.UserConfigDir()
+	return filepath.Join(configDir, "gospt/queue.json")
+}
+
+// lockQueue locks the queue against this and other gospt processes and
+// returns the function unlocking it.
+func lockQueue() (func(), error) {
+	queueMu.Lock()
+	fileLock := lock.New(filepath.Join(filepath.Dir(queuePath()), "queue.lock"))
+	if err := fileLock.Lock(); err != nil {
+		queueMu.Unlock()
+		return nil, err
+	}
+	return func() {
+		fileLock.Unlock()
+		queueMu.Unlock()
+	}, nil
+}
+
+func (c *Commands) LoadQueue() ([]QueueItem, error) {
+	unlock, err := lockQueue()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return loadQueue()
+}
+
+func loadQueue() ([]QueueItem, error) {
+	items := []QueueItem{}
+	data, err := os.ReadFile(queuePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return items, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", queuePath(), err)
+	}
+	return items, nil
+}
+
+// editQueue loads the queue, applies fn and writes the result back.
+func editQueue(fn func([]QueueItem) ([]QueueItem, error)) error {
+	unlock, err := lockQueue()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	items, err := loadQueue()
+	if err != nil {
+		return err
+	}
+	items, err = fn(items)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(queuePath()), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(queuePath(), data, 0o600)
+}
+
+func queueItem(track spotify.SimpleTrack) QueueItem {
+	artist := ""
+	if len(track.Artists) > 0 {
+		artist = track.Artists[0].Name
+	}
+	return QueueItem{ID: track.ID, Name: track.Name, Artist: artist, Duration: int(track.Duration)}
+}
+
+// CollectionTracks returns the tracks of a track, album, playlist or an
+// artist's top tracks. query is an id, uri or url, playlists can also be
+// found by name.
+func (c *Commands) CollectionTracks(ctx *gctx.Context, kind, query string) ([]QueueItem, error) {
+	id := uriID(query, kind)
+	if id == "" {
+		id = spotify.ID(query)
+	}
+	items := []QueueItem{}
+	switch kind {
+	case "track":
+		track, err := c.Client().GetTrack(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, queueItem(track.SimpleTrack))
+	case "album":
+		for page := 1; ; page++ {
+			tracks, err := c.AlbumTracks(ctx, id, page)
+			if err != nil {
+				return nil, err
+			}
+			for _, track := range tracks.Tracks {
+				items = append(items, queueItem(track))
+			}
+			if len(tracks.Tracks) < 50 {
+				break
+			}
+		}
+	case "playlist":
+		playlist, err := c.FindPlaylist(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		playlistItems, err := c.AllPlaylistItems(ctx, playlist.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range playlistItems {
+			if item.Track.Track == nil || item.IsLocal {
+				continue
+			}
+			items = append(items, queueItem(item.Track.Track.SimpleTrack))
+		}
+	case "artist":
+		tracks, err := c.ArtistTopTracks(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		for _, track := range tracks {
+			items = append(items, queueItem(track.SimpleTrack))
+		}
+	default:
+		return nil, fmt.Errorf("cannot queue %q, use track, album, playlist or artist", kind)
+	}
+	return items, nil
+}
+
+// QueueTracks adds items to the gospt queue or straight to spotify's queue.
+func (c *Commands) QueueTracks(ctx *gctx.Context, items []QueueItem, pos QueuePosition) error {
+	switch pos {
+	case QueueSpotify:
+		for _, item := range items {
+			if err := c.QueueSong(ctx, item.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	case QueueNext:
+		return editQueue(func(queue []QueueItem) ([]QueueItem, error) {
+			return append(append([]QueueItem{}, items...), queue...), nil
+		})
+	case QueueLast:
+		return editQueue(func(queue []QueueItem) ([]QueueItem, error) {
+			return append(queue, items...), nil
+		})
+	}
+	return fmt.Errorf("unknown queue position %q", pos)
+}
+
+// QueueCollection queues the tracks returned by CollectionTracks and returns
+// how many were queued.
+func (c *Commands) QueueCollection(ctx *gctx.Context, kind, query string, pos QueuePosition) (int, error) {
+	items, err := c.CollectionTracks(ctx, kind, query)
+	if err != nil {
+		return 0, err
+	}
+	return len(items), c.QueueTracks(ctx, items, pos)
+}
+
+// RemoveFromQueue removes the tracks at the given 1-based positions of the
+// gospt queue.
+func (c *Commands) RemoveFromQueue(positions []int) error {
+	return editQueue(func(queue []QueueItem) ([]QueueItem, error) {
+		sort.Sort(sort.Reverse(sort.IntSlice(positions)))
+		for idx, pos := range positions {
+			if idx > 0 && positions[idx-1] == pos {
+				continue
+			}
+			if pos < 1 || pos > len(queue) {
+				return nil, fmt.Errorf("no track at position %d", pos)
+			}
+			queue = append(queue[:pos-1], queue[pos:]...)
+		}
+		return queue, nil
+	})
+}
+
+// RemoveQueuedTrack removes the first occurrence of a track from the gospt
+// queue. Positions go stale when another process feeds or edits the queue,
+// ids do not. A track that is not queued anymore is not an error.
+func (c *Commands) RemoveQueuedTrack(id spotify.ID) error {
+	return editQueue(func(queue []QueueItem) ([]QueueItem, error) {
+		for idx, item := range queue {
+			if item.ID == id {
+				return append(queue[:idx], queue[idx+1:]...), nil
+			}
+		}
+		return queue, nil
+	})
+}
+
+// MoveInQueue moves the track at 1-based position from to position to.
+func (c *Commands) MoveInQueue(from, to int) error {
+	return editQueue(func(queue []QueueItem) ([]QueueItem, error) {
+		if from < 1 || from > len(queue) || to < 1 || to > len(queue) {
+			return nil, fmt.Errorf("positions must be between 1 and %d", len(queue))
+		}
+		item := queue[from-1]
+		queue = append(queue[:from-1], queue[from:]...)
+		return append(queue[:to-1], append([]QueueItem{item}, queue[to-1:]...)...), nil
+	})
+}
+
+func (c *Commands) ClearQueue() error {
+	return editQueue(func(queue []QueueItem) ([]QueueItem, error) {
+		return []QueueItem{}, nil
+	})
+}
+
+func (c *Commands) PrintQueue(ctx *gctx.Context) error {
+	queue, err := c.UserQueue(ctx)
+	if err != nil {
+		return err
+	}
+	if queue.CurrentlyPlaying.ID != "" {
+		fmt.Printf("Now playing: %s\n", trackLabel(queue.CurrentlyPlaying))
+	}
+	items, err := c.LoadQueue()
+	if err != nil {
+		return err
+	}
+	if len(items) > 0 {
+		fmt.Println("\ngospt queue:")
+		for idx, item := range items {
+			fmt.Printf("%3d. %s - %s\n", idx+1, item.Name, item.Artist)
+		}
+	}
+	if len(queue.Items) > 0 {
+		fmt.Println("\nspotify queue:")
+		for idx, track := range queue.Items {
+			fmt.Printf("%3d. %s\n", idx+1, trackLabel(track))
+		}
+	}
+	return nil
+}
+
+// trackLabel names a track of spotify's queue, episodes come without artists.
+func trackLabel(track spotify.FullTrack) string {
+	if len(track.Artists) == 0 {
+		return track.Name
+	}
+	return track.Name + " - " + track.Artists[0].Name
+}
+
+// QueueFeeder hands the first track of the gospt queue to spotify when the
+// current track is about to end. It is fed the player state on every poll.
+// Only one process feeds at a time, otherwise every running TUI and watch
+// would queue the same track.
+type QueueFeeder struct {
+	c     *Commands
+	lead  time.Duration
+	fed   bool
+	owner *lock.File
+}
+
+// NewQueueFeeder returns a feeder that queues the next track lead before the
+// current one ends. lead has to be longer than the poll interval.
+func (c *Commands) NewQueueFeeder(lead time.Duration) *QueueFeeder {
+	return &QueueFeeder{c: c, lead: lead, owner: lock.New(filepath.Join(filepath.Dir(queuePath()), "queue_feeder.lock"))}
+}
+
+// Close lets another process take over feeding the queue.
+func (f *QueueFeeder) Close() error {
+	return f.owner.Unlock()
+}
+
+func (f *QueueFeeder) Update(ctx *gctx.Context, state *spotify.PlayerState) {
+	if state == nil || !state.Playing || state.Item == nil || !f.owner.TryLock() {
+		return
+	}
+	remaining := time.Duration(int(state.Item.Duration)-int(state.Progress)) * time.Millisecond
+	if remaining > f.lead {
+		f.fed = false
+		return
+	}
+	if f.fed {
+		return
+	}
+	f.fed = true
+	// the queue is not locked while spotify is asked, the track is taken out
+	// by id afterwards in case the queue was edited meanwhile
+	queue, err := f.c.LoadQueue()
+	if err == nil && len(queue) > 0 {
+		if err = f.c.QueueSong(ctx, queue[0].ID); err == nil {
+			err = f.c.RemoveQueuedTrack(queue[0].ID)
+		}
+	}
+	if err != nil {
+		ctx.Debug.Trace().Err(err).Msg("failed to feed queue")
+	}
+}
@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+
+	"git.asdf.cafe/abs3nt/gospt/src/gctx"
+)
+
+func TestQueueFeeder(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	var mu sync.Mutex
+	queued := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/me/player/queue" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		mu.Lock()
+		queued = append(queued, r.URL.Query().Get("uri"))
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	ctx := gctx.NewContext(context.Background())
+	c := NewWithClient(ctx, spotify.New(server.Client(), spotify.WithBaseURL(server.URL+"/")))
+	items := []QueueItem{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "b"}}
+	if err := c.QueueTracks(ctx, items, QueueLast); err != nil {
+		t.Fatal(err)
+	}
+
+	// the track played from the queue view is taken out by id
+	if err := c.RemoveQueuedTrack("b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RemoveQueuedTrack("missing"); err != nil {
+		t.Fatal(err)
+	}
+	queue, err := c.LoadQueue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(queue, []QueueItem{{ID: "a"}, {ID: "c"}, {ID: "b"}}) {
+		t.Fatalf("queue = %v", queue)
+	}
+
+	// of two feeders only the first one feeds
+	ending := &spotify.PlayerState{CurrentlyPlaying: spotify.CurrentlyPlaying{
+		Playing:  true,
+		Progress: 175000,
+		Item:     &spotify.FullTrack{SimpleTrack: spotify.SimpleTrack{ID: "playing", Duration: 180000}},
+	}}
+	first, second := c.NewQueueFeeder(10*time.Second), c.NewQueueFeeder(10*time.Second)
+	first.Update(ctx, ending)
+	second.Update(ctx, ending)
+	first.Close()
+	defer second.Close()
+	queue, err = c.LoadQueue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(queued, []string{"spotify:track:a"}) || !reflect.DeepEqual(queue, []QueueItem{{ID: "c"}, {ID: "b"}}) {
+		t.Errorf("queued %v, queue left %v", queued, queue)
+	}
+}
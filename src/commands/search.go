@@ -0,0 +1,218 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/zmb3/spotify/v2"
+
+	"git.asdf.cafe/abs3nt/gospt/src/gctx"
+)
+
+var searchTypes = map[string]spotify.SearchType{
+	"track":    spotify.SearchTypeTrack,
+	"album":    spotify.SearchTypeAlbum,
+	"artist":   spotify.SearchTypeArtist,
+	"playlist": spotify.SearchTypePlaylist,
+	"show":     spotify.SearchTypeShow,
+	"episode":  spotify.SearchTypeEpisode,
+}
+
+// AllSearchTypes searches everything gospt can show.
+const AllSearchTypes = spotify.SearchTypeTrack | spotify.SearchTypeAlbum | spotify.SearchTypeArtist |
+	spotify.SearchTypePlaylist | spotify.SearchTypeShow | spotify.SearchTypeEpisode
+
+// ParseSearchTypes parses type names like track or albums, each name can
+// also be a comma separated list. No names means every type.
+func ParseSearchTypes(names []string) (spotify.SearchType, error) {
+	var types spotify.SearchType
+	for _, name := range names {
+		for _, name := range strings.Split(name, ",") {
+			name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), "s")
+			if name == "" {
+				continue
+			}
+			t, ok := searchTypes[name]
+			if !ok {
+				valid := []string{}
+				for name := range searchTypes {
+					valid = append(valid, name)
+				}
+				sort.Strings(valid)
+				return 0, fmt.Errorf("unknown search type %q, use %s", name, strings.Join(valid, ", "))
+			}
+			types |= t
+		}
+	}
+	if types == 0 {
+		return AllSearchTypes, nil
+	}
+	return types, nil
+}
+
+// SearchFilters are spotify's field filters, they narrow down a query.
+type SearchFilters struct {
+	Artist  string
+	Album   string
+	Track   string
+	Year    string
+	Genre   string
+	ISRC    string
+	UPC     string
+	New     bool
+	Hipster bool
+}
+
+// Apply adds the set filters to a query.
+func (f SearchFilters) Apply(query string) string {
+	parts := []string{}
+	if query = strings.TrimSpace(query); query != "" {
+		parts = append(parts, query)
+	}
+	for _, filter := range []struct{ field, value string }{
+		{"artist", f.Artist},
+		{"album", f.Album},
+		{"track", f.Track},
+		{"year", f.Year},
+		{"genre", f.Genre},
+		{"isrc", f.ISRC},
+		{"upc", f.UPC},
+	} {
+		if filter.value == "" {
+			continue
+		}
+		value := filter.value
+		if strings.ContainsAny(value, " \t") {
+			value = fmt.Sprintf("%q", value)
+		}
+		parts = append(parts, filter.field+":"+value)
+	}
+	if f.New {
+		parts = append(parts, "tag:new")
+	}
+	if f.Hipster {
+		parts = append(parts, "tag:hipster")
+	}
+	return strings.Join(parts, " ")
+}
+
+// ParseSearch takes a type:track,album qualifier out of a query, spotify's own
+// field filters like artist: or year: are passed on as they are. types is
+// used when the query has no type qualifier.
+func ParseSearch(input string, types spotify.SearchType) (string, spotify.SearchType, error) {
+	words := []string{}
+	names := []string{}
+	for _, word := range strings.Fields(input) {
+		if name, found := strings.CutPrefix(word, "type:"); found {
+			names = append(names, name)
+			continue
+		}
+		words = append(words, word)
+	}
+	if len(names) > 0 {
+		var err error
+		types, err = ParseSearchTypes(names)
+		if err != nil {
+			return "", 0, err
+		}
+	}
+	query := strings.Join(words, " ")
+	if query == "" {
+		return "", 0, fmt.Errorf("nothing to search for")
+	}
+	return query, types, nil
+}
+
+func (c *Commands) Search(ctx *gctx.Context, search string, types spotify.SearchType, page int) (*spotify.SearchResult, error) {
+	return c.searchPage(ctx, search, types, 50, (page-1)*50)
+}
+
+func (c *Commands) searchPage(ctx *gctx.Context, search string, types spotify.SearchType, limit, offset int) (*spotify.SearchResult, error) {
+	result, err := c.Client().
+		Search(ctx, search, types, spotify.Limit(limit), spotify.Offset(offset), c.market())
+	if err != nil {
+		return nil, err
+	}
+	// spotify sometimes leaves holes in show and episode results
+	if result.Shows != nil {
+		shows := []spotify.FullShow{}
+		for _, show := range result.Shows.Shows {
+			if show.ID != "" {
+				shows = append(shows, show)
+			}
+		}
+		result.Shows.Shows = shows
+	}
+	if result.Episodes != nil {
+		episodes := []spotify.EpisodePage{}
+		for _, episode := range result.Episodes.Episodes {
+			if episode.ID != "" {
+				episodes = append(episodes, episode)
+			}
+		}
+		result.Episodes.Episodes = episodes
+	}
+	return result, nil
+}
+
+func artistNames(artists []spotify.SimpleArtist) string {
+	names := []string{}
+	for _, artist := range artists {
+		names = append(names, artist.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// PrintSearch searches and prints the results as text or json.
+func (c *Commands) PrintSearch(ctx *gctx.Context, query string, types spotify.SearchType, limit, offset int, asJSON bool) error {
+	result, err := c.searchPage(ctx, query, types, limit, offset)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	}
+	section := func(title string, count int) bool {
+		if count == 0 {
+			return false
+		}
+		fmt.Printf("%s:\n", title)
+		return true
+	}
+	if result.Tracks != nil && section("Tracks", len(result.Tracks.Tracks)) {
+		for _, track := range result.Tracks.Tracks {
+			fmt.Printf("  %s - %s (%s)\n", track.Name, artistNames(track.Artists), track.URI)
+		}
+	}
+	if result.Albums != nil && section("Albums", len(result.Albums.Albums)) {
+		for _, album := range result.Albums.Albums {
+			fmt.Printf("  %s - %s, %s (%s)\n", album.Name, artistNames(album.Artists), album.ReleaseDate, album.URI)
+		}
+	}
+	if result.Artists != nil && section("Artists", len(result.Artists.Artists)) {
+		for _, artist := range result.Artists.Artists {
+			fmt.Printf("  %s (%s)\n", artist.Name, artist.URI)
+		}
+	}
+	if result.Playlists != nil && section("Playlists", len(result.Playlists.Playlists)) {
+		for _, playlist := range result.Playlists.Playlists {
+			fmt.Printf("  %s by %s (%s)\n", playlist.Name, playlist.Owner.DisplayName, playlist.URI)
+		}
+	}
+	if result.Shows != nil && section("Shows", len(result.Shows.Shows)) {
+		for _, show := range result.Shows.Shows {
+			fmt.Printf("  %s - %s (%s)\n", show.Name, show.Publisher, show.URI)
+		}
+	}
+	if result.Episodes != nil && section("Episodes", len(result.Episodes.Episodes)) {
+		for _, episode := range result.Episodes.Episodes {
+			fmt.Printf("  %s, %s (%s)\n", episode.Name, episode.ReleaseDate, episode.URI)
+		}
+	}
+	return nil
+}
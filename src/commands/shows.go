@@ -0,0 +1,326 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+
+	"git.asdf.cafe/abs3nt/gospt/src/gctx"
+)
+
+// cacheSize is how many lookups a lookupCache keeps.
+const cacheSize = 64
+
+// lookupCache keeps the latest lookups by id, the player asks about the same
+// item on every poll. The oldest lookup goes once it is full.
+type lookupCache[T any] struct {
+	mu     sync.Mutex
+	values map[spotify.ID]T
+	order  []spotify.ID
+}
+
+func newLookupCache[T any]() *lookupCache[T] {
+	return &lookupCache[T]{values: map[spotify.ID]T{}}
+}
+
+func (l *lookupCache[T]) get(id spotify.ID) (T, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	value, ok := l.values[id]
+	return value, ok
+}
+
+func (l *lookupCache[T]) put(id spotify.ID, value T) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.values[id]; !ok {
+		l.order = append(l.order, id)
+	}
+	l.values[id] = value
+	for len(l.order) > cacheSize {
+		delete(l.values, l.order[0])
+		l.order = l.order[1:]
+	}
+}
+
+func (l *lookupCache[T]) drop(id spotify.ID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.values[id]; !ok {
+		return
+	}
+	delete(l.values, id)
+	for idx, cached := range l.order {
+		if cached == id {
+			l.order = append(l.order[:idx], l.order[idx+1:]...)
+			break
+		}
+	}
+}
+
+// retryMissing is how long a failed lookup is not tried again.
+const retryMissing = time.Minute
+
+// misses remembers lookups that failed, so the poll of the player does not
+// repeat them every second.
+type misses struct {
+	failed *lookupCache[time.Time]
+}
+
+func newMisses() misses {
+	return misses{failed: newLookupCache[time.Time]()}
+}
+
+func (m misses) recent(id spotify.ID) bool {
+	at, ok := m.failed.get(id)
+	return ok && time.Since(at) < retryMissing
+}
+
+func (m misses) add(id spotify.ID) {
+	m.failed.put(id, time.Now())
+}
+
+var (
+	// episodes caches episode details by id, the player only reports the
+	// parts of an episode that fit a track.
+	episodes       = newLookupCache[*spotify.EpisodePage]()
+	missedEpisodes = newMisses()
+)
+
+// PlayerState is like the client's PlayerState but also reports episodes and
+// audiobook chapters. They come back in the shape of a track with the show or
+// audiobook in place of the album, so code that expects a track keeps working.
+func (c *Commands) PlayerState(ctx *gctx.Context) (*spotify.PlayerState, error) {
+	state, err := c.Client().PlayerState(ctx, spotify.AdditionalTypes(spotify.EpisodeAdditionalType, spotify.TrackAdditionalType))
+	if err != nil {
+		return nil, err
+	}
+	c.fillEpisode(ctx, state.Item)
+	return state, nil
+}
+
+// CurrentlyPlaying is like the client's PlayerCurrentlyPlaying but also
+// reports episodes the same way as PlayerState.
+func (c *Commands) CurrentlyPlaying(ctx *gctx.Context) (*spotify.CurrentlyPlaying, error) {
+	current, err := c.Client().PlayerCurrentlyPlaying(ctx, spotify.AdditionalTypes(spotify.EpisodeAdditionalType, spotify.TrackAdditionalType))
+	if err != nil {
+		return nil, err
+	}
+	c.fillEpisode(ctx, current.Item)
+	return current, nil
+}
+
+func IsEpisode(item *spotify.FullTrack) bool {
+	return item != nil && item.Type == "episode"
+}
+
+func (c *Commands) Episode(ctx *gctx.Context, id spotify.ID) (*spotify.EpisodePage, error) {
+	if episode, ok := episodes.get(id); ok {
+		return episode, nil
+	}
+	if missedEpisodes.recent(id) {
+		return nil, fmt.Errorf("episode %s was not found", id)
+	}
+	episode, err := c.Client().GetEpisode(ctx, string(id), c.market())
+	if err != nil {
+		missedEpisodes.add(id)
+		return nil, err
+	}
+	episodes.put(id, episode)
+	return episode, nil
+}
+
+func (c *Commands) fillEpisode(ctx *gctx.Context, item *spotify.FullTrack) {
+	if IsChapter(item) {
+		c.fillChapter(ctx, item)
+		return
+	}
+	if !IsEpisode(item) {
+		return
+	}
+	// a chapter reported as an episode was looked up as a chapter before
+	if _, ok := chapters.get(item.ID); ok {
+		c.fillChapter(ctx, item)
+		return
+	}
+	episode, err := c.Episode(ctx, item.ID)
+	if err != nil {
+		// audiobook chapters can be reported as episodes
+		if !c.fillChapter(ctx, item) {
+			ctx.Debug.Trace().Err(err).Msg("failed to get episode")
+		}
+		return
+	}
+	show := episode.Show
+	item.Artists = []spotify.SimpleArtist{{Name: show.Name, ID: show.ID, URI: show.URI, ExternalURLs: show.ExternalURLs}}
+	item.Album = spotify.SimpleAlbum{Name: show.Name, ID: show.ID, URI: show.URI, Images: episode.Images, ExternalURLs: show.ExternalURLs}
+	if len(item.Album.Images) == 0 {
+		item.Album.Images = show.Images
+	}
+	if item.Duration == 0 {
+		item.Duration = episode.Duration_ms
+	}
+}
+
+func (c *Commands) UserShows(ctx *gctx.Context, page int) (*spotify.SavedShowPage, error) {
+	return c.Client().CurrentUsersShows(ctx, spotify.Limit(50), spotify.Offset((page-1)*50))
+}
+
+func (c *Commands) ShowEpisodes(ctx *gctx.Context, show spotify.ID, page int) (*spotify.SimpleEpisodePage, error) {
+	return c.Client().GetShowEpisodes(ctx, string(show), spotify.Limit(50), spotify.Offset((page-1)*50), c.market())
+}
+
+// The web api has no endpoint to mark episodes as played, episodes marked in
+// gospt are kept in played.json next to the config.
+func playedPath() string {
+	configDir, _ := os.UserConfigDir()
+	return filepath.Join(configDir, "gospt/played.json")
+}
+
+func loadPlayed() (map[spotify.ID]bool, error) {
+	played := map[spotify.ID]bool{}
+	data, err := os.ReadFile(playedPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return played, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return played, json.Unmarshal(data, &played)
+}
+
+// MarkPlayed marks episodes as played, or as unplayed when played is false.
+// Unplayed overrides the fully played state reported by spotify.
+func (c *Commands) MarkPlayed(ids []spotify.ID, played bool) error {
+	c.playedMu.Lock()
+	defer c.playedMu.Unlock()
+	// marks made by other gospt processes since the cache was filled are kept
+	marks, err := loadPlayed()
+	if err != nil {
+		return err
+	}
+	c.played = nil
+	for _, id := range ids {
+		marks[id] = played
+		// the cached resume point is out of date now
+		episodes.drop(id)
+	}
+	data, err := json.MarshalIndent(marks, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(playedPath()), 0o700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(playedPath(), data, 0o600); err != nil {
+		return err
+	}
+	c.played = marks
+	return nil
+}
+
+// EpisodePlayed reports whether an episode was marked as played in gospt or
+// fully played according to spotify.
+func (c *Commands) EpisodePlayed(episode *spotify.EpisodePage) bool {
+	c.playedMu.Lock()
+	if c.played == nil {
+		// a broken file is read again next time
+		c.played, _ = loadPlayed()
+	}
+	played, ok := c.played[episode.ID]
+	c.playedMu.Unlock()
+	if ok {
+		return played
+	}
+	return episode.ResumePoint.FullyPlayed
+}
+
+// ResumeEpisode plays an episode in the context of its show from where it was
+// left off, or from the start if it was played.
+func (c *Commands) ResumeEpisode(ctx *gctx.Context, episode *spotify.EpisodePage) error {
+	position := 0
+	if !c.EpisodePlayed(episode) {
+		position = int(episode.ResumePoint.ResumePositionMs)
+	}
+	show := episode.Show.URI
+	return c.withDevice(ctx, func(opt *spotify.PlayOptions) error {
+		opt.PositionMs = spotify.Numeric(position)
+		if show != "" {
+			opt.PlaybackContext = &show
+			opt.PlaybackOffset = &spotify.PlaybackOffset{URI: episode.URI}
+		} else {
+			opt.URIs = []spotify.URI{episode.URI}
+		}
+		return c.Client().PlayOpt(ctx, opt)
+	})
+}
+
+// FormatEpisode describes an episode for listings.
+func (c *Commands) FormatEpisode(episode *spotify.EpisodePage) string {
+	duration := (time.Duration(episode.Duration_ms) * time.Millisecond).Round(time.Minute)
+	state := ""
+	switch {
+	case c.EpisodePlayed(episode):
+		state = ", played"
+	case episode.ResumePoint.ResumePositionMs > 0:
+		left := time.Duration(int(episode.Duration_ms)-int(episode.ResumePoint.ResumePositionMs)) * time.Millisecond
+		state = fmt.Sprintf(", %s left", left.Round(time.Minute))
+	}
+	return fmt.Sprintf("%s - %s%s", episode.ReleaseDate, duration, state)
+}
+
+// ResolveEpisode finds the episode an id, uri or url points at.
+func (c *Commands) ResolveEpisode(ctx *gctx.Context, query string) (*spotify.EpisodePage, error) {
+	id := uriID(query, "episode")
+	if id == "" {
+		id = spotify.ID(query)
+	}
+	return c.Client().GetEpisode(ctx, string(id), c.market())
+}
+
+func (c *Commands) PrintShows(ctx *gctx.Context) error {
+	for page := 1; ; page++ {
+		shows, err := c.UserShows(ctx, page)
+		if err != nil {
+			return err
+		}
+		for _, show := range shows.Shows {
+			fmt.Printf("%s - %s (%s)\n", show.Name, show.Publisher, show.URI)
+		}
+		if len(shows.Shows) < 50 {
+			return nil
+		}
+	}
+}
+
+func (c *Commands) PrintEpisodes(ctx *gctx.Context, query string, limit int) error {
+	id := uriID(query, "show")
+	if id == "" {
+		id = spotify.ID(query)
+	}
+	printed := 0
+	for page := 1; printed < limit; page++ {
+		episodes, err := c.ShowEpisodes(ctx, id, page)
+		if err != nil {
+			return err
+		}
+		for idx := range episodes.Episodes {
+			if printed == limit {
+				break
+			}
+			episode := &episodes.Episodes[idx]
+			fmt.Printf("%s\n  %s (%s)\n", episode.Name, c.FormatEpisode(episode), episode.URI)
+			printed++
+		}
+		if len(episodes.Episodes) < 50 {
+			break
+		}
+	}
+	return nil
+}
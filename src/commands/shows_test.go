@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+func TestLookupCache(t *testing.T) {
+	cache := newLookupCache[int]()
+	for i := 0; i < cacheSize+10; i++ {
+		cache.put(spotify.ID(fmt.Sprint(i)), i)
+	}
+	if len(cache.values) != cacheSize || len(cache.order) != cacheSize {
+		t.Fatalf("cache holds %d values and %d ids, want %d", len(cache.values), len(cache.order), cacheSize)
+	}
+	if _, ok := cache.get("0"); ok {
+		t.Error("the oldest lookup was kept")
+	}
+	if value, ok := cache.get(spotify.ID(fmt.Sprint(cacheSize + 9))); !ok || value != cacheSize+9 {
+		t.Errorf("newest lookup = %d, %v", value, ok)
+	}
+
+	// putting a cached id again does not count twice
+	cache.put("20", 200)
+	if value, _ := cache.get("20"); value != 200 || len(cache.order) != cacheSize {
+		t.Errorf("value %d, %d ids", value, len(cache.order))
+	}
+
+	cache.drop("20")
+	if _, ok := cache.get("20"); ok || len(cache.order) != cacheSize-1 {
+		t.Errorf("dropped lookup kept, %d ids", len(cache.order))
+	}
+	cache.drop("missing")
+}
+
+func TestMisses(t *testing.T) {
+	missed := newMisses()
+	if missed.recent("a") {
+		t.Error("an id never looked up is missing")
+	}
+	missed.add("a")
+	if !missed.recent("a") {
+		t.Error("a failed lookup is not remembered")
+	}
+	missed.failed.put("b", time.Now().Add(-retryMissing))
+	if missed.recent("b") {
+		t.Error("a failed lookup is not retried after retryMissing")
+	}
+}
+
+func TestEpisodePlayed(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	c := &Commands{}
+	played := &spotify.EpisodePage{ID: "e1", ResumePoint: spotify.ResumePointObject{FullyPlayed: true}}
+	unplayed := &spotify.EpisodePage{ID: "e2"}
+	if !c.EpisodePlayed(played) || c.EpisodePlayed(unplayed) {
+		t.Fatal("without marks spotify's played state is used")
+	}
+	if err := c.MarkPlayed([]spotify.ID{"e1"}, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.MarkPlayed([]spotify.ID{"e2"}, true); err != nil {
+		t.Fatal(err)
+	}
+	if c.EpisodePlayed(played) || !c.EpisodePlayed(unplayed) {
+		t.Error("marks are not used over spotify's played state")
+	}
+
+	// the marks are read once and kept after that
+	if err := os.Remove(playedPath()); err != nil {
+		t.Fatal(err)
+	}
+	if !c.EpisodePlayed(unplayed) {
+		t.Error("played.json was read again")
+	}
+	if fresh := (&Commands{}); fresh.EpisodePlayed(unplayed) {
+		t.Error("a mark was kept outside played.json")
+	}
+}
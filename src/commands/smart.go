@@ -0,0 +1,388 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+	"gopkg.in/yaml.v3"
+
+	"git.asdf.cafe/abs3nt/gospt/src/gctx"
+)
+
+// SmartRules filter the tracks of a smart playlist. Durations accept go
+// durations ("4m30s") and ages additionally accept days and weeks ("30d", "2w").
+type SmartRules struct {
+	AddedWithin     string   `yaml:"added_within"`
+	AddedBefore     string   `yaml:"added_before"`
+	Artists         []string `yaml:"artists"`
+	ExcludeArtists  []string `yaml:"exclude_artists"`
+	MinDuration     string   `yaml:"min_duration"`
+	MaxDuration     string   `yaml:"max_duration"`
+	MinPopularity   int      `yaml:"min_popularity"`
+	ExcludeExplicit bool     `yaml:"exclude_explicit"`
+}
+
+// SmartPlaylist is a rule based playlist. Sources are "library", "albums" or
+// "playlist:<name>" and default to the saved tracks.
+type SmartPlaylist struct {
+	Name        string     `yaml:"name"`
+	Description string     `yaml:"description"`
+	Sources     []string   `yaml:"sources"`
+	Rules       SmartRules `yaml:"rules"`
+	Shuffle     bool       `yaml:"shuffle"`
+	Limit       int        `yaml:"limit"`
+}
+
+type smartTrack struct {
+	ID         spotify.ID
+	Artists    []spotify.SimpleArtist
+	Duration   time.Duration
+	AddedAt    time.Time
+	Popularity int
+	Explicit   bool
+}
+
+func smartConfigPath() string {
+	configDir, _ := os.UserConfigDir()
+	return filepath.Join(configDir, "gospt/smart.yml")
+}
+
+func smartStatePath() string {
+	configDir, _ := os.UserConfigDir()
+	return filepath.Join(configDir, "gospt/smart.json")
+}
+
+func LoadSmartPlaylists() ([]SmartPlaylist, error) {
+	raw, err := os.ReadFile(smartConfigPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no smart playlists defined, write them to %s", smartConfigPath())
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Playlists []SmartPlaylist `yaml:"playlists"`
+	}
+	if err := yaml.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", smartConfigPath(), err)
+	}
+	for _, sp := range file.Playlists {
+		if sp.Name == "" {
+			return nil, fmt.Errorf("%s: smart playlist without a name", smartConfigPath())
+		}
+	}
+	return file.Playlists, nil
+}
+
+func parseAge(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, found := strings.CutSuffix(s, suffix); found {
+			amt, err := strconv.Atoi(n)
+			if err != nil {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(amt) * unit, nil
+		}
+	}
+	return time.ParseDuration(s)
+}
+
+func (r SmartRules) matcher() (func(smartTrack) bool, error) {
+	checks := []func(smartTrack) bool{}
+	if r.AddedWithin != "" {
+		age, err := parseAge(r.AddedWithin)
+		if err != nil {
+			return nil, err
+		}
+		since := time.Now().Add(-age)
+		checks = append(checks, func(t smartTrack) bool { return t.AddedAt.After(since) })
+	}
+	if r.AddedBefore != "" {
+		age, err := parseAge(r.AddedBefore)
+		if err != nil {
+			return nil, err
+		}
+		before := time.Now().Add(-age)
+		checks = append(checks, func(t smartTrack) bool { return !t.AddedAt.IsZero() && t.AddedAt.Before(before) })
+	}
+	if r.MinDuration != "" {
+		minDuration, err := time.ParseDuration(r.MinDuration)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, func(t smartTrack) bool { return t.Duration >= minDuration })
+	}
+	if r.MaxDuration != "" {
+		maxDuration, err := time.ParseDuration(r.MaxDuration)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, func(t smartTrack) bool { return t.Duration <= maxDuration })
+	}
+	if len(r.Artists) > 0 {
+		checks = append(checks, func(t smartTrack) bool { return hasArtist(t.Artists, r.Artists) })
+	}
+	if len(r.ExcludeArtists) > 0 {
+		checks = append(checks, func(t smartTrack) bool { return !hasArtist(t.Artists, r.ExcludeArtists) })
+	}
+	if r.MinPopularity > 0 {
+		// album tracks carry no popularity, so the rule only applies when it is known
+		checks = append(checks, func(t smartTrack) bool { return t.Popularity < 0 || t.Popularity >= r.MinPopularity })
+	}
+	if r.ExcludeExplicit {
+		checks = append(checks, func(t smartTrack) bool { return !t.Explicit })
+	}
+	return func(t smartTrack) bool {
+		for _, check := range checks {
+			if !check(t) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func hasArtist(artists []spotify.SimpleArtist, names []string) bool {
+	for _, artist := range artists {
+		for _, name := range names {
+			if strings.EqualFold(artist.Name, name) || string(artist.ID) == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func parseAddedAt(addedAt string) time.Time {
+	added, _ := time.Parse(spotify.TimestampLayout, addedAt)
+	return added
+}
+
+func fullSmartTrack(track *spotify.FullTrack, addedAt string) smartTrack {
+	return smartTrack{
+		ID:         track.ID,
+		Artists:    track.Artists,
+		Duration:   track.TimeDuration(),
+		AddedAt:    parseAddedAt(addedAt),
+		Popularity: int(track.Popularity),
+		Explicit:   track.Explicit,
+	}
+}
+
+func (c *Commands) smartSource(ctx *gctx.Context, source string) ([]smartTrack, error) {
+	tracks := []smartTrack{}
+	switch {
+	case source == "library":
+		for page := 1; ; page++ {
+			saved, err := c.TrackList(ctx, page)
+			if err != nil {
+				return nil, err
+			}
+			for _, track := range saved.Tracks {
+				track := track
+				tracks = append(tracks, fullSmartTrack(&track.FullTrack, track.AddedAt))
+			}
+			if len(saved.Tracks) < 50 {
+				return tracks, nil
+			}
+		}
+	case source == "albums":
+		for page := 1; ; page++ {
+			albums, err := c.UserAlbums(ctx, page)
+			if err != nil {
+				return nil, err
+			}
+			for _, album := range albums.Albums {
+				albumTracks := album.Tracks.Tracks
+				for albumPage := 2; len(albumTracks) < int(album.Tracks.Total); albumPage++ {
+					more, err := c.AlbumTracks(ctx, album.ID, albumPage)
+					if err != nil {
+						return nil, err
+					}
+					if len(more.Tracks) == 0 {
+						break
+					}
+					albumTracks = append(albumTracks, more.Tracks...)
+				}
+				for _, track := range albumTracks {
+					tracks = append(tracks, smartTrack{
+						ID:         track.ID,
+						Artists:    track.Artists,
+						Duration:   track.TimeDuration(),
+						AddedAt:    parseAddedAt(album.AddedAt),
+						Popularity: -1,
+						Explicit:   track.Explicit,
+					})
+				}
+			}
+			if len(albums.Albums) < 50 {
+				return tracks, nil
+			}
+		}
+	case strings.HasPrefix(source, "playlist:"):
+		playlist, err := c.FindPlaylist(ctx, strings.TrimPrefix(source, "playlist:"))
+		if err != nil {
+			return nil, err
+		}
+		for page := 1; ; page++ {
+			items, err := c.PlaylistTracks(ctx, playlist.ID, page)
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range items.Items {
+				if item.Track.Track != nil && !item.IsLocal {
+					tracks = append(tracks, fullSmartTrack(item.Track.Track, item.AddedAt))
+				}
+			}
+			if len(items.Items) < 50 {
+				return tracks, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown smart playlist source %q, expected library, albums or playlist:<name>", source)
+}
+
+// SmartTracks evaluates a smart playlist and returns the ids it should contain.
+func (c *Commands) SmartTracks(ctx *gctx.Context, sp SmartPlaylist) ([]spotify.ID, error) {
+	match, err := sp.Rules.matcher()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", sp.Name, err)
+	}
+	sources := sp.Sources
+	if len(sources) == 0 {
+		sources = []string{"library"}
+	}
+	seen := map[spotify.ID]bool{}
+	ids := []spotify.ID{}
+	for _, source := range sources {
+		tracks, err := c.smartSource(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+		for _, track := range tracks {
+			if track.ID == "" || seen[track.ID] || !match(track) {
+				continue
+			}
+			seen[track.ID] = true
+			ids = append(ids, track.ID)
+		}
+	}
+	if sp.Shuffle {
+		rand.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+	}
+	if sp.Limit > 0 && len(ids) > sp.Limit {
+		ids = ids[:sp.Limit]
+	}
+	return ids, nil
+}
+
+func loadSmartState() map[string]spotify.ID {
+	state := map[string]spotify.ID{}
+	raw, err := os.ReadFile(smartStatePath())
+	if err != nil {
+		return state
+	}
+	json.Unmarshal(raw, &state)
+	return state
+}
+
+func saveSmartState(state map[string]spotify.ID) error {
+	raw, err := json.MarshalIndent(state, "", " ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(smartStatePath(), raw, 0o600)
+}
+
+// SmartSync materializes smart playlists into real spotify playlists. When
+// names are given only those playlists are synced.
+func (c *Commands) SmartSync(ctx *gctx.Context, names []string) error {
+	playlists, err := LoadSmartPlaylists()
+	if err != nil {
+		return err
+	}
+	state := loadSmartState()
+	synced := 0
+	for _, sp := range playlists {
+		if len(names) > 0 && !containsFold(names, sp.Name) {
+			continue
+		}
+		ids, err := c.SmartTracks(ctx, sp)
+		if err != nil {
+			return err
+		}
+		synced++
+		if len(ids) == 0 {
+			// replacing with no tracks would empty the playlist, which is
+			// more likely a broken rule or source than what was meant
+			fmt.Printf("Skipped %s, no tracks match its rules\n", sp.Name)
+			continue
+		}
+		playlistID, ok := state[sp.Name]
+		if ok {
+			if _, err := c.Client().GetPlaylist(ctx, playlistID, spotify.Fields("id")); err != nil {
+				ok = false
+			}
+		}
+		if !ok {
+			description := sp.Description
+			if description == "" {
+				description = "Automanaged smart playlist"
+			}
+			playlist, err := c.Client().CreatePlaylistForUser(ctx, c.User(), sp.Name, description, false, false)
+			if err != nil {
+				return err
+			}
+			playlistID = playlist.ID
+			state[sp.Name] = playlistID
+			if err := saveSmartState(state); err != nil {
+				return err
+			}
+		}
+		if err := c.ReplacePlaylistTracks(ctx, playlistID, ids); err != nil {
+			return fmt.Errorf("%s: %w", sp.Name, err)
+		}
+		fmt.Printf("Synced %s with %d tracks\n", sp.Name, len(ids))
+	}
+	if synced == 0 && len(names) > 0 {
+		return fmt.Errorf("no smart playlist named %s", strings.Join(names, ", "))
+	}
+	return nil
+}
+
+func (c *Commands) SmartList(ctx *gctx.Context) error {
+	playlists, err := LoadSmartPlaylists()
+	if err != nil {
+		return err
+	}
+	state := loadSmartState()
+	for _, sp := range playlists {
+		synced := "not synced"
+		if id, ok := state[sp.Name]; ok {
+			synced = "spotify:playlist:" + string(id)
+		}
+		sources := sp.Sources
+		if len(sources) == 0 {
+			sources = []string{"library"}
+		}
+		fmt.Printf("%s (%s) from %s\n", sp.Name, synced, strings.Join(sources, ", "))
+	}
+	return nil
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
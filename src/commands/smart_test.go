@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		age  string
+		want time.Duration
+		err  bool
+	}{
+		{"30d", 30 * 24 * time.Hour, false},
+		{"2w", 14 * 24 * time.Hour, false},
+		{"0d", 0, false},
+		{"36h", 36 * time.Hour, false},
+		{"4m30s", 4*time.Minute + 30*time.Second, false},
+		{"d", 0, true},
+		{"1.5w", 0, true},
+		{"soon", 0, true},
+	}
+	for _, test := range tests {
+		got, err := parseAge(test.age)
+		if (err != nil) != test.err {
+			t.Errorf("parseAge(%q) error = %v, want error %v", test.age, err, test.err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("parseAge(%q) = %v, want %v", test.age, got, test.want)
+		}
+	}
+}
+
+func TestSmartRules(t *testing.T) {
+	now := time.Now()
+	track := smartTrack{
+		ID:         "t1",
+		Artists:    []spotify.SimpleArtist{{ID: "a1", Name: "Artist X"}},
+		Duration:   4 * time.Minute,
+		AddedAt:    now.Add(-10 * 24 * time.Hour),
+		Popularity: 40,
+		Explicit:   true,
+	}
+	albumTrack := track
+	albumTrack.Popularity = -1
+	undated := track
+	undated.AddedAt = time.Time{}
+	tests := []struct {
+		name  string
+		rules SmartRules
+		track smartTrack
+		want  bool
+	}{
+		{"no rules", SmartRules{}, track, true},
+		{"added within", SmartRules{AddedWithin: "2w"}, track, true},
+		{"added too long ago", SmartRules{AddedWithin: "7d"}, track, false},
+		{"added before", SmartRules{AddedBefore: "1w"}, track, true},
+		{"added too recently", SmartRules{AddedBefore: "30d"}, track, false},
+		{"unknown added date", SmartRules{AddedBefore: "1d"}, undated, false},
+		{"artist by name", SmartRules{Artists: []string{"artist x"}}, track, true},
+		{"artist by id", SmartRules{Artists: []string{"a1"}}, track, true},
+		{"other artist", SmartRules{Artists: []string{"Artist Y"}}, track, false},
+		{"excluded artist", SmartRules{ExcludeArtists: []string{"Artist X"}}, track, false},
+		{"long enough", SmartRules{MinDuration: "3m"}, track, true},
+		{"too short", SmartRules{MinDuration: "5m"}, track, false},
+		{"too long", SmartRules{MaxDuration: "3m30s"}, track, false},
+		{"popular enough", SmartRules{MinPopularity: 40}, track, true},
+		{"not popular enough", SmartRules{MinPopularity: 50}, track, false},
+		{"unknown popularity", SmartRules{MinPopularity: 50}, albumTrack, true},
+		{"explicit", SmartRules{ExcludeExplicit: true}, track, false},
+		{"all rules", SmartRules{AddedWithin: "30d", Artists: []string{"Artist X"}, MaxDuration: "5m", MinPopularity: 10}, track, true},
+	}
+	for _, test := range tests {
+		match, err := test.rules.matcher()
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if got := match(test.track); got != test.want {
+			t.Errorf("%s: match = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSmartRulesInvalid(t *testing.T) {
+	for _, rules := range []SmartRules{
+		{AddedWithin: "a month"},
+		{AddedBefore: "xw"},
+		{MinDuration: "30d"},
+		{MaxDuration: "long"},
+	} {
+		if _, err := rules.matcher(); err == nil {
+			t.Errorf("%+v: no error", rules)
+		}
+	}
+}
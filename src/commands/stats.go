@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"git.asdf.cafe/abs3nt/gospt/src/gctx"
+	"git.asdf.cafe/abs3nt/gospt/src/history"
+)
+
+// parseDay parses a YYYY-MM-DD date in local time.
+func parseDay(s string) (time.Time, error) {
+	day, err := time.ParseInLocation(time.DateOnly, s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, use YYYY-MM-DD", s)
+	}
+	return day, nil
+}
+
+// Stats prints a report of the local listening history. from and to are
+// dates, to is inclusive; since is an age like 30d and overrides from.
+func (c *Commands) Stats(ctx *gctx.Context, from, to, since string, limit int, asJSON bool) error {
+	now := time.Now()
+	end := now
+	if to != "" {
+		day, err := parseDay(to)
+		if err != nil {
+			return err
+		}
+		end = day.AddDate(0, 0, 1)
+	}
+	start := end.AddDate(0, 0, -30)
+	switch {
+	case since != "":
+		age, err := parseAge(since)
+		if err != nil {
+			return err
+		}
+		start = end.Add(-age)
+	case from != "":
+		day, err := parseDay(from)
+		if err != nil {
+			return err
+		}
+		start = day
+	}
+	if !start.Before(end) {
+		return fmt.Errorf("start of range is after the end")
+	}
+	db, err := history.Open(history.DefaultPath())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	report, err := history.Stats(db, start, end, limit)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	report.Print(os.Stdout)
+	return nil
+}
@@ -0,0 +1,30 @@
+package commands
+
+import (
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+
+	"git.asdf.cafe/abs3nt/gospt/src/gctx"
+)
+
+// Watch polls the player state every interval and hands every result to fn
+// until the context is cancelled, which is not an error. Failed polls are
+// passed on as nil.
+func (c *Commands) Watch(ctx *gctx.Context, interval time.Duration, fn func(*spotify.PlayerState)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		state, err := c.PlayerState(ctx)
+		if err != nil {
+			ctx.Debug.Trace().Err(err).Msg("watch poll failed")
+			state = nil
+		}
+		fn(state)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -5,4 +5,44 @@ var Values struct {
 	ClientSecret    string `yaml:"client_secret"`
 	ClientSecretCmd string `yaml:"client_secret_cmd"`
 	Port            string `yaml:"port"`
+	Market          string `yaml:"market"`
+	HideUnplayable  bool   `yaml:"hide_unplayable"`
+	Keys            struct {
+		Preset   string         `yaml:"preset"`
+		Bindings map[string]any `yaml:"bindings"`
+	} `yaml:"keys"`
+	Theme struct {
+		Preset          string `yaml:"preset"`
+		Border          string `yaml:"border"`
+		BorderColor     string `yaml:"border_color"`
+		Title           string `yaml:"title"`
+		TitleBackground string `yaml:"title_background"`
+		Text            string `yaml:"text"`
+		Description     string `yaml:"description"`
+		Selected        string `yaml:"selected"`
+		SelectedDesc    string `yaml:"selected_description"`
+		SelectedBorder  string `yaml:"selected_border"`
+		SelectedBold    bool   `yaml:"selected_bold"`
+		Dimmed          string `yaml:"dimmed"`
+		Status          string `yaml:"status"`
+		ProgressStart   string `yaml:"progress_start"`
+		ProgressEnd     string `yaml:"progress_end"`
+		ProgressEmpty   string `yaml:"progress_empty"`
+	} `yaml:"theme"`
+	NowPlaying struct {
+		Hidden   bool   `yaml:"hidden"`
+		AlbumArt string `yaml:"album_art"`
+	} `yaml:"now_playing"`
+	Layout struct {
+		Single   bool   `yaml:"single"`
+		SidePane string `yaml:"side_pane"`
+	} `yaml:"layout"`
+	Scrobble struct {
+		ListenBrainzURL   string `yaml:"listenbrainz_url"`
+		ListenBrainzToken string `yaml:"listenbrainz_token"`
+		LastFMURL         string `yaml:"lastfm_url"`
+		LastFMAPIKey      string `yaml:"lastfm_api_key"`
+		LastFMSecret      string `yaml:"lastfm_secret"`
+		LastFMSessionKey  string `yaml:"lastfm_session_key"`
+	} `yaml:"scrobble"`
 }
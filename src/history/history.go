@@ -0,0 +1,168 @@
+package history
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+	_ "modernc.org/sqlite"
+	"tuxpa.in/a/zlog/log"
+
+	"git.asdf.cafe/abs3nt/gospt/src/lock"
+)
+
+// saveEvery is how often the listened time of the current play is written.
+const saveEvery = 15 * time.Second
+
+const schema = `CREATE TABLE IF NOT EXISTS plays (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	track_id TEXT NOT NULL,
+	track_name TEXT NOT NULL,
+	artist_id TEXT NOT NULL,
+	artist_name TEXT NOT NULL,
+	album_id TEXT NOT NULL,
+	album_name TEXT NOT NULL,
+	duration_ms INTEGER NOT NULL,
+	context_uri TEXT NOT NULL,
+	context_type TEXT NOT NULL,
+	device_name TEXT NOT NULL,
+	started_at INTEGER NOT NULL,
+	listened_ms INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS plays_started_at ON plays (started_at);`
+
+func DefaultPath() string {
+	configDir, _ := os.UserConfigDir()
+	return filepath.Join(configDir, "gospt/history.db")
+}
+
+// Open opens the history database. The stats command reads it while the TUI
+// or gospt watch write to it, so writers wait for each other instead of
+// failing and readers do not block the writer.
+func Open(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+type play struct {
+	rowID    int64
+	trackID  spotify.ID
+	listened time.Duration
+	progress time.Duration
+	saved    time.Time
+}
+
+// Recorder writes every observed play to the history database. It is fed the
+// player state on every poll, the same way the scrobbler is. Only one process
+// records at a time, so plays are not written twice when the TUI and gospt
+// watch run side by side.
+type Recorder struct {
+	db    *sql.DB
+	owner *lock.File
+
+	mu       sync.Mutex
+	current  *play
+	lastSeen time.Time
+}
+
+func NewRecorder(path string) (*Recorder, error) {
+	db, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{db: db, owner: lock.New(filepath.Join(filepath.Dir(path), "history.lock"))}, nil
+}
+
+func (r *Recorder) Update(state *spotify.PlayerState) {
+	if r == nil || !r.owner.TryLock() {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	defer func() { r.lastSeen = now }()
+	if state == nil || state.Item == nil {
+		return
+	}
+	progress := time.Duration(state.Progress) * time.Millisecond
+	restarted := r.current != nil && progress < r.current.progress && progress < 10*time.Second && r.current.listened > 30*time.Second
+	if r.current == nil || r.current.trackID != state.Item.ID || restarted {
+		if !state.Playing {
+			// only record plays that actually started
+			return
+		}
+		r.finish()
+		r.start(state, now.Add(-progress))
+		r.current.progress = progress
+		return
+	}
+	if state.Playing && !r.lastSeen.IsZero() {
+		elapsed := now.Sub(r.lastSeen)
+		if advanced := progress - r.current.progress; advanced >= 0 && advanced < elapsed {
+			elapsed = advanced
+		}
+		if elapsed > 0 {
+			r.current.listened += elapsed
+		}
+	}
+	r.current.progress = progress
+	if now.Sub(r.current.saved) > saveEvery {
+		r.save()
+	}
+}
+
+func (r *Recorder) start(state *spotify.PlayerState, startedAt time.Time) {
+	track := state.Item
+	artistID, artistName := "", ""
+	if len(track.Artists) > 0 {
+		artistID, artistName = string(track.Artists[0].ID), track.Artists[0].Name
+	}
+	res, err := r.db.Exec(`INSERT INTO plays (track_id, track_name, artist_id, artist_name, album_id, album_name, duration_ms, context_uri, context_type, device_name, started_at, listened_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0)`,
+		string(track.ID), track.Name, artistID, artistName, string(track.Album.ID), track.Album.Name, int(track.Duration),
+		string(state.PlaybackContext.URI), state.PlaybackContext.Type, state.Device.Name, startedAt.Unix())
+	if err != nil {
+		log.Trace().Err(err).Msg("failed to record play")
+		r.current = nil
+		return
+	}
+	rowID, _ := res.LastInsertId()
+	r.current = &play{rowID: rowID, trackID: track.ID, saved: time.Now()}
+}
+
+func (r *Recorder) save() {
+	r.current.saved = time.Now()
+	_, err := r.db.Exec("UPDATE plays SET listened_ms = ? WHERE id = ?", r.current.listened.Milliseconds(), r.current.rowID)
+	if err != nil {
+		log.Trace().Err(err).Msg("failed to update play")
+	}
+}
+
+func (r *Recorder) finish() {
+	if r.current != nil {
+		r.save()
+		r.current = nil
+	}
+}
+
+// Close writes the current play and closes the database.
+func (r *Recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.finish()
+	r.owner.Unlock()
+	return r.db.Close()
+}
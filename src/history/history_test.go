@@ -0,0 +1,151 @@
+package history
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+func openTest(t *testing.T) (*sql.DB, string) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, path
+}
+
+func addPlay(t *testing.T, db *sql.DB, track, artist, album string, started time.Time, listened, duration time.Duration) {
+	_, err := db.Exec(`INSERT INTO plays (track_id, track_name, artist_id, artist_name, album_id, album_name, duration_ms, context_uri, context_type, device_name, started_at, listened_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?, '', '', '', ?, ?)`,
+		track, track, artist, artist, album, album, duration.Milliseconds(), started.Unix(), listened.Milliseconds())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStats(t *testing.T) {
+	db, _ := openTest(t)
+	day := time.Date(2024, 1, 10, 12, 0, 0, 0, time.Local)
+	full := 3 * time.Minute
+	addPlay(t, db, "airbag", "radiohead", "ok computer", day, full, full)
+	addPlay(t, db, "airbag", "radiohead", "ok computer", day.Add(time.Hour), full, full)
+	addPlay(t, db, "lucky", "radiohead", "ok computer", day.Add(2*time.Hour), full, full)
+	addPlay(t, db, "teardrop", "massive attack", "mezzanine", day.Add(3*time.Hour), full, full)
+	// a skip is counted as a play but not in the tops
+	addPlay(t, db, "teardrop", "massive attack", "mezzanine", day.Add(4*time.Hour), 10*time.Second, full)
+	addPlay(t, db, "teardrop", "massive attack", "mezzanine", day.Add(5*time.Hour), 5*time.Second, full)
+	// out of range
+	addPlay(t, db, "lucky", "radiohead", "ok computer", day.AddDate(0, 0, -10), full, full)
+	addPlay(t, db, "lucky", "radiohead", "ok computer", day.AddDate(0, 0, 10), full, full)
+
+	report, err := Stats(db, day.Add(-time.Hour), day.AddDate(0, 0, 1), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Plays != 6 || report.Skips != 2 || report.SkipRate != 2.0/6 {
+		t.Errorf("plays %d, skips %d, skip rate %f", report.Plays, report.Skips, report.SkipRate)
+	}
+	if want := (4*full + 15*time.Second).Milliseconds(); report.ListenedMs != want {
+		t.Errorf("listened %d, want %d", report.ListenedMs, want)
+	}
+	if len(report.TopTracks) != 3 || report.TopTracks[0].ID != "airbag" || report.TopTracks[0].Plays != 2 || report.TopTracks[0].Artist != "radiohead" {
+		t.Errorf("top tracks %+v", report.TopTracks)
+	}
+	if len(report.TopArtists) != 2 || report.TopArtists[0].ID != "radiohead" || report.TopArtists[0].Plays != 3 || report.TopArtists[1].Plays != 1 {
+		t.Errorf("top artists %+v", report.TopArtists)
+	}
+	if len(report.TopAlbums) != 2 || report.TopAlbums[0].ID != "ok computer" || report.TopAlbums[0].ListenedMs != (3*full).Milliseconds() {
+		t.Errorf("top albums %+v", report.TopAlbums)
+	}
+	if len(report.Days) != 1 || report.Days[0].Date != "2024-01-10" || report.Days[0].Plays != 6 {
+		t.Errorf("days %+v", report.Days)
+	}
+
+	limited, err := Stats(db, day.Add(-time.Hour), day.AddDate(0, 0, 1), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limited.TopTracks) != 1 || len(limited.TopArtists) != 1 {
+		t.Errorf("limit 1 gave %d tracks and %d artists", len(limited.TopTracks), len(limited.TopArtists))
+	}
+
+	empty, err := Stats(db, day.AddDate(1, 0, 0), day.AddDate(1, 0, 1), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if empty.Plays != 0 || empty.SkipRate != 0 || len(empty.TopTracks) != 0 {
+		t.Errorf("empty range %+v", empty)
+	}
+}
+
+func TestRecorderCloseSavesCurrentPlay(t *testing.T) {
+	_, path := openTest(t)
+	r, err := NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := &spotify.PlayerState{CurrentlyPlaying: spotify.CurrentlyPlaying{
+		Playing: true,
+		Item: &spotify.FullTrack{SimpleTrack: spotify.SimpleTrack{
+			ID: "airbag", Name: "Airbag", Duration: 284000,
+			Artists: []spotify.SimpleArtist{{ID: "radiohead", Name: "Radiohead"}},
+		}},
+	}}
+	r.Update(state)
+	// five seconds later, less than saveEvery
+	r.lastSeen = r.lastSeen.Add(-5 * time.Second)
+	state.Progress = 5000
+	r.Update(state)
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var listened int64
+	if err := db.QueryRow("SELECT listened_ms FROM plays WHERE track_id = 'airbag'").Scan(&listened); err != nil {
+		t.Fatal(err)
+	}
+	if listened != 5000 {
+		t.Errorf("listened_ms = %d, want 5000", listened)
+	}
+}
+
+func TestOneRecordingProcess(t *testing.T) {
+	db, path := openTest(t)
+	first, err := NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	state := &spotify.PlayerState{CurrentlyPlaying: spotify.CurrentlyPlaying{
+		Playing: true,
+		Item:    &spotify.FullTrack{SimpleTrack: spotify.SimpleTrack{ID: "airbag", Name: "Airbag"}},
+	}}
+	first.Update(state)
+	second.Update(state)
+	first.Close()
+	// the second recorder takes over once the first is closed
+	state.Item = &spotify.FullTrack{SimpleTrack: spotify.SimpleTrack{ID: "paranoid", Name: "Paranoid Android"}}
+	second.Update(state)
+
+	var plays int
+	if err := db.QueryRow("SELECT COUNT(*) FROM plays").Scan(&plays); err != nil {
+		t.Fatal(err)
+	}
+	if plays != 2 {
+		t.Errorf("recorded %d plays, want 2", plays)
+	}
+}
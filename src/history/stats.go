@@ -0,0 +1,126 @@
+package history
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"time"
+)
+
+// skipped is the sql condition for a play that was skipped, stopped within
+// the first 30 seconds and before the track was nearly over.
+const skipped = "(listened_ms < 30000 AND listened_ms < duration_ms * 9 / 10)"
+
+type Count struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Artist     string `json:"artist,omitempty"`
+	Plays      int    `json:"plays"`
+	ListenedMs int64  `json:"listened_ms"`
+}
+
+type Day struct {
+	Date       string `json:"date"`
+	Plays      int    `json:"plays"`
+	ListenedMs int64  `json:"listened_ms"`
+}
+
+type Report struct {
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	Plays      int       `json:"plays"`
+	ListenedMs int64     `json:"listened_ms"`
+	Skips      int       `json:"skips"`
+	SkipRate   float64   `json:"skip_rate"`
+	TopTracks  []Count   `json:"top_tracks"`
+	TopArtists []Count   `json:"top_artists"`
+	TopAlbums  []Count   `json:"top_albums"`
+	Days       []Day     `json:"days"`
+}
+
+// Stats summarizes the plays started between from and to.
+func Stats(db *sql.DB, from, to time.Time, limit int) (*Report, error) {
+	report := &Report{From: from, To: to}
+	args := []any{from.Unix(), to.Unix()}
+	err := db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(listened_ms), 0), COALESCE(SUM(CASE WHEN `+skipped+` THEN 1 ELSE 0 END), 0)
+		FROM plays WHERE started_at >= ? AND started_at < ?`, args...).
+		Scan(&report.Plays, &report.ListenedMs, &report.Skips)
+	if err != nil {
+		return nil, err
+	}
+	if report.Plays > 0 {
+		report.SkipRate = float64(report.Skips) / float64(report.Plays)
+	}
+	top := func(id, name, artist string) ([]Count, error) {
+		rows, err := db.Query(fmt.Sprintf(`SELECT %s, MAX(%s), MAX(%s), COUNT(*), SUM(listened_ms)
+			FROM plays WHERE started_at >= ? AND started_at < ? AND NOT %s
+			GROUP BY %s ORDER BY COUNT(*) DESC, SUM(listened_ms) DESC LIMIT ?`, id, name, artist, skipped, id), from.Unix(), to.Unix(), limit)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		out := []Count{}
+		for rows.Next() {
+			count := Count{}
+			if err := rows.Scan(&count.ID, &count.Name, &count.Artist, &count.Plays, &count.ListenedMs); err != nil {
+				return nil, err
+			}
+			out = append(out, count)
+		}
+		return out, rows.Err()
+	}
+	if report.TopTracks, err = top("track_id", "track_name", "artist_name"); err != nil {
+		return nil, err
+	}
+	if report.TopArtists, err = top("artist_id", "artist_name", "''"); err != nil {
+		return nil, err
+	}
+	if report.TopAlbums, err = top("album_id", "album_name", "artist_name"); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT date(started_at, 'unixepoch', 'localtime') AS day, COUNT(*), SUM(listened_ms)
+		FROM plays WHERE started_at >= ? AND started_at < ? GROUP BY day ORDER BY day`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		day := Day{}
+		if err := rows.Scan(&day.Date, &day.Plays, &day.ListenedMs); err != nil {
+			return nil, err
+		}
+		report.Days = append(report.Days, day)
+	}
+	return report, rows.Err()
+}
+
+func listened(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).Round(time.Minute).String()
+}
+
+func (r *Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "%s - %s\n", r.From.Format(time.DateOnly), r.To.Format(time.DateOnly))
+	fmt.Fprintf(w, "%d plays, %s listened, %.1f%% skipped\n", r.Plays, listened(r.ListenedMs), r.SkipRate*100)
+	sections := []struct {
+		title  string
+		counts []Count
+	}{
+		{"Top tracks", r.TopTracks},
+		{"Top artists", r.TopArtists},
+		{"Top albums", r.TopAlbums},
+	}
+	for _, section := range sections {
+		fmt.Fprintf(w, "\n%s\n", section.title)
+		for idx, count := range section.counts {
+			name := count.Name
+			if count.Artist != "" {
+				name += " - " + count.Artist
+			}
+			fmt.Fprintf(w, "%3d. %s (%d plays, %s)\n", idx+1, name, count.Plays, listened(count.ListenedMs))
+		}
+	}
+	fmt.Fprintf(w, "\nListening per day\n")
+	for _, day := range r.Days {
+		fmt.Fprintf(w, "%s %4d plays %s\n", day.Date, day.Plays, listened(day.ListenedMs))
+	}
+}
@@ -0,0 +1,83 @@
+// Package lock holds advisory file locks shared between gospt processes, so
+// the TUI and the long running commands can tell which of them does a job.
+package lock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"tuxpa.in/a/zlog/log"
+)
+
+// errLocked is returned by flock when another process holds the lock.
+var errLocked = errors.New("locked by another process")
+
+// File is an advisory lock on a file. The lock is released when it is
+// unlocked or the process exits.
+type File struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+func New(path string) *File {
+	return &File{path: path}
+}
+
+// TryLock takes the lock without waiting and reports whether this process
+// holds it. When the lock file cannot be opened at all the lock is treated as
+// held, so a read only config dir does not turn the job off.
+func (l *File) TryLock() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file != nil {
+		return true
+	}
+	err := l.lock(false)
+	if err != nil && !errors.Is(err, errLocked) {
+		log.Trace().Err(err).Str("path", l.path).Msg("failed to open lock file")
+		return true
+	}
+	return err == nil
+}
+
+// Lock waits until the lock is free and takes it.
+func (l *File) Lock() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file != nil {
+		return nil
+	}
+	return l.lock(true)
+}
+
+func (l *File) lock(wait bool) error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := flock(f, wait); err != nil {
+		f.Close()
+		return err
+	}
+	l.file = f
+	return nil
+}
+
+// Unlock releases the lock if this process holds it.
+func (l *File) Unlock() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
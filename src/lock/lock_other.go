@@ -0,0 +1,10 @@
+//go:build !unix
+
+package lock
+
+import "os"
+
+// flock does not lock anything, without flock every process does every job.
+func flock(f *os.File, wait bool) error {
+	return nil
+}
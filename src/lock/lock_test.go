@@ -0,0 +1,49 @@
+//go:build unix
+
+package lock
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTryLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gospt", "job.lock")
+	first, second := New(path), New(path)
+	if !first.TryLock() {
+		t.Fatal("a free lock was not taken")
+	}
+	if !first.TryLock() {
+		t.Error("a held lock is not held anymore")
+	}
+	if second.TryLock() {
+		t.Fatal("a lock was taken twice")
+	}
+	if err := first.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if !second.TryLock() {
+		t.Error("an unlocked lock was not taken")
+	}
+}
+
+func TestLockWaits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "job.lock")
+	first, second := New(path), New(path)
+	if err := first.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	locked := make(chan error)
+	go func() { locked <- second.Lock() }()
+	select {
+	case <-locked:
+		t.Fatal("a held lock was taken")
+	case <-time.After(50 * time.Millisecond):
+	}
+	first.Unlock()
+	if err := <-locked; err != nil {
+		t.Fatal(err)
+	}
+	second.Unlock()
+}
@@ -0,0 +1,92 @@
+package scrobble
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// lastFMServer checks the signature of each call, records its parameters and
+// answers with body.
+func lastFMServer(t *testing.T, body *string, got *[]url.Values) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parse: %v", err)
+		}
+		params := r.PostForm
+		sig := params.Get("api_sig")
+		params.Del("api_sig")
+		if want := (&LastFM{Secret: "shh"}).sign(params); sig != want {
+			t.Errorf("api_sig = %s, want %s", sig, want)
+		}
+		*got = append(*got, params)
+		fmt.Fprint(w, *body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLastFMSign(t *testing.T) {
+	params := url.Values{}
+	params.Set("method", "track.scrobble")
+	params.Set("api_key", "key")
+	params.Set("sk", "session")
+	params.Set("format", "json")
+	// sorted keys with their values, format left out, then the secret
+	sum := md5.Sum([]byte("api_keykeymethodtrack.scrobblesksessionshh"))
+	if got := (&LastFM{Secret: "shh"}).sign(params); got != hex.EncodeToString(sum[:]) {
+		t.Errorf("sign = %s, want %s", got, hex.EncodeToString(sum[:]))
+	}
+}
+
+func TestLastFMSubmit(t *testing.T) {
+	body, got := `{"scrobbles":{}}`, []url.Values{}
+	server := lastFMServer(t, &body, &got)
+	l := &LastFM{URL: server.URL, APIKey: "key", Secret: "shh", SessionKey: "session", HTTP: server.Client()}
+	listens := []Listen{
+		{Artist: "Radiohead", Track: "Airbag", Album: "OK Computer", DurationMs: 284000, ListenedAt: 100},
+		{Artist: "Radiohead", Track: "Lucky", Album: "OK Computer", DurationMs: 259000, ListenedAt: 400},
+	}
+
+	if err := l.Submit(context.Background(), listens); err != nil {
+		t.Fatal(err)
+	}
+	params := got[0]
+	for key, want := range map[string]string{
+		"method":       "track.scrobble",
+		"api_key":      "key",
+		"sk":           "session",
+		"format":       "json",
+		"artist[0]":    "Radiohead",
+		"track[1]":     "Lucky",
+		"timestamp[1]": "400",
+		"duration[0]":  "284",
+	} {
+		if params.Get(key) != want {
+			t.Errorf("%s = %q, want %q", key, params.Get(key), want)
+		}
+	}
+
+	body = `{"error":9,"message":"Invalid session key"}`
+	if err := l.Submit(context.Background(), listens); err == nil {
+		t.Error("Submit succeeded on an error response")
+	}
+}
+
+func TestLastFMNowPlaying(t *testing.T) {
+	body, got := `{"nowplaying":{}}`, []url.Values{}
+	server := lastFMServer(t, &body, &got)
+	l := &LastFM{URL: server.URL, APIKey: "key", Secret: "shh", SessionKey: "session", HTTP: server.Client()}
+
+	if err := l.NowPlaying(context.Background(), Listen{Artist: "Radiohead", Track: "Airbag", DurationMs: 284000}); err != nil {
+		t.Fatal(err)
+	}
+	if got[0].Get("method") != "track.updateNowPlaying" || got[0].Get("track") != "Airbag" || got[0].Get("duration") != "284" {
+		t.Errorf("params = %v", got[0])
+	}
+}
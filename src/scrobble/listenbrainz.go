@@ -0,0 +1,103 @@
+package scrobble
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const defaultListenBrainzURL = "https://api.listenbrainz.org"
+
+// ListenBrainz submits listens to a ListenBrainz compatible server. URL is the
+// base of the api and can point to a self hosted or local stand-in server.
+type ListenBrainz struct {
+	URL   string
+	Token string
+	HTTP  *http.Client
+}
+
+type lbPayload struct {
+	ListenedAt    int64           `json:"listened_at,omitempty"`
+	TrackMetadata lbTrackMetadata `json:"track_metadata"`
+}
+
+type lbTrackMetadata struct {
+	ArtistName     string         `json:"artist_name"`
+	TrackName      string         `json:"track_name"`
+	ReleaseName    string         `json:"release_name,omitempty"`
+	AdditionalInfo map[string]any `json:"additional_info"`
+}
+
+func (l *ListenBrainz) Name() string {
+	return "listenbrainz"
+}
+
+func (l *ListenBrainz) NowPlaying(ctx context.Context, listen Listen) error {
+	listen.ListenedAt = 0
+	return l.send(ctx, "playing_now", []Listen{listen})
+}
+
+func (l *ListenBrainz) Submit(ctx context.Context, listens []Listen) error {
+	listenType := "import"
+	if len(listens) == 1 {
+		listenType = "single"
+	}
+	return l.send(ctx, listenType, listens)
+}
+
+func (l *ListenBrainz) send(ctx context.Context, listenType string, listens []Listen) error {
+	payload := []lbPayload{}
+	for _, listen := range listens {
+		info := map[string]any{
+			"submission_client": "gospt",
+			"music_service":     "spotify.com",
+			"duration_ms":       listen.DurationMs,
+		}
+		if listen.SpotifyID != "" {
+			info["spotify_id"] = "https://open.spotify.com/track/" + listen.SpotifyID
+		}
+		if listen.ISRC != "" {
+			info["isrc"] = listen.ISRC
+		}
+		payload = append(payload, lbPayload{
+			ListenedAt: listen.ListenedAt,
+			TrackMetadata: lbTrackMetadata{
+				ArtistName:     listen.Artist,
+				TrackName:      listen.Track,
+				ReleaseName:    listen.Album,
+				AdditionalInfo: info,
+			},
+		})
+	}
+	body, err := json.Marshal(map[string]any{
+		"listen_type": listenType,
+		"payload":     payload,
+	})
+	if err != nil {
+		return err
+	}
+	base := l.URL
+	if base == "" {
+		base = defaultListenBrainzURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(base, "/")+"/1/submit-listens", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Token "+l.Token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := l.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("listenbrainz: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
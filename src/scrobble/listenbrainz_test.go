@@ -0,0 +1,81 @@
+package scrobble
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type lbRequest struct {
+	ListenType string      `json:"listen_type"`
+	Payload    []lbPayload `json:"payload"`
+}
+
+// listenBrainzServer records the submissions it gets and answers with status.
+func listenBrainzServer(t *testing.T, status *int, got *[]lbRequest) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/1/submit-listens" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Token secret" {
+			t.Errorf("Authorization = %q", auth)
+		}
+		req := lbRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		*got = append(*got, req)
+		w.WriteHeader(*status)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestListenBrainzSubmit(t *testing.T) {
+	status, got := http.StatusOK, []lbRequest{}
+	server := listenBrainzServer(t, &status, &got)
+	l := &ListenBrainz{URL: server.URL + "/", Token: "secret", HTTP: server.Client()}
+	listen := Listen{Artist: "Radiohead", Track: "Airbag", Album: "OK Computer", DurationMs: 284000, ListenedAt: 100, SpotifyID: "abc", ISRC: "GBAYE9700123"}
+
+	if err := l.Submit(context.Background(), []Listen{listen}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Submit(context.Background(), []Listen{listen, listen}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ListenType != "single" || got[1].ListenType != "import" || len(got[1].Payload) != 2 {
+		t.Fatalf("requests = %+v", got)
+	}
+	payload := got[0].Payload[0]
+	if payload.ListenedAt != 100 || payload.TrackMetadata.ArtistName != "Radiohead" || payload.TrackMetadata.TrackName != "Airbag" || payload.TrackMetadata.ReleaseName != "OK Computer" {
+		t.Errorf("payload = %+v", payload)
+	}
+	info := payload.TrackMetadata.AdditionalInfo
+	if info["spotify_id"] != "https://open.spotify.com/track/abc" || info["isrc"] != "GBAYE9700123" || info["duration_ms"] != float64(284000) {
+		t.Errorf("additional_info = %v", info)
+	}
+
+	status = http.StatusUnauthorized
+	if err := l.Submit(context.Background(), []Listen{listen}); err == nil {
+		t.Error("Submit succeeded on 401")
+	}
+}
+
+func TestListenBrainzNowPlaying(t *testing.T) {
+	status, got := http.StatusOK, []lbRequest{}
+	server := listenBrainzServer(t, &status, &got)
+	l := &ListenBrainz{URL: server.URL, Token: "secret", HTTP: server.Client()}
+
+	if err := l.NowPlaying(context.Background(), Listen{Artist: "Radiohead", Track: "Airbag", ListenedAt: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ListenType != "playing_now" {
+		t.Fatalf("requests = %+v", got)
+	}
+	// playing now listens have no time
+	if got[0].Payload[0].ListenedAt != 0 {
+		t.Errorf("listened_at = %d", got[0].Payload[0].ListenedAt)
+	}
+}
@@ -0,0 +1,311 @@
+package scrobble
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+	"tuxpa.in/a/zlog/log"
+
+	"git.asdf.cafe/abs3nt/gospt/src/config"
+	"git.asdf.cafe/abs3nt/gospt/src/lock"
+)
+
+const (
+	// maxThreshold is the longest a track has to play before it is submitted,
+	// shorter tracks are submitted after half of their duration.
+	maxThreshold = 4 * time.Minute
+	// minDuration is the shortest track that is submitted at all.
+	minDuration = 30 * time.Second
+	retryEvery  = time.Minute
+	// closeWait is how long Close waits for submissions still in flight.
+	closeWait = 5 * time.Second
+)
+
+var errScrobbling = errors.New("another gospt process is scrobbling, it retries the queue itself")
+
+type Listen struct {
+	Artist     string `json:"artist"`
+	Track      string `json:"track"`
+	Album      string `json:"album"`
+	DurationMs int    `json:"duration_ms"`
+	ListenedAt int64  `json:"listened_at"`
+	SpotifyID  string `json:"spotify_id"`
+	ISRC       string `json:"isrc"`
+}
+
+type Service interface {
+	Name() string
+	NowPlaying(ctx context.Context, listen Listen) error
+	Submit(ctx context.Context, listens []Listen) error
+}
+
+// FromConfig returns the services that have credentials in the config.
+func FromConfig() []Service {
+	cfg := config.Values.Scrobble
+	services := []Service{}
+	if cfg.ListenBrainzToken != "" {
+		services = append(services, &ListenBrainz{URL: cfg.ListenBrainzURL, Token: cfg.ListenBrainzToken, HTTP: http.DefaultClient})
+	}
+	if cfg.LastFMAPIKey != "" && cfg.LastFMSessionKey != "" {
+		services = append(services, &LastFM{URL: cfg.LastFMURL, APIKey: cfg.LastFMAPIKey, Secret: cfg.LastFMSecret, SessionKey: cfg.LastFMSessionKey, HTTP: http.DefaultClient})
+	}
+	return services
+}
+
+func DefaultQueuePath() string {
+	configDir, _ := os.UserConfigDir()
+	return filepath.Join(configDir, "gospt/scrobble_queue.json")
+}
+
+// Scrobbler follows the current playback and submits a listen once a track
+// has played for half its duration or four minutes. Failed submissions are
+// kept in a queue on disk and retried on later updates. Only one process
+// scrobbles at a time, the scrobblers of other processes ignore their updates
+// until it exits.
+type Scrobbler struct {
+	Services  []Service
+	QueuePath string
+
+	owner    *lock.File
+	inFlight sync.WaitGroup
+	flushMu  sync.Mutex
+
+	mu        sync.Mutex
+	current   *Listen
+	threshold time.Duration
+	played    time.Duration
+	progress  time.Duration
+	lastSeen  time.Time
+	submitted bool
+	lastRetry time.Time
+}
+
+func New(services []Service, queuePath string) *Scrobbler {
+	return &Scrobbler{
+		Services:  services,
+		QueuePath: queuePath,
+		owner:     lock.New(filepath.Join(filepath.Dir(queuePath), "scrobble.lock")),
+	}
+}
+
+func listenFromTrack(track *spotify.FullTrack) Listen {
+	artists := []string{}
+	for _, artist := range track.Artists {
+		artists = append(artists, artist.Name)
+	}
+	return Listen{
+		Artist:     strings.Join(artists, ", "),
+		Track:      track.Name,
+		Album:      track.Album.Name,
+		DurationMs: int(track.Duration),
+		SpotifyID:  string(track.ID),
+		ISRC:       track.ExternalIDs["isrc"],
+	}
+}
+
+// Update feeds the scrobbler a fresh playback state, it is meant to be called
+// on every poll of the current playback.
+func (s *Scrobbler) Update(ctx context.Context, playing *spotify.CurrentlyPlaying) {
+	if len(s.Services) == 0 || !s.owner.TryLock() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	defer func() { s.lastSeen = now }()
+	if time.Since(s.lastRetry) > retryEvery {
+		s.lastRetry = now
+		s.async(func() { s.Flush(ctx) })
+	}
+	if playing == nil || playing.Item == nil || playing.Item.Type == "episode" {
+		return
+	}
+	progress := time.Duration(playing.Progress) * time.Millisecond
+	replayed := s.submitted && progress < s.progress && progress < 10*time.Second
+	if s.current == nil || s.current.SpotifyID != string(playing.Item.ID) || replayed {
+		listen := listenFromTrack(playing.Item)
+		listen.ListenedAt = now.Add(-progress).Unix()
+		s.current = &listen
+		s.threshold = min(time.Duration(listen.DurationMs)*time.Millisecond/2, maxThreshold)
+		s.played = 0
+		s.progress = progress
+		s.submitted = false
+		if playing.Playing {
+			s.async(func() { s.nowPlaying(ctx, listen) })
+		}
+		return
+	}
+	if playing.Playing && !s.lastSeen.IsZero() {
+		// count wall clock time, but never more than the track advanced so seeks don't count
+		elapsed := now.Sub(s.lastSeen)
+		if advanced := progress - s.progress; advanced >= 0 && advanced < elapsed {
+			elapsed = advanced
+		}
+		if elapsed > 0 {
+			s.played += elapsed
+		}
+	}
+	s.progress = progress
+	if !s.submitted && s.played >= s.threshold && time.Duration(s.current.DurationMs)*time.Millisecond >= minDuration {
+		s.submitted = true
+		listen := *s.current
+		s.async(func() { s.submit(ctx, listen) })
+	}
+}
+
+func (s *Scrobbler) async(fn func()) {
+	s.inFlight.Add(1)
+	go func() {
+		defer s.inFlight.Done()
+		fn()
+	}()
+}
+
+// Close waits a while for submissions still in flight, listens that fail
+// because the context was cancelled are queued on disk, and lets another
+// process take over scrobbling.
+func (s *Scrobbler) Close() error {
+	done := make(chan struct{})
+	go func() {
+		s.inFlight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(closeWait):
+		log.Trace().Msg("scrobbles still in flight at exit")
+	}
+	return s.owner.Unlock()
+}
+
+func (s *Scrobbler) nowPlaying(ctx context.Context, listen Listen) {
+	for _, service := range s.Services {
+		if err := service.NowPlaying(ctx, listen); err != nil {
+			log.Trace().Err(err).Str("service", service.Name()).Msg("now playing failed")
+		}
+	}
+}
+
+func (s *Scrobbler) submit(ctx context.Context, listen Listen) {
+	for _, service := range s.Services {
+		if err := service.Submit(ctx, []Listen{listen}); err != nil {
+			log.Trace().Err(err).Str("service", service.Name()).Msg("scrobble failed, queueing")
+			s.enqueue(service.Name(), listen)
+		}
+	}
+}
+
+type queued struct {
+	Service string `json:"service"`
+	Listen  Listen `json:"listen"`
+}
+
+var queueMu sync.Mutex
+
+func (s *Scrobbler) loadQueue() []queued {
+	out := []queued{}
+	raw, err := os.ReadFile(s.QueuePath)
+	if err != nil {
+		return out
+	}
+	json.Unmarshal(raw, &out)
+	return out
+}
+
+func (s *Scrobbler) saveQueue(q []queued) error {
+	if len(q) == 0 {
+		err := os.Remove(s.QueuePath)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	raw, err := json.Marshal(q)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.QueuePath, raw, 0o600)
+}
+
+func (s *Scrobbler) enqueue(service string, listen Listen) {
+	queueMu.Lock()
+	defer queueMu.Unlock()
+	q := append(s.loadQueue(), queued{Service: service, Listen: listen})
+	if err := s.saveQueue(q); err != nil {
+		log.Trace().Err(err).Msg("failed to save scrobble queue")
+	}
+}
+
+// Pending returns the number of listens waiting to be resubmitted.
+func (s *Scrobbler) Pending() int {
+	queueMu.Lock()
+	defer queueMu.Unlock()
+	return len(s.loadQueue())
+}
+
+// Flush retries every queued listen, listens that fail again stay queued. The
+// queue is not locked while submitting, so listens queued meanwhile are kept.
+func (s *Scrobbler) Flush(ctx context.Context) error {
+	if !s.owner.TryLock() {
+		return errScrobbling
+	}
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+	queueMu.Lock()
+	q := s.loadQueue()
+	queueMu.Unlock()
+	byService := map[string][]Listen{}
+	for _, item := range q {
+		byService[item.Service] = append(byService[item.Service], item.Listen)
+	}
+	sent := map[queued]int{}
+	var lastErr error
+	for name, listens := range byService {
+		var service Service
+		for _, candidate := range s.Services {
+			if candidate.Name() == name {
+				service = candidate
+			}
+		}
+		if service == nil {
+			// keep listens for services that are not configured right now
+			continue
+		}
+		for start := 0; start < len(listens); start += 50 {
+			batch := listens[start:min(start+50, len(listens))]
+			if err := service.Submit(ctx, batch); err != nil {
+				lastErr = fmt.Errorf("%s: %w", name, err)
+				continue
+			}
+			for _, listen := range batch {
+				sent[queued{Service: name, Listen: listen}]++
+			}
+		}
+	}
+	if len(sent) == 0 {
+		return lastErr
+	}
+	queueMu.Lock()
+	defer queueMu.Unlock()
+	remaining := []queued{}
+	for _, item := range s.loadQueue() {
+		if sent[item] > 0 {
+			sent[item]--
+			continue
+		}
+		remaining = append(remaining, item)
+	}
+	if err := s.saveQueue(remaining); err != nil {
+		return err
+	}
+	return lastErr
+}
@@ -0,0 +1,78 @@
+package scrobble
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestQueueAndFlush(t *testing.T) {
+	var up atomic.Bool
+	var submitted atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		submitted.Add(1)
+	}))
+	defer server.Close()
+	s := New([]Service{&ListenBrainz{URL: server.URL, Token: "secret", HTTP: server.Client()}}, filepath.Join(t.TempDir(), "queue.json"))
+	listen := Listen{Artist: "Radiohead", Track: "Airbag", DurationMs: 284000, ListenedAt: 100}
+
+	// a failed submission is queued
+	s.submit(context.Background(), listen)
+	s.submit(context.Background(), listen)
+	if pending := s.Pending(); pending != 2 {
+		t.Fatalf("Pending = %d after failures, want 2", pending)
+	}
+	if err := s.Flush(context.Background()); err == nil {
+		t.Error("Flush succeeded while the server is down")
+	}
+	if pending := s.Pending(); pending != 2 {
+		t.Fatalf("Pending = %d after a failed flush, want 2", pending)
+	}
+
+	// and sent once the server is back
+	up.Store(true)
+	if err := s.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if pending := s.Pending(); pending != 0 {
+		t.Errorf("Pending = %d after flushing, want 0", pending)
+	}
+	if submitted.Load() != 1 {
+		t.Errorf("submitted %d batches, want 1", submitted.Load())
+	}
+}
+
+func TestFlushKeepsUnconfiguredServices(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+	s := New(nil, path)
+	s.enqueue("lastfm", Listen{Track: "Airbag"})
+	if err := s.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if pending := s.Pending(); pending != 1 {
+		t.Errorf("Pending = %d, want the listen kept for lastfm", pending)
+	}
+}
+
+func TestOneScrobblingProcess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+	first, second := New(nil, path), New(nil, path)
+	first.enqueue("lastfm", Listen{Track: "Airbag"})
+	if err := first.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Flush(context.Background()); err != errScrobbling {
+		t.Errorf("Flush while another scrobbler runs = %v, want %v", err, errScrobbling)
+	}
+	first.Close()
+	if err := second.Flush(context.Background()); err != nil {
+		t.Errorf("Flush after the other scrobbler closed = %v", err)
+	}
+}
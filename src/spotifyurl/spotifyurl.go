@@ -0,0 +1,146 @@
+// Package spotifyurl resolves spotify uris, open.spotify.com links and
+// shortened spotify.link links to the item they point at.
+package spotifyurl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+type Kind string
+
+const (
+	Track     Kind = "track"
+	Album     Kind = "album"
+	Playlist  Kind = "playlist"
+	Artist    Kind = "artist"
+	Episode   Kind = "episode"
+	Show      Kind = "show"
+	Audiobook Kind = "audiobook"
+	Chapter   Kind = "chapter"
+	User      Kind = "user"
+)
+
+var kinds = map[Kind]bool{Track: true, Album: true, Playlist: true, Artist: true, Episode: true, Show: true, Audiobook: true, Chapter: true, User: true}
+
+// Target is the item a link points at.
+type Target struct {
+	Kind Kind
+	ID   spotify.ID
+}
+
+func (t Target) URI() spotify.URI {
+	return spotify.URI(fmt.Sprintf("spotify:%s:%s", t.Kind, t.ID))
+}
+
+func (t Target) URL() string {
+	return fmt.Sprintf("https://open.spotify.com/%s/%s", t.Kind, t.ID)
+}
+
+// IsContext reports whether the target can be played as a playback context.
+func (t Target) IsContext() bool {
+	switch t.Kind {
+	case Album, Playlist, Artist, Show, Audiobook:
+		return true
+	}
+	return false
+}
+
+func (t Target) String() string {
+	return string(t.URI())
+}
+
+var shortHosts = map[string]bool{"spotify.link": true, "spotify.app.link": true}
+
+// IsShort reports whether s is a shortened link that has to be resolved over
+// the network.
+func IsShort(s string) bool {
+	u, err := url.Parse(strings.TrimSpace(s))
+	return err == nil && shortHosts[u.Host]
+}
+
+// Parse parses spotify uris like spotify:track:id, including the old
+// spotify:user:name:playlist:id form, and open.spotify.com links including
+// intl-xx and embed paths. It does not resolve shortened links.
+func Parse(s string) (Target, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "spotify:") {
+		parts := strings.Split(s, ":")[1:]
+		return fromParts(s, parts)
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		if !strings.Contains(s, "/") {
+			return Target{}, fmt.Errorf("not a spotify link or uri: %q", s)
+		}
+		u, err = url.Parse("https://" + s)
+		if err != nil {
+			return Target{}, fmt.Errorf("not a spotify link or uri: %q", s)
+		}
+	}
+	if shortHosts[u.Host] {
+		return Target{}, fmt.Errorf("%s is a shortened link and has to be resolved", s)
+	}
+	if u.Host != "open.spotify.com" && u.Host != "play.spotify.com" {
+		return Target{}, fmt.Errorf("not a spotify link: %q", s)
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for len(parts) > 0 && (strings.HasPrefix(parts[0], "intl-") || parts[0] == "embed") {
+		parts = parts[1:]
+	}
+	return fromParts(s, parts)
+}
+
+func fromParts(s string, parts []string) (Target, error) {
+	// spotify:user:name:playlist:id
+	if len(parts) == 4 && parts[0] == string(User) && parts[2] == string(Playlist) {
+		parts = parts[2:]
+	}
+	// anything else under a user, like their collection, is not one item
+	if len(parts) > 2 && parts[0] == string(User) {
+		return Target{}, fmt.Errorf("not a spotify link or uri: %q", s)
+	}
+	if len(parts) < 2 || !kinds[Kind(parts[0])] || parts[1] == "" {
+		return Target{}, fmt.Errorf("not a spotify link or uri: %q", s)
+	}
+	return Target{Kind: Kind(parts[0]), ID: spotify.ID(parts[1])}, nil
+}
+
+var openLink = regexp.MustCompile(`https://open\.spotify\.com/[^"'\s<>]+`)
+
+// Resolve is like Parse but also follows shortened links.
+func Resolve(ctx context.Context, client *http.Client, s string) (Target, error) {
+	if !IsShort(s) {
+		return Parse(s)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSpace(s), nil)
+	if err != nil {
+		return Target{}, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Target{}, err
+	}
+	defer resp.Body.Close()
+	if target, err := Parse(resp.Request.URL.String()); err == nil {
+		return target, nil
+	}
+	// spotify.app.link answers with a page that links to open.spotify.com
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return Target{}, err
+	}
+	for _, link := range openLink.FindAllString(string(body), -1) {
+		if target, err := Parse(link); err == nil {
+			return target, nil
+		}
+	}
+	return Target{}, fmt.Errorf("could not resolve %s", s)
+}
@@ -0,0 +1,114 @@
+package spotifyurl
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Target
+	}{
+		{"https://open.spotify.com/track/4uLU6hMCjMI75M1A2tKUQC", Target{Track, "4uLU6hMCjMI75M1A2tKUQC"}},
+		{"http://open.spotify.com/album/1DFixLWuPkv3KT3TnV35m3/", Target{Album, "1DFixLWuPkv3KT3TnV35m3"}},
+		{"open.spotify.com/artist/4Z8W4fKeB5YxbusRsdQVPb", Target{Artist, "4Z8W4fKeB5YxbusRsdQVPb"}},
+		{"https://play.spotify.com/show/5CfCWKI5pZ28U0uOzXkDHe", Target{Show, "5CfCWKI5pZ28U0uOzXkDHe"}},
+		{"https://open.spotify.com/intl-de/track/4uLU6hMCjMI75M1A2tKUQC", Target{Track, "4uLU6hMCjMI75M1A2tKUQC"}},
+		{"https://open.spotify.com/intl-pt/embed/episode/512ojhOuo1ktJprKbVcKyQ", Target{Episode, "512ojhOuo1ktJprKbVcKyQ"}},
+		{"https://open.spotify.com/embed/playlist/37i9dQZF1DXcBWIGoYBM5M", Target{Playlist, "37i9dQZF1DXcBWIGoYBM5M"}},
+		{"https://open.spotify.com/playlist/37i9dQZF1DXcBWIGoYBM5M?si=abc123&pt=x", Target{Playlist, "37i9dQZF1DXcBWIGoYBM5M"}},
+		{"https://open.spotify.com/track/4uLU6hMCjMI75M1A2tKUQC#fragment", Target{Track, "4uLU6hMCjMI75M1A2tKUQC"}},
+		{"  https://open.spotify.com/audiobook/7iHfbu1YPACw6oZPAFJtqe\n", Target{Audiobook, "7iHfbu1YPACw6oZPAFJtqe"}},
+		{"spotify:track:4uLU6hMCjMI75M1A2tKUQC", Target{Track, "4uLU6hMCjMI75M1A2tKUQC"}},
+		{"spotify:chapter:0D5wENdkdwbqlrHoaJ9g29", Target{Chapter, "0D5wENdkdwbqlrHoaJ9g29"}},
+		{"spotify:user:spotify:playlist:37i9dQZF1DXcBWIGoYBM5M", Target{Playlist, "37i9dQZF1DXcBWIGoYBM5M"}},
+		{"spotify:user:someone", Target{User, "someone"}},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.in)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", test.in, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Parse(%q) = %v, want %v", test.in, got, test.want)
+		}
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, in := range []string{
+		"",
+		// bare ids are not links, callers look them up themselves
+		"4uLU6hMCjMI75M1A2tKUQC",
+		"some playlist name",
+		"spotify:",
+		"spotify:track",
+		"spotify:track:",
+		"spotify:podcast:abc",
+		"spotify:user:someone:collection:abc",
+		"https://open.spotify.com/",
+		"https://open.spotify.com/track",
+		"https://open.spotify.com/genre/pop",
+		"https://example.com/track/4uLU6hMCjMI75M1A2tKUQC",
+		"https://spotify.link/abc",
+		"ftp://open.evil.com/track/abc",
+	} {
+		if got, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) = %v, want an error", in, got)
+		}
+	}
+}
+
+func TestTarget(t *testing.T) {
+	target := Target{Playlist, "37i9dQZF1DXcBWIGoYBM5M"}
+	if target.URI() != "spotify:playlist:37i9dQZF1DXcBWIGoYBM5M" || target.URL() != "https://open.spotify.com/playlist/37i9dQZF1DXcBWIGoYBM5M" {
+		t.Errorf("uri %s, url %s", target.URI(), target.URL())
+	}
+	if !target.IsContext() || (Target{Track, "x"}).IsContext() {
+		t.Error("IsContext")
+	}
+	if !IsShort("https://spotify.link/abc") || IsShort("https://open.spotify.com/track/abc") {
+		t.Error("IsShort")
+	}
+}
+
+// shortLinks answers for spotify.link with a redirect and for
+// spotify.app.link with a page linking to the track.
+type shortLinks struct{}
+
+func (shortLinks) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Request: req, Body: io.NopCloser(strings.NewReader(""))}
+	switch req.URL.Host {
+	case "spotify.link":
+		resp.StatusCode = http.StatusFound
+		resp.Header.Set("Location", "https://open.spotify.com/track/4uLU6hMCjMI75M1A2tKUQC?si=x")
+	case "spotify.app.link":
+		resp.Body = io.NopCloser(strings.NewReader(`<a href="https://open.spotify.com/album/1DFixLWuPkv3KT3TnV35m3?si=y">open</a>`))
+	case "open.spotify.com":
+		resp.Body = io.NopCloser(strings.NewReader("<html></html>"))
+	}
+	return resp, nil
+}
+
+func TestResolve(t *testing.T) {
+	client := &http.Client{Transport: shortLinks{}}
+	for in, want := range map[string]Target{
+		"https://spotify.link/abc":              {Track, "4uLU6hMCjMI75M1A2tKUQC"},
+		"https://spotify.app.link/def":          {Album, "1DFixLWuPkv3KT3TnV35m3"},
+		"spotify:artist:4Z8W4fKeB5YxbusRsdQVPb": {Artist, "4Z8W4fKeB5YxbusRsdQVPb"},
+	} {
+		got, err := Resolve(context.Background(), client, in)
+		if err != nil {
+			t.Errorf("Resolve(%q) error: %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("Resolve(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
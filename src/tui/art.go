@@ -0,0 +1,257 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	_ "image/jpeg"
+	"image/png"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/zmb3/spotify/v2"
+
+	"git.asdf.cafe/abs3nt/gospt/src/config"
+)
+
+// artProtocol is how album art is drawn.
+type artProtocol string
+
+const (
+	artNone   artProtocol = "none"
+	artBlocks artProtocol = "blocks"
+	artKitty  artProtocol = "kitty"
+	artSixel  artProtocol = "sixel"
+)
+
+const (
+	artCols = 12
+	artRows = 6
+	// artImageID is the id the kitty image is drawn with so it can be
+	// replaced and deleted.
+	artImageID = 4177
+)
+
+type artMsg struct {
+	url string
+	img image.Image
+}
+
+type artDrawMsg string
+
+// artProtocolFor picks the protocol from the now_playing section of the config,
+// or from the terminal.
+func artProtocolFor() (artProtocol, error) {
+	switch strings.ToLower(config.Values.NowPlaying.AlbumArt) {
+	case "", "auto":
+	case "none", "off":
+		return artNone, nil
+	case "blocks":
+		return artBlocks, nil
+	case "kitty":
+		return artKitty, nil
+	case "sixel":
+		return artSixel, nil
+	default:
+		return artNone, fmt.Errorf("unknown album_art %q, use auto, kitty, sixel, blocks or none", config.Values.NowPlaying.AlbumArt)
+	}
+	term, program := os.Getenv("TERM"), os.Getenv("TERM_PROGRAM")
+	switch {
+	case os.Getenv("NO_COLOR") != "":
+		return artNone, nil
+	case os.Getenv("TMUX") != "" || strings.HasPrefix(term, "screen"):
+		// multiplexers do not pass graphics through
+		return artBlocks, nil
+	case os.Getenv("KITTY_WINDOW_ID") != "" || term == "xterm-kitty" || program == "WezTerm" || program == "ghostty":
+		return artKitty, nil
+	case strings.HasPrefix(term, "foot") || strings.HasPrefix(term, "mlterm") || strings.Contains(term, "sixel"):
+		return artSixel, nil
+	}
+	return artBlocks, nil
+}
+
+// artURL is the smallest image of the current album that still fills the
+// panel.
+func artURL(item *spotify.FullTrack) string {
+	if item == nil || len(item.Album.Images) == 0 {
+		return ""
+	}
+	best := item.Album.Images[0]
+	for _, img := range item.Album.Images {
+		if img.Width >= 64 && img.Width < best.Width {
+			best = img
+		}
+	}
+	return best.URL
+}
+
+func fetchArt(url string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return artMsg{url: url}
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return artMsg{url: url}
+		}
+		defer resp.Body.Close()
+		img, _, err := image.Decode(resp.Body)
+		if err != nil {
+			return artMsg{url: url}
+		}
+		return artMsg{url: url, img: img}
+	}
+}
+
+// scale resizes an image by averaging the pixels that fall in each new one.
+func scale(src image.Image, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	b := src.Bounds()
+	for y := 0; y < h; y++ {
+		y0, y1 := b.Min.Y+y*b.Dy()/h, b.Min.Y+(y+1)*b.Dy()/h
+		y1 = max(y1, y0+1)
+		for x := 0; x < w; x++ {
+			x0, x1 := b.Min.X+x*b.Dx()/w, b.Min.X+(x+1)*b.Dx()/w
+			x1 = max(x1, x0+1)
+			var r, g, bl, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, _ := src.At(sx, sy).RGBA()
+					r, g, bl, n = r+pr>>8, g+pg>>8, bl+pb>>8, n+1
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / n), uint8(g / n), uint8(bl / n), 255})
+		}
+	}
+	return dst
+}
+
+func hex(c color.RGBA) lipgloss.Color {
+	return lipgloss.Color(fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B))
+}
+
+// halfBlocks draws the art with two pixels per cell, the top one as the
+// foreground of ▀ and the bottom one as its background.
+func halfBlocks(img image.Image) []string {
+	small := scale(img, artCols, artRows*2)
+	lines := make([]string, artRows)
+	for row := 0; row < artRows; row++ {
+		var b strings.Builder
+		for col := 0; col < artCols; col++ {
+			top, bottom := small.RGBAAt(col, row*2), small.RGBAAt(col, row*2+1)
+			b.WriteString(lipgloss.NewStyle().Foreground(hex(top)).Background(hex(bottom)).Render("▀"))
+		}
+		lines[row] = b.String()
+	}
+	return lines
+}
+
+// kittyImage places the art at the cursor with the kitty graphics protocol,
+// replacing the one drawn before.
+func kittyImage(img image.Image) string {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, scale(img, 160, 160)); err != nil {
+		return ""
+	}
+	data := base64.StdEncoding.EncodeToString(buf.Bytes())
+	var b strings.Builder
+	b.WriteString(kittyDelete())
+	for first := true; len(data) > 0; first = false {
+		chunk := data[:min(4096, len(data))]
+		data = data[len(chunk):]
+		more := 0
+		if len(data) > 0 {
+			more = 1
+		}
+		if first {
+			fmt.Fprintf(&b, "\x1b_Ga=T,q=2,f=100,i=%d,c=%d,r=%d,C=1,m=%d;%s\x1b\\", artImageID, artCols, artRows, more, chunk)
+		} else {
+			fmt.Fprintf(&b, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	return b.String()
+}
+
+func kittyDelete() string {
+	return fmt.Sprintf("\x1b_Ga=d,d=I,q=2,i=%d\x1b\\", artImageID)
+}
+
+// sixelImage encodes the art as sixels, sized to the cells of the panel.
+func sixelImage(img image.Image) string {
+	cw, ch := cellSize()
+	w, h := artCols*cw, artRows*ch
+	pal := color.Palette(palette.WebSafe)
+	quantized := image.NewPaletted(image.Rect(0, 0, w, h), pal)
+	draw.FloydSteinberg.Draw(quantized, quantized.Bounds(), scale(img, w, h), image.Point{})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\x1bP0;1;0q\"1;1;%d;%d", w, h)
+	used := map[uint8]bool{}
+	for _, idx := range quantized.Pix {
+		used[idx] = true
+	}
+	for idx := range used {
+		r, g, bl, _ := pal[idx].RGBA()
+		fmt.Fprintf(&b, "#%d;2;%d;%d;%d", idx, r*100/0xffff, g*100/0xffff, bl*100/0xffff)
+	}
+	row := make([]byte, w)
+	for y0 := 0; y0 < h; y0 += 6 {
+		band := map[uint8]bool{}
+		for y := y0; y < min(y0+6, h); y++ {
+			for x := 0; x < w; x++ {
+				band[quantized.ColorIndexAt(x, y)] = true
+			}
+		}
+		for idx := range band {
+			for x := 0; x < w; x++ {
+				bits := byte(0)
+				for k := 0; k < 6 && y0+k < h; k++ {
+					if quantized.ColorIndexAt(x, y0+k) == idx {
+						bits |= 1 << k
+					}
+				}
+				row[x] = 63 + bits
+			}
+			fmt.Fprintf(&b, "#%d", idx)
+			writeSixelRow(&b, row)
+			b.WriteByte('$')
+		}
+		b.WriteByte('-')
+	}
+	b.WriteString("\x1b\\")
+	return b.String()
+}
+
+// writeSixelRow writes a row of sixels with repeats run length encoded.
+func writeSixelRow(b *strings.Builder, row []byte) {
+	for x := 0; x < len(row); {
+		run := 1
+		for x+run < len(row) && row[x+run] == row[x] {
+			run++
+		}
+		if run > 3 {
+			fmt.Fprintf(b, "!%d%c", run, row[x])
+		} else {
+			b.Write(row[x : x+run])
+		}
+		x += run
+	}
+}
+
+// writeAt writes an image straight to the terminal at the given cell, the
+// cursor is put back where the renderer left it.
+func writeAt(seq string, row, col int) {
+	fmt.Fprintf(os.Stdout, "\x1b7\x1b[%d;%dH%s\x1b8", row+1, col+1, seq)
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package tui
+
+// cellSize is the size of a terminal cell in pixels.
+func cellSize() (int, int) {
+	return 10, 20
+}
@@ -0,0 +1,84 @@
+package tui
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/zmb3/spotify/v2"
+)
+
+// album names the album of a track for the filter.
+func (i mainItem) album() string {
+	switch item := i.SpotifyItem.(type) {
+	case spotify.FullTrack:
+		return item.Album.Name
+	case spotify.SavedTrack:
+		return item.Album.Name
+	case spotify.PlaylistTrack:
+		return item.Track.Album.Name
+	case spotify.PlaylistItem:
+		if item.Track.Track != nil {
+			return item.Track.Track.Album.Name
+		}
+	case spotify.SimpleAlbum:
+		return item.Name
+	}
+	return ""
+}
+
+// FilterValue starts with the title so the list highlights matches in it,
+// the artist and album come after it.
+func (i mainItem) FilterValue() string {
+	return strings.Join([]string{i.Title(), i.Artist.Name, i.album(), i.Desc}, " ")
+}
+
+// refilter runs the filter the list would otherwise run in the background, so
+// the filtered items follow changes to the items right away.
+func (m *mainModel) refilter(cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+	m.list, _ = m.list.Update(cmd())
+}
+
+// syncFilter drops the filter when the view changes and filters again when
+// items were loaded or replaced.
+func (m *mainModel) syncFilter() {
+	if m.mode != m.filterMode {
+		m.filterMode = m.mode
+		m.filtered = 0
+		m.list.ResetFilter()
+		return
+	}
+	if m.list.FilterState() != list.Unfiltered && len(m.list.Items()) != m.filtered {
+		m.filtered = len(m.list.Items())
+		m.refilter(m.list.SetItems(m.list.Items()))
+	}
+}
+
+// loadAll loads the remaining pages so the filter searches all of them, each
+// page asks for the next one when it arrives.
+func (m *mainModel) loadAll() tea.Cmd {
+	if len(m.list.Items())%pageSize != 0 {
+		return nil
+	}
+	m.loadingAll = true
+	return m.loadMore()
+}
+
+// itemIndex is where the selected item is among all items, the list's index
+// is into the filtered items while a filter is applied.
+func (m *mainModel) itemIndex() int {
+	if !m.list.IsFiltered() {
+		return m.list.Index()
+	}
+	selected := m.list.SelectedItem()
+	for index, item := range m.list.Items() {
+		if reflect.DeepEqual(item, selected) {
+			return index
+		}
+	}
+	return -1
+}
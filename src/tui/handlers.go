@@ -1,121 +1,139 @@
 package tui
 
 import (
+	"fmt"
+
 	"github.com/zmb3/spotify/v2"
 
 	"git.asdf.cafe/abs3nt/gospt/src/commands"
 	"git.asdf.cafe/abs3nt/gospt/src/gctx"
 )
 
-func HandlePlayWithContext(ctx *gctx.Context, commands *commands.Commands, uri *spotify.URI, pos *int) {
-	err := commands.PlaySongInPlaylist(ctx, uri, pos)
-	if err != nil {
-		return
-	}
+func HandlePlayWithContext(ctx *gctx.Context, commands *commands.Commands, uri *spotify.URI, pos *int) error {
+	return commands.PlaySongInPlaylist(ctx, uri, pos)
 }
 
-func HandleRadio(ctx *gctx.Context, commands *commands.Commands, song spotify.SimpleTrack) {
-	err := commands.RadioGivenSong(ctx, song, 0)
-	if err != nil {
-		return
-	}
+func HandlePlayInContext(ctx *gctx.Context, commands *commands.Commands, context spotify.URI, track spotify.URI) error {
+	return commands.PlayInContext(ctx, context, track)
 }
 
-func HandleAlbumRadio(ctx *gctx.Context, commands *commands.Commands, album spotify.SimpleAlbum) {
-	err := commands.RadioFromAlbum(ctx, album)
-	if err != nil {
-		return
-	}
+func HandleRadio(ctx *gctx.Context, commands *commands.Commands, song spotify.SimpleTrack) error {
+	return commands.RadioGivenSong(ctx, song, 0)
 }
 
-func HandleSeek(ctx *gctx.Context, commands *commands.Commands, fwd bool) {
-	err := commands.Seek(ctx, fwd)
-	if err != nil {
-		return
-	}
+func HandleRadioGivenList(ctx *gctx.Context, commands *commands.Commands, songs []spotify.ID, name string) error {
+	return commands.RadioGivenList(ctx, songs, name)
 }
 
-func HandleVolume(ctx *gctx.Context, commands *commands.Commands, up bool) {
+func HandleAlbumRadio(ctx *gctx.Context, commands *commands.Commands, album spotify.SimpleAlbum) error {
+	return commands.RadioFromAlbum(ctx, album)
+}
+
+func HandleSeek(ctx *gctx.Context, commands *commands.Commands, fwd bool) error {
+	return commands.Seek(ctx, fwd)
+}
+
+func HandleSeekTo(ctx *gctx.Context, commands *commands.Commands, pos int) error {
+	return commands.SetPosition(ctx, pos)
+}
+
+func HandleVolume(ctx *gctx.Context, commands *commands.Commands, up bool) error {
 	vol := 10
 	if !up {
 		vol = -10
 	}
-	err := commands.ChangeVolume(ctx, vol)
-	if err != nil {
-		return
-	}
+	return commands.ChangeVolume(ctx, vol)
 }
 
-func HandleArtistRadio(ctx *gctx.Context, commands *commands.Commands, artist spotify.SimpleArtist) {
-	err := commands.RadioGivenArtist(ctx, artist)
-	if err != nil {
-		return
-	}
+func HandleArtistRadio(ctx *gctx.Context, commands *commands.Commands, artist spotify.SimpleArtist) error {
+	return commands.RadioGivenArtist(ctx, artist)
 }
 
-func HandleAlbumArtist(ctx *gctx.Context, commands *commands.Commands, artist spotify.SimpleArtist) {
-	err := commands.RadioGivenArtist(ctx, artist)
-	if err != nil {
-		return
-	}
+func HandleArtistSectionRadio(ctx *gctx.Context, commands *commands.Commands, section artistSection) error {
+	name := section.Artist.Name + " " + section.Name
+	switch section.Kind {
+	case topTracksSection:
+		tracks, err := commands.ArtistTopTracks(ctx, section.Artist.ID)
+		if err != nil {
+			return err
+		}
+		if len(tracks) == 0 {
+			return fmt.Errorf("%s has no top tracks", section.Artist.Name)
+		}
+		seeds := []spotify.ID{}
+		for _, track := range tracks[:min(5, len(tracks))] {
+			seeds = append(seeds, track.ID)
+		}
+		return commands.RadioGivenList(ctx, seeds, name)
+	case relatedSection:
+		related, err := commands.RelatedArtists(ctx, section.Artist.ID)
+		if err != nil {
+			return err
+		}
+		artists := []spotify.SimpleArtist{}
+		for _, artist := range related {
+			artists = append(artists, artist.SimpleArtist)
+		}
+		return commands.RadioFromArtists(ctx, artists, name)
+	case albumSection:
+		albums, err := commands.ArtistAlbumsOfType(ctx, section.Artist.ID, section.AlbumType, 1)
+		if err != nil {
+			return err
+		}
+		return commands.RadioFromAlbums(ctx, albums.Albums, name)
+	}
+	return nil
 }
 
-func HandlePlaylistRadio(ctx *gctx.Context, commands *commands.Commands, playlist spotify.SimplePlaylist) {
-	err := commands.RadioFromPlaylist(ctx, playlist)
-	if err != nil {
-		return
-	}
+func HandleAlbumArtist(ctx *gctx.Context, commands *commands.Commands, artist spotify.SimpleArtist) error {
+	return commands.RadioGivenArtist(ctx, artist)
 }
 
-func HandleLibraryRadio(ctx *gctx.Context, commands *commands.Commands) {
-	err := commands.RadioFromSavedTracks(ctx)
-	if err != nil {
-		return
-	}
+func HandlePlaylistRadio(ctx *gctx.Context, commands *commands.Commands, playlist spotify.SimplePlaylist) error {
+	return commands.RadioFromPlaylist(ctx, playlist)
 }
 
-func HandlePlayLikedSong(ctx *gctx.Context, commands *commands.Commands, position int) {
-	err := commands.PlayLikedSongs(ctx, position)
-	if err != nil {
-		return
-	}
+func HandleLibraryRadio(ctx *gctx.Context, commands *commands.Commands) error {
+	return commands.RadioFromSavedTracks(ctx)
+}
+
+func HandlePlayLikedSong(ctx *gctx.Context, commands *commands.Commands, position int) error {
+	return commands.PlayLikedSongs(ctx, position)
 }
 
-func HandlePlayTrack(ctx *gctx.Context, commands *commands.Commands, track spotify.ID) {
+func HandlePlayTrack(ctx *gctx.Context, commands *commands.Commands, track spotify.ID) error {
 	err := commands.QueueSong(ctx, track)
 	if err != nil {
-		return
-	}
-	err = commands.Next(ctx, 1, false)
-	if err != nil {
-		return
+		return err
 	}
+	return commands.Next(ctx, 1, false)
 }
 
-func HandleNextInQueue(ctx *gctx.Context, commands *commands.Commands, amt int) {
-	err := commands.Next(ctx, amt, true)
-	if err != nil {
-		return
-	}
+func HandleNextInQueue(ctx *gctx.Context, commands *commands.Commands, amt int) error {
+	return commands.Next(ctx, amt, true)
 }
 
-func HandleQueueItem(ctx *gctx.Context, commands *commands.Commands, item spotify.ID) {
-	err := commands.QueueSong(ctx, item)
-	if err != nil {
-		return
-	}
+func HandleQueueCollection(ctx *gctx.Context, c *commands.Commands, kind, query string, pos commands.QueuePosition) error {
+	_, err := c.QueueCollection(ctx, kind, query, pos)
+	return err
 }
 
-func HandleDeleteTrackFromPlaylist(ctx *gctx.Context, commands *commands.Commands, item, playlist spotify.ID) {
-	err := commands.DeleteTracksFromPlaylist(ctx, []spotify.ID{item}, playlist)
-	if err != nil {
-		return
-	}
+func HandleDeleteTracksFromPlaylist(ctx *gctx.Context, commands *commands.Commands, items []spotify.ID, playlist spotify.ID) error {
+	return commands.DeleteTracksFromPlaylist(ctx, items, playlist)
 }
 
-func HandleSetDevice(ctx *gctx.Context, commands *commands.Commands, player spotify.PlayerDevice) {
-	err := commands.SetDevice(ctx, player)
-	if err != nil {
-		return
-	}
+func HandleAddTracksToPlaylist(ctx *gctx.Context, commands *commands.Commands, playlist spotify.ID, items []spotify.ID) error {
+	return commands.AddTracksToPlaylist(ctx, playlist, items)
+}
+
+func HandleSetDevice(ctx *gctx.Context, commands *commands.Commands, player spotify.PlayerDevice) error {
+	return commands.SetDevice(ctx, player)
+}
+
+func HandleResumeEpisode(ctx *gctx.Context, commands *commands.Commands, episode spotify.EpisodePage) error {
+	return commands.ResumeEpisode(ctx, &episode)
+}
+
+func HandleResumeChapter(ctx *gctx.Context, c *commands.Commands, chapter commands.Chapter, book spotify.URI) error {
+	return c.ResumeChapter(ctx, chapter, book)
 }
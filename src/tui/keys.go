@@ -0,0 +1,314 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"git.asdf.cafe/abs3nt/gospt/src/config"
+)
+
+// action names a key binding in the keys section of the config.
+type action string
+
+const (
+	actionQuit          action = "quit"
+	actionBack          action = "back"
+	actionSelect        action = "select"
+	actionSearch        action = "search"
+	actionFilter        action = "filter"
+	actionSort          action = "sort"
+	actionSortReverse   action = "sort_reverse"
+	actionPinSearch     action = "pin_search"
+	actionRunPinned     action = "run_pinned"
+	actionDevices       action = "devices"
+	actionCopy          action = "copy"
+	actionSeekForward   action = "seek_forward"
+	actionSeekBackward  action = "seek_backward"
+	actionVolumeUp      action = "volume_up"
+	actionVolumeDown    action = "volume_down"
+	actionRadio         action = "radio"
+	actionQueueLast     action = "queue_last"
+	actionQueueNext     action = "queue_next"
+	actionQueueRemove   action = "queue_remove"
+	actionQueueUp       action = "queue_up"
+	actionQueueDown     action = "queue_down"
+	actionDelete        action = "delete_from_playlist"
+	actionMarkPlayed    action = "mark_played"
+	actionLike          action = "like"
+	actionFollow        action = "follow"
+	actionSaveAlbum     action = "save_album"
+	actionVisual        action = "visual"
+	actionMarkUp        action = "mark_up"
+	actionMarkDown      action = "mark_down"
+	actionMarkAll       action = "mark_all"
+	actionInvertMarks   action = "invert_marks"
+	actionAddToPlaylist action = "add_to_playlist"
+	actionFocusNext     action = "focus_next"
+	actionFocusPrev     action = "focus_prev"
+	actionSidePane      action = "side_pane"
+	actionUp            action = "up"
+	actionDown          action = "down"
+	actionNextPage      action = "next_page"
+	actionPrevPage      action = "prev_page"
+	actionTop           action = "top"
+	actionBottom        action = "bottom"
+	actionHelp          action = "help"
+)
+
+// actions are listed in the order of the help with their default keys.
+var actions = []struct {
+	name action
+	help string
+	keys []string
+}{
+	{actionBack, "back", []string{"q", "esc", "backspace"}},
+	{actionSelect, "select", []string{"enter", " ", "p"}},
+	{actionSearch, "search", []string{"/", "s"}},
+	{actionFilter, "filter loaded items", []string{"f"}},
+	{actionSort, "sort by next field", []string{"o"}},
+	{actionSortReverse, "reverse sort", []string{"O"}},
+	{actionRadio, "start radio", []string{"ctrl+r"}},
+	{actionQueueLast, "queue last", []string{"ctrl+p", "ctrl+@"}},
+	{actionQueueNext, "queue next", []string{"ctrl+n"}},
+	{actionDevices, "select device", []string{"d"}},
+	{actionSeekForward, "seek forward", []string{">"}},
+	{actionSeekBackward, "seek backward", []string{"<"}},
+	{actionVolumeUp, "volume up", []string{"+"}},
+	{actionVolumeDown, "volume down", []string{"-"}},
+	{actionCopy, "copy link to item", []string{"c"}},
+	{actionLike, "like", []string{"L"}},
+	{actionSaveAlbum, "save album", []string{"S"}},
+	{actionFollow, "follow artist", []string{"F"}},
+	{actionMarkPlayed, "mark episode played", []string{"m"}},
+	{actionDelete, "delete from playlist", []string{"ctrl+d"}},
+	{actionQueueRemove, "remove from gospt queue", []string{"x"}},
+	{actionQueueUp, "move up in gospt queue", []string{"K"}},
+	{actionQueueDown, "move down in gospt queue", []string{"J"}},
+	{actionVisual, "visual mode, select marks", []string{"v"}},
+	{actionMarkUp, "mark up", []string{"shift+up"}},
+	{actionMarkDown, "mark down", []string{"shift+down"}},
+	{actionMarkAll, "mark all", []string{"ctrl+a"}},
+	{actionInvertMarks, "invert marks", []string{"*"}},
+	{actionAddToPlaylist, "add to playlist", []string{"a"}},
+	{actionFocusNext, "next pane", []string{"tab"}},
+	{actionFocusPrev, "previous pane", []string{"shift+tab"}},
+	{actionSidePane, "queue or lyrics pane", []string{"y"}},
+	{actionPinSearch, "pin search", []string{"ctrl+s"}},
+	{actionRunPinned, "run pinned search", []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"}},
+	{actionQuit, "quit", []string{"ctrl+c"}},
+	{actionUp, "up", []string{"up", "k"}},
+	{actionDown, "down", []string{"down", "j"}},
+	{actionNextPage, "next page", []string{"right", "l", "pgdown"}},
+	{actionPrevPage, "prev page", []string{"left", "h", "pgup"}},
+	{actionTop, "go to start", []string{"home", "g"}},
+	{actionBottom, "go to end", []string{"end", "G"}},
+	{actionHelp, "more", []string{"?"}},
+}
+
+// shortHelp are the actions shown below the list, the rest is in the full
+// help.
+var shortHelp = []action{actionBack, actionSelect, actionSearch, actionFilter, actionRadio, actionQueueLast, actionDevices}
+
+// presets change the default keys of some actions.
+var presets = map[string]map[action][]string{
+	"default": {},
+	"vim": {
+		actionBack:     {"h", "q", "esc", "backspace"},
+		actionSelect:   {"l", "enter", " "},
+		actionNextPage: {"ctrl+f", "right", "pgdown"},
+		actionPrevPage: {"ctrl+b", "left", "pgup"},
+		actionTop:      {"g", "home"},
+		actionBottom:   {"G", "end"},
+	},
+	"emacs": {
+		actionBack:      {"ctrl+g", "esc", "backspace"},
+		actionSelect:    {"enter"},
+		actionSearch:    {"ctrl+s"},
+		actionPinSearch: {"alt+s"},
+		actionUp:        {"ctrl+p", "up"},
+		actionDown:      {"ctrl+n", "down"},
+		actionNextPage:  {"ctrl+v", "pgdown"},
+		actionPrevPage:  {"alt+v", "pgup"},
+		actionTop:       {"alt+<", "home"},
+		actionBottom:    {"alt+>", "end"},
+		actionQueueLast: {"alt+p"},
+		actionQueueNext: {"alt+n"},
+		actionRadio:     {"alt+r"},
+		actionDelete:    {"alt+d"},
+	},
+}
+
+type keyMap map[action]key.Binding
+
+// loadKeyMap builds the key bindings from the preset and the bindings in the
+// keys section of the config. A key bound to two actions is an error.
+func loadKeyMap() (keyMap, error) {
+	cfg := config.Values.Keys
+	preset := strings.ToLower(cfg.Preset)
+	if preset == "" {
+		preset = "default"
+	}
+	overrides, ok := presets[preset]
+	if !ok {
+		return nil, fmt.Errorf("unknown key preset %q, use default, vim or emacs", cfg.Preset)
+	}
+	keys := map[action][]string{}
+	for _, a := range actions {
+		keys[a.name] = a.keys
+	}
+	for name, bound := range overrides {
+		keys[name] = bound
+	}
+	names := []string{}
+	for name := range cfg.Bindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := keys[action(name)]; !ok {
+			return nil, fmt.Errorf("unknown action %q in keys", name)
+		}
+		keys[action(name)] = bindingKeys(cfg.Bindings[name])
+	}
+	owners := map[string]action{}
+	conflicts := []string{}
+	for _, a := range actions {
+		for _, k := range keys[a.name] {
+			if owner, ok := owners[k]; ok {
+				conflicts = append(conflicts, fmt.Sprintf("%s is bound to %s and %s", keyLabel(k), owner, a.name))
+				continue
+			}
+			owners[k] = a.name
+		}
+	}
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("conflicting keys: %s", strings.Join(conflicts, ", "))
+	}
+	km := keyMap{}
+	for _, a := range actions {
+		labels := []string{}
+		for _, k := range keys[a.name] {
+			labels = append(labels, keyLabel(k))
+		}
+		binding := key.NewBinding(key.WithKeys(keys[a.name]...), key.WithHelp(strings.Join(labels, "/"), a.help))
+		if len(keys[a.name]) == 0 {
+			binding.SetEnabled(false)
+		}
+		km[a.name] = binding
+	}
+	return km, nil
+}
+
+// bindingKeys reads the keys of an action from the config, a single key or a
+// list of keys. Space can be written as "space".
+func bindingKeys(value any) []string {
+	raw := []any{value}
+	if list, ok := value.([]any); ok {
+		raw = list
+	}
+	keys := []string{}
+	for _, k := range raw {
+		s, ok := k.(string)
+		if !ok {
+			s = fmt.Sprint(k)
+		}
+		if s == "space" {
+			s = " "
+		}
+		if s == "" {
+			continue
+		}
+		keys = append(keys, s)
+	}
+	return keys
+}
+
+func keyLabel(k string) string {
+	if k == " " {
+		return "space"
+	}
+	return k
+}
+
+func (k keyMap) matches(msg tea.KeyMsg, a action) bool {
+	return key.Matches(msg, k[a])
+}
+
+// navigates tells if a key moves the cursor of a list.
+func (k keyMap) navigates(msg tea.KeyMsg) bool {
+	for _, a := range []action{actionUp, actionDown, actionNextPage, actionPrevPage, actionTop, actionBottom} {
+		if k.matches(msg, a) {
+			return true
+		}
+	}
+	return false
+}
+
+// pinned is the number of the pinned search a key runs, or 0.
+func (k keyMap) pinned(msg tea.KeyMsg) int {
+	for idx, bound := range k[actionRunPinned].Keys() {
+		if msg.String() == bound {
+			return idx + 1
+		}
+	}
+	return 0
+}
+
+// pinnedLabel is the key that runs the n-th pinned search.
+func (k keyMap) pinnedLabel(n int) string {
+	bound := k[actionRunPinned].Keys()
+	if n < 1 || n > len(bound) {
+		return ""
+	}
+	return keyLabel(bound[n-1])
+}
+
+// label is the first key of an action for descriptions.
+func (k keyMap) label(a action) string {
+	bound := k[a].Keys()
+	if len(bound) == 0 {
+		return ""
+	}
+	return keyLabel(bound[0])
+}
+
+func (k keyMap) help(names ...action) []key.Binding {
+	bindings := []key.Binding{}
+	for _, name := range names {
+		bindings = append(bindings, k[name])
+	}
+	return bindings
+}
+
+// fullHelp lists every action except the list's own, which the list shows.
+func (k keyMap) fullHelp() []key.Binding {
+	names := []action{}
+	for _, a := range actions {
+		switch a.name {
+		case actionUp, actionDown, actionNextPage, actionPrevPage, actionTop, actionBottom, actionHelp:
+			continue
+		}
+		names = append(names, a.name)
+	}
+	history := key.NewBinding(key.WithKeys("up", "down"), key.WithHelp("↑/↓", "search history while typing"))
+	return append(k.help(names...), history)
+}
+
+// apply puts the navigation keys in the list's own key map.
+func (k keyMap) apply(l *list.Model) {
+	l.KeyMap.CursorUp = k[actionUp]
+	l.KeyMap.CursorDown = k[actionDown]
+	l.KeyMap.NextPage = k[actionNextPage]
+	l.KeyMap.PrevPage = k[actionPrevPage]
+	l.KeyMap.GoToStart = k[actionTop]
+	l.KeyMap.GoToEnd = k[actionBottom]
+	l.KeyMap.Filter = k[actionFilter]
+	l.KeyMap.ClearFilter = k[actionBack]
+	l.KeyMap.ShowFullHelp = k[actionHelp]
+	l.KeyMap.CloseFullHelp = k[actionHelp]
+}
@@ -0,0 +1,108 @@
+package tui
+
+import (
+	"io"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/zmb3/spotify/v2"
+
+	"git.asdf.cafe/abs3nt/gospt/src/commands"
+	"git.asdf.cafe/abs3nt/gospt/src/spotifyurl"
+)
+
+// likesMsg is which of the looked up tracks are in the library.
+type likesMsg struct {
+	saved map[spotify.ID]bool
+}
+
+// likedMsg is the tracks that were liked or unliked, and the message telling
+// so.
+type likedMsg struct {
+	likesMsg
+	notice string
+}
+
+// heartDelegate draws the tracks in the library with a heart, saved is the
+// model's set of them.
+type heartDelegate struct {
+	list.DefaultDelegate
+	saved map[spotify.ID]bool
+}
+
+// likedItem is a listed track that is in the library.
+type likedItem struct{ mainItem }
+
+func (i likedItem) Title() string { return i.title(true) }
+
+func (d heartDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	if listed, ok := item.(mainItem); ok {
+		if id, ok := trackID(listed); ok && d.saved[id] {
+			item = likedItem{listed}
+		}
+	}
+	d.DefaultDelegate.Render(w, m, index, item)
+}
+
+// lookupLikes checks the library for listed tracks that were not looked up
+// yet, each track is looked up the first time it is listed.
+func (m *mainModel) lookupLikes(items []list.Item) tea.Cmd {
+	ids := []spotify.ID{}
+	for _, item := range items {
+		listed, ok := item.(mainItem)
+		if !ok {
+			continue
+		}
+		id, ok := trackID(listed)
+		if !ok || m.known[id] {
+			continue
+		}
+		m.known[id] = true
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	ctx, c := m.ctx, m.commands
+	return func() tea.Msg {
+		saved, err := c.InLibrary(ctx, spotifyurl.Track, ids)
+		if err != nil {
+			ctx.Debug.Trace().Err(err).Msg("failed to check liked tracks")
+			return nil
+		}
+		return likesMsg{saved: saved}
+	}
+}
+
+func (m *mainModel) setLikes(msg likesMsg) {
+	for id, liked := range msg.saved {
+		m.known[id] = true
+		m.saved[id] = liked
+	}
+}
+
+// trackID is the id of a listed track, other items have none.
+func trackID(item mainItem) (spotify.ID, bool) {
+	var id spotify.ID
+	switch track := item.SpotifyItem.(type) {
+	case spotify.FullTrack:
+		if commands.IsEpisode(&track) || commands.IsChapter(&track) {
+			return "", false
+		}
+		id = track.ID
+	case spotify.SimpleTrack:
+		id = track.ID
+	case spotify.SavedTrack:
+		id = track.ID
+	case spotify.PlaylistTrack:
+		id = track.Track.ID
+	case spotify.PlaylistItem:
+		if track.Track.Track == nil {
+			return "", false
+		}
+		id = track.Track.Track.ID
+	case commands.QueueItem:
+		id = track.ID
+	}
+	return id, id != ""
+}
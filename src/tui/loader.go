@@ -5,40 +5,110 @@ import (
 	"time"
 
 	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/zmb3/spotify/v2"
+
+	"git.asdf.cafe/abs3nt/gospt/src/commands"
+	"git.asdf.cafe/abs3nt/gospt/src/gctx"
 )
 
-func (m *mainModel) LoadMoreItems() {
-	loading = true
-	defer func() {
-		page++
-		loading = false
-	}()
-	switch m.mode {
-	case "artist":
-		albums, err := m.commands.ArtistAlbums(m.ctx, m.artist.ID, (page + 1))
+// pageSize is how many items a full page of most views has, a view with a
+// multiple of it listed may have more.
+const pageSize = 50
+
+// pageRequest is what loading the next page of a view needs, it is copied
+// from the model so the page loads without touching it.
+type pageRequest struct {
+	ctx       *gctx.Context
+	commands  *commands.Commands
+	view      int
+	page      int
+	mode      Mode
+	playlist  spotify.SimplePlaylist
+	artist    spotify.SimpleArtist
+	section   artistSection
+	show      spotify.SimpleShow
+	audiobook commands.SimpleAudiobook
+	query     string
+	// after is the last listed item, for views paged by cursor
+	after spotify.ID
+}
+
+// loadMore loads the next page of the view, it arrives as a pageMsg.
+func (m *mainModel) loadMore() tea.Cmd {
+	if m.waiting || m.loading || m.lastPage {
+		return nil
+	}
+	m.loading = true
+	r := pageRequest{
+		ctx:       m.ctx,
+		commands:  m.commands,
+		view:      m.view,
+		page:      m.page + 1,
+		mode:      m.mode,
+		playlist:  m.playlist,
+		artist:    m.artist,
+		section:   m.section,
+		show:      m.show,
+		audiobook: m.audiobook,
+	}
+	if m.searchResults != nil {
+		r.query = m.searchResults.Query
+	}
+	if items := m.list.Items(); len(items) > 0 {
+		if last, ok := items[len(items)-1].(mainItem); ok {
+			r.after = last.ID
+		}
+	}
+	return func() tea.Msg {
+		items, err := r.load()
+		return pageMsg{view: r.view, items: items, err: err}
+	}
+}
+
+// addPage adds a loaded page to the list, while loading all of the view it
+// asks for the next page.
+func (m *mainModel) addPage(msg pageMsg) tea.Cmd {
+	if msg.view != m.view {
+		return nil
+	}
+	m.loading = false
+	if msg.err != nil {
+		m.loadingAll = false
+		m.ctx.Debug.Trace().Err(msg.err).Msg("failed to load more items")
+		return nil
+	}
+	m.page++
+	m.refilter(m.list.SetItems(append(m.list.Items(), msg.items...)))
+	if len(msg.items) == 0 || len(m.list.Items())%pageSize != 0 {
+		m.lastPage = true
+		m.loadingAll = false
+	}
+	if m.loadingAll {
+		return m.loadMore()
+	}
+	return nil
+}
+
+func (r pageRequest) load() ([]list.Item, error) {
+	items := []list.Item{}
+	switch r.mode {
+	case ArtistSection, SearchArtistSection:
+		if r.section.Kind != albumSection {
+			return items, nil
+		}
+		albums, err := r.commands.ArtistAlbumsOfType(r.ctx, r.artist.ID, r.section.AlbumType, r.page)
 		if err != nil {
-			return
+			return nil, err
 		}
-		items := []list.Item{}
 		for _, album := range albums.Albums {
-			items = append(items, mainItem{
-				Name:        album.Name,
-				ID:          album.ID,
-				Desc:        fmt.Sprintf("%s by %s", album.AlbumType, album.Artists[0].Name),
-				SpotifyItem: album,
-			})
-		}
-		for _, item := range items {
-			m.list.InsertItem(len(m.list.Items())+1, item)
+			items = append(items, artistAlbumItem(album))
 		}
-		main_updates <- m
-		return
-	case "artists":
-		artists, err := m.commands.UserArtists(m.ctx, (page + 1))
+	case Artists:
+		artists, err := r.commands.UserArtists(r.ctx, r.after)
 		if err != nil {
-			return
+			return nil, err
 		}
-		items := []list.Item{}
 		for _, artist := range artists.Artists {
 			items = append(items, mainItem{
 				Name:        artist.Name,
@@ -47,37 +117,11 @@ func (m *mainModel) LoadMoreItems() {
 				SpotifyItem: artist.SimpleArtist,
 			})
 		}
-		for _, item := range items {
-			m.list.InsertItem(len(m.list.Items())+1, item)
-		}
-		main_updates <- m
-		return
-	case "album":
-		tracks, err := m.commands.AlbumTracks(m.ctx, m.album.ID, (page + 1))
-		if err != nil {
-			return
-		}
-		items := []mainItem{}
-		for _, track := range tracks.Tracks {
-			items = append(items, mainItem{
-				Name:     track.Name,
-				Artist:   track.Artists[0],
-				Duration: track.TimeDuration().Round(time.Second).String(),
-				ID:       track.ID,
-				Desc:     track.Artists[0].Name + " - " + track.TimeDuration().Round(time.Second).String(),
-			})
-		}
-		for _, item := range items {
-			m.list.InsertItem(len(m.list.Items())+1, item)
-		}
-		main_updates <- m
-		return
-	case "albums":
-		albums, err := m.commands.UserAlbums(m.ctx, (page + 1))
+	case Albums:
+		albums, err := r.commands.UserAlbums(r.ctx, r.page)
 		if err != nil {
-			return
+			return nil, err
 		}
-		items := []list.Item{}
 		for _, album := range albums.Albums {
 			items = append(items, mainItem{
 				Name:        album.Name,
@@ -86,17 +130,11 @@ func (m *mainModel) LoadMoreItems() {
 				SpotifyItem: album.SimpleAlbum,
 			})
 		}
-		for _, item := range items {
-			m.list.InsertItem(len(m.list.Items())+1, item)
-		}
-		main_updates <- m
-		return
-	case "main":
-		playlists, err := m.commands.Playlists(m.ctx, (page + 1))
+	case Main:
+		playlists, err := r.commands.Playlists(r.ctx, r.page)
 		if err != nil {
-			return
+			return nil, err
 		}
-		items := []list.Item{}
 		for _, playlist := range playlists.Playlists {
 			items = append(items, mainItem{
 				Name:        playlist.Name,
@@ -104,50 +142,95 @@ func (m *mainModel) LoadMoreItems() {
 				SpotifyItem: playlist,
 			})
 		}
-		for _, item := range items {
-			m.list.InsertItem(len(m.list.Items())+1, item)
-		}
-		main_updates <- m
-		return
-	case "playlist":
-		playlistItems, err := m.commands.PlaylistTracks(m.ctx, m.playlist.ID, (page + 1))
+	case Playlist:
+		playlistItems, err := r.commands.PlaylistTracks(r.ctx, r.playlist.ID, r.page)
 		if err != nil {
-			return
+			return nil, err
 		}
-		items := []mainItem{}
 		for _, item := range playlistItems.Items {
-			items = append(items, mainItem{
-				Name:     item.Track.Track.Name,
-				Artist:   item.Track.Track.Artists[0],
-				Duration: item.Track.Track.TimeDuration().Round(time.Second).String(),
-				ID:       item.Track.Track.ID,
-				Desc:     item.Track.Track.Artists[0].Name + " - " + item.Track.Track.TimeDuration().Round(time.Second).String(),
-			})
+			items = append(items, playlistItem(item))
+		}
+	case Shows:
+		shows, err := r.commands.UserShows(r.ctx, r.page)
+		if err != nil {
+			return nil, err
 		}
-		for _, item := range items {
-			m.list.InsertItem(len(m.list.Items())+1, item)
+		for _, show := range shows.Shows {
+			items = append(items, showItem(show))
 		}
-		main_updates <- m
-		return
-	case "tracks":
-		tracks, err := m.commands.TrackList(m.ctx, (page + 1))
+	case Show, SearchShow:
+		episodes, err := r.commands.ShowEpisodes(r.ctx, r.show.ID, r.page)
 		if err != nil {
-			return
+			return nil, err
 		}
-		items := []list.Item{}
-		for _, track := range tracks.Tracks {
-			items = append(items, mainItem{
-				Name:     track.Name,
-				Artist:   track.Artists[0],
-				Duration: track.TimeDuration().Round(time.Second).String(),
-				ID:       track.ID,
-				Desc:     track.Artists[0].Name + " - " + track.TimeDuration().Round(time.Second).String(),
-			})
+		for _, episode := range episodes.Episodes {
+			items = append(items, episodeItem(r.commands, r.show, episode))
 		}
-		for _, item := range items {
-			m.list.InsertItem(len(m.list.Items())+1, item)
+	case Audiobooks:
+		books, _, err := r.commands.UserAudiobooks(r.ctx, r.page)
+		if err != nil {
+			return nil, err
 		}
-		main_updates <- m
-		return
+		for _, book := range books {
+			items = append(items, audiobookItem(book))
+		}
+	case Audiobook:
+		chapters, err := r.commands.AudiobookChapters(r.ctx, r.audiobook.ID, r.page)
+		if err != nil {
+			return nil, err
+		}
+		for _, chapter := range chapters {
+			items = append(items, chapterItem(chapter))
+		}
+	case SearchTracks, SearchAlbums, SearchArtists, SearchPlaylists, SearchShows, SearchEpisodes:
+		return r.search()
+	case Tracks:
+		tracks, err := r.commands.TrackList(r.ctx, r.page)
+		if err != nil {
+			return nil, err
+		}
+		for _, track := range tracks.Tracks {
+			item, _ := markUnplayable(mainItem{
+				Name:        track.Name,
+				Artist:      track.Artists[0],
+				Duration:    track.TimeDuration().Round(time.Second).String(),
+				ID:          track.ID,
+				Desc:        track.Artists[0].Name + " - " + track.TimeDuration().Round(time.Second).String(),
+				SpotifyItem: track,
+			}, track.IsPlayable, false)
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// search loads the next page of the search results being shown.
+func (r pageRequest) search() ([]list.Item, error) {
+	types := map[Mode]spotify.SearchType{
+		SearchTracks:    spotify.SearchTypeTrack,
+		SearchAlbums:    spotify.SearchTypeAlbum,
+		SearchArtists:   spotify.SearchTypeArtist,
+		SearchPlaylists: spotify.SearchTypePlaylist,
+		SearchShows:     spotify.SearchTypeShow,
+		SearchEpisodes:  spotify.SearchTypeEpisode,
+	}
+	result, err := r.commands.Search(r.ctx, r.query, types[r.mode], r.page)
+	if err != nil {
+		return nil, err
+	}
+	switch r.mode {
+	case SearchTracks:
+		return SearchTracksView(r.ctx, r.commands, result.Tracks)
+	case SearchAlbums:
+		return SearchAlbumsView(r.ctx, r.commands, result.Albums)
+	case SearchArtists:
+		return SearchArtistsView(r.ctx, r.commands, result.Artists)
+	case SearchPlaylists:
+		return SearchPlaylistsView(r.ctx, r.commands, result.Playlists)
+	case SearchShows:
+		return SearchShowsView(r.ctx, r.commands, result.Shows)
+	case SearchEpisodes:
+		return SearchEpisodesView(r.ctx, r.commands, result.Episodes)
 	}
+	return nil, nil
 }
@@ -0,0 +1,188 @@
+package tui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/zmb3/spotify/v2"
+
+	"git.asdf.cafe/abs3nt/gospt/src/commands"
+)
+
+// lyricsURL is lrclib, which has lyrics for most tracks and needs no key.
+const lyricsURL = "https://lrclib.net/api/get"
+
+type lyricLine struct {
+	at   time.Duration
+	text string
+}
+
+// lyrics are synced when each line has the time it is sung at.
+type lyrics struct {
+	synced bool
+	lines  []lyricLine
+}
+
+type lyricsMsg struct {
+	id     spotify.ID
+	lyrics *lyrics
+}
+
+// syncedLine is a line of synced lyrics like [01:02.34] text.
+var syncedLine = regexp.MustCompile(`^\[(\d+):(\d+(?:\.\d+)?)\]\s?(.*)$`)
+
+// fetchLyrics looks up the lyrics of a track, synced ones when there are any.
+// A track without lyrics gets none.
+func fetchLyrics(item *spotify.FullTrack) tea.Cmd {
+	id := item.ID
+	query := url.Values{}
+	query.Set("track_name", item.Name)
+	if len(item.Artists) > 0 {
+		query.Set("artist_name", item.Artists[0].Name)
+	}
+	query.Set("album_name", item.Album.Name)
+	query.Set("duration", strconv.Itoa(int(item.Duration)/1000))
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, lyricsURL+"?"+query.Encode(), nil)
+		if err != nil {
+			return lyricsMsg{id: id}
+		}
+		req.Header.Set("User-Agent", "gospt (https://git.asdf.cafe/abs3nt/gospt)")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return lyricsMsg{id: id}
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return lyricsMsg{id: id}
+		}
+		var found struct {
+			Plain  string `json:"plainLyrics"`
+			Synced string `json:"syncedLyrics"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&found); err != nil {
+			return lyricsMsg{id: id}
+		}
+		return lyricsMsg{id: id, lyrics: parseLyrics(found.Plain, found.Synced)}
+	}
+}
+
+func parseLyrics(plain, synced string) *lyrics {
+	if synced != "" {
+		parsed := &lyrics{synced: true}
+		for _, line := range strings.Split(synced, "\n") {
+			match := syncedLine.FindStringSubmatch(strings.TrimSpace(line))
+			if match == nil {
+				continue
+			}
+			minutes, _ := strconv.Atoi(match[1])
+			seconds, _ := strconv.ParseFloat(match[2], 64)
+			at := time.Duration(minutes)*time.Minute + time.Duration(seconds*float64(time.Second))
+			parsed.lines = append(parsed.lines, lyricLine{at: at, text: match[3]})
+		}
+		if len(parsed.lines) > 0 {
+			return parsed
+		}
+	}
+	if plain == "" {
+		return nil
+	}
+	parsed := &lyrics{}
+	for _, line := range strings.Split(plain, "\n") {
+		parsed.lines = append(parsed.lines, lyricLine{text: strings.TrimSpace(line)})
+	}
+	return parsed
+}
+
+// updateLyrics fetches the lyrics of the current track when the lyrics pane
+// shows and the track changed.
+func (m *mainModel) updateLyrics() tea.Cmd {
+	if !m.split || m.sidePane != sideLyrics || m.state == nil || m.state.Item == nil {
+		return nil
+	}
+	item := m.state.Item
+	if item.ID == m.lyricsID || isSpoken(item) {
+		return nil
+	}
+	m.lyricsID, m.lyrics, m.lyricsScroll, m.lyricsLoading = item.ID, nil, 0, true
+	return fetchLyrics(item)
+}
+
+func (m *mainModel) setLyrics(msg lyricsMsg) {
+	if msg.id != m.lyricsID {
+		return
+	}
+	m.lyrics, m.lyricsLoading = msg.lyrics, false
+}
+
+// lyricsView shows the lyrics in the side pane, synced lyrics follow the
+// track with the line being sung highlighted and in the middle.
+func (m *mainModel) lyricsView(width, height int) string {
+	title := m.side.Styles.TitleBar.Render(m.side.Styles.Title.Render("Lyrics"))
+	height -= lipgloss.Height(title)
+	// indented like the items of the lists
+	text := lipgloss.NewStyle().Width(width).PaddingLeft(2).Foreground(themeColor(m.theme.text))
+	current := text.Foreground(themeColor(m.theme.selected)).Bold(true)
+	status := text.Foreground(themeColor(m.theme.status))
+	switch {
+	case m.state != nil && m.state.Item != nil && isSpoken(m.state.Item):
+		return title + status.Render("No lyrics for episodes and chapters")
+	case m.lyricsLoading:
+		return title + status.Render("Looking up lyrics…")
+	case m.lyrics == nil:
+		return title + status.Render("No lyrics found")
+	}
+	sung := -1
+	if m.lyrics.synced && m.state != nil {
+		progress := time.Duration(m.state.Progress) * time.Millisecond
+		for i, line := range m.lyrics.lines {
+			if line.at <= progress {
+				sung = i
+			}
+		}
+	}
+	rows := []string{}
+	sungRow := 0
+	for i, line := range m.lyrics.lines {
+		style := text
+		if i == sung {
+			style, sungRow = current, len(rows)
+		}
+		rows = append(rows, strings.Split(style.Render(line.text), "\n")...)
+	}
+	start := m.lyricsScroll
+	if m.lyrics.synced {
+		start = sungRow - height/2
+	}
+	start = max(min(start, len(rows)-height), 0)
+	end := min(start+height, len(rows))
+	return title + "\n" + strings.Join(rows[start:end], "\n")
+}
+
+// scrollLyrics moves plain lyrics up or down a line, synced lyrics follow
+// the track instead.
+func (m *mainModel) scrollLyrics(down bool) {
+	if m.lyrics == nil || m.lyrics.synced {
+		return
+	}
+	if down {
+		m.lyricsScroll = min(m.lyricsScroll+1, len(m.lyrics.lines)-1)
+	} else {
+		m.lyricsScroll = max(m.lyricsScroll-1, 0)
+	}
+}
+
+// isSpoken tells episodes and chapters, which have no lyrics, from tracks.
+func isSpoken(item *spotify.FullTrack) bool {
+	return commands.IsEpisode(item) || commands.IsChapter(item)
+}
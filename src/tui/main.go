@@ -2,6 +2,7 @@ package tui
 
 import (
 	"fmt"
+	"image"
 	"strings"
 	"time"
 
@@ -16,42 +17,49 @@ import (
 
 	"git.asdf.cafe/abs3nt/gospt/src/commands"
 	"git.asdf.cafe/abs3nt/gospt/src/gctx"
+	"git.asdf.cafe/abs3nt/gospt/src/history"
+	"git.asdf.cafe/abs3nt/gospt/src/scrobble"
+	"git.asdf.cafe/abs3nt/gospt/src/spotifyurl"
 )
 
-var (
-	P                *tea.Program
-	DocStyle         = lipgloss.NewStyle().Margin(0, 2).Border(lipgloss.DoubleBorder(), true, true, true, true)
-	currentlyPlaying *spotify.CurrentlyPlaying
-	playbackContext  string
-	main_updates     chan *mainModel
-	page             = 1
-	loading          = false
-	showingMessage   = false
-)
+// DocStyle frames the TUI, InitMain sets it from the theme before the program
+// starts and it is only read after that.
+var DocStyle = lipgloss.NewStyle().Margin(0, 2).Border(lipgloss.DoubleBorder(), true, true, true, true)
 
 type Mode string
 
 const (
-	Album             Mode = "album"
-	ArtistAlbum       Mode = "artistalbum"
-	Artist            Mode = "artist"
-	Artists           Mode = "artists"
-	Queue             Mode = "queue"
-	Tracks            Mode = "tracks"
-	Albums            Mode = "albums"
-	Main              Mode = "main"
-	Playlists         Mode = "playlists"
-	Playlist          Mode = "playlist"
-	Devices           Mode = "devices"
-	Search            Mode = "search"
-	SearchAlbums      Mode = "searchalbums"
-	SearchAlbum       Mode = "searchalbum"
-	SearchArtists     Mode = "searchartists"
-	SearchArtist      Mode = "searchartist"
-	SearchArtistAlbum Mode = "searchartistalbum"
-	SearchTracks      Mode = "searchtracks"
-	SearchPlaylists   Mode = "searchplaylsits"
-	SearchPlaylist    Mode = "searchplaylist"
+	Album               Mode = "album"
+	ArtistAlbum         Mode = "artistalbum"
+	ArtistSection       Mode = "artistsection"
+	Artist              Mode = "artist"
+	Artists             Mode = "artists"
+	Shows               Mode = "shows"
+	Show                Mode = "show"
+	Audiobooks          Mode = "audiobooks"
+	Audiobook           Mode = "audiobook"
+	Queue               Mode = "queue"
+	Tracks              Mode = "tracks"
+	Albums              Mode = "albums"
+	Main                Mode = "main"
+	Playlists           Mode = "playlists"
+	Playlist            Mode = "playlist"
+	Devices             Mode = "devices"
+	Search              Mode = "search"
+	SearchAlbums        Mode = "searchalbums"
+	SearchAlbum         Mode = "searchalbum"
+	SearchArtists       Mode = "searchartists"
+	SearchArtist        Mode = "searchartist"
+	SearchArtistAlbum   Mode = "searchartistalbum"
+	SearchArtistSection Mode = "searchartistsection"
+	SearchTracks        Mode = "searchtracks"
+	SearchPlaylists     Mode = "searchplaylsits"
+	SearchPlaylist      Mode = "searchplaylist"
+	SearchShows         Mode = "searchshows"
+	SearchShow          Mode = "searchshow"
+	SearchEpisodes      Mode = "searchepisodes"
+	RecentSearches      Mode = "recentsearches"
+	AddToPlaylist       Mode = "addtoplaylist"
 )
 
 type mainItem struct {
@@ -61,18 +69,36 @@ type mainItem struct {
 	ID          spotify.ID
 	Desc        string
 	SpotifyItem any
+	Marked      bool
+	// Order is the 1-based position in spotify's order once the list is sorted
+	Order int
 }
 
 type SearchResults struct {
+	Query     string
 	Tracks    *spotify.FullTrackPage
 	Artists   *spotify.FullArtistPage
 	Playlists *spotify.SimplePlaylistPage
 	Albums    *spotify.SimpleAlbumPage
+	Shows     *spotify.SimpleShowPage
+	Episodes  *spotify.SimpleEpisodePage
 }
 
-func (i mainItem) Title() string       { return i.Name }
 func (i mainItem) Description() string { return i.Desc }
-func (i mainItem) FilterValue() string { return i.Title() + i.Desc }
+
+func (i mainItem) Title() string { return i.title(false) }
+
+// title marks tracks in the library with a heart and marked items with a dot.
+func (i mainItem) title(liked bool) string {
+	title := i.Name
+	if liked {
+		title = "♥ " + title
+	}
+	if i.Marked {
+		title = "● " + title
+	}
+	return title
+}
 
 type mainModel struct {
 	list            list.Model
@@ -82,127 +108,153 @@ type mainModel struct {
 	mode            Mode
 	playlist        spotify.SimplePlaylist
 	artist          spotify.SimpleArtist
+	section         artistSection
 	album           spotify.SimpleAlbum
+	show            spotify.SimpleShow
+	audiobook       commands.SimpleAudiobook
 	searchResults   *SearchResults
 	progress        progress.Model
 	playing         *spotify.CurrentlyPlaying
 	playbackContext string
+	contextURI      spotify.URI
 	search          string
+	searches        *searchStore
+	sorts           *sortStore
+	keys            keyMap
+	historyIndex    int
+	draft           string
+	scrobbler       *scrobble.Scrobbler
+	history         *history.Recorder
+	feeder          *commands.QueueFeeder
+	theme           theme
+	state           *spotify.PlayerState
+	showPanel       bool
+	artProtocol     artProtocol
+	artURL          string
+	artImg          image.Image
+	artBlocks       []string
+	artSeq          string
+	artDrawn        string
+	delegate        list.DefaultDelegate
+	lastClick       time.Time
+	visual          bool
+	pick            *playlistPick
+	filterMode      Mode
+	filtered        int
+	sortMode        Mode
+	sorted          int
+	// view counts the views shown, pages and items loaded for an earlier
+	// view are dropped
+	view int
+	page int
+	// waiting is set while the first items of an opened view are fetched
+	waiting      bool
+	loading      bool
+	loadingAll   bool
+	lastPage     bool
+	messageUntil time.Time
+	pending      []tea.Cmd
+	stack        []viewState
+	crumb        string
+	// saved are the listed tracks in the library, known the ones looked up
+	saved map[spotify.ID]bool
+	known map[spotify.ID]bool
+	// the split layout, the tree and side panes sit next to the list
+	split         bool
+	focus         pane
+	compact       list.DefaultDelegate
+	tree          list.Model
+	library       map[string][]treeItem
+	treeLoading   map[string]bool
+	treeOpen      map[string]bool
+	side          list.Model
+	sidePane      sidePane
+	sideTrack     spotify.ID
+	lyricsID      spotify.ID
+	lyrics        *lyrics
+	lyricsScroll  int
+	lyricsLoading bool
+	lastClickPane pane
+	width         int
+	height        int
+	treeWidth     int
+	contentWidth  int
+	sideWidth     int
+	bodyHeight    int
 }
 
 func (m *mainModel) PlayRadio() {
-	go m.SendMessage("Starting radio for "+m.list.SelectedItem().(mainItem).Title(), 2*time.Second)
-	selectedItem := m.list.SelectedItem().(mainItem).SpotifyItem
-	switch item := selectedItem.(type) {
-	case spotify.SimplePlaylist:
-		go HandlePlaylistRadio(m.ctx, m.commands, item)
+	ctx, c := m.ctx, m.commands
+	if targets := m.targets(); len(targets) > 1 {
+		ids := targetTracks(targets)
+		if len(ids) == 0 {
+			return
+		}
+		// recommendations take at most five seeds
+		ids = ids[:min(5, len(ids))]
+		m.SendMessage("Starting radio for "+targetName(targets), 2*time.Second)
+		name := targets[0].Name
+		m.after(background(func() error {
+			return HandleRadioGivenList(ctx, c, ids, name)
+		}))
+		m.ClearMarks()
 		return
+	}
+	var radio func() error
+	switch item := m.list.SelectedItem().(mainItem).SpotifyItem.(type) {
+	case spotify.SimplePlaylist:
+		radio = func() error { return HandlePlaylistRadio(ctx, c, item) }
 	case *spotify.SavedTrackPage:
-		go HandleLibraryRadio(m.ctx, m.commands)
-		return
+		radio = func() error { return HandleLibraryRadio(ctx, c) }
 	case spotify.SimpleAlbum:
-		go HandleAlbumRadio(m.ctx, m.commands, item)
-		return
+		radio = func() error { return HandleAlbumRadio(ctx, c, item) }
 	case spotify.FullAlbum:
-		go HandleAlbumRadio(m.ctx, m.commands, item.SimpleAlbum)
-		return
+		radio = func() error { return HandleAlbumRadio(ctx, c, item.SimpleAlbum) }
 	case spotify.SimpleArtist:
-		go HandleArtistRadio(m.ctx, m.commands, item)
-		return
+		radio = func() error { return HandleArtistRadio(ctx, c, item) }
+	case artistFollow:
+		radio = func() error { return HandleArtistRadio(ctx, c, item.Artist) }
+	case artistSection:
+		radio = func() error { return HandleArtistSectionRadio(ctx, c, item) }
 	case spotify.FullArtist:
-		go HandleArtistRadio(m.ctx, m.commands, item.SimpleArtist)
-		return
+		radio = func() error { return HandleArtistRadio(ctx, c, item.SimpleArtist) }
 	case spotify.SimpleTrack:
-		go HandleRadio(m.ctx, m.commands, item)
-		return
+		radio = func() error { return HandleRadio(ctx, c, item) }
 	case spotify.FullTrack:
-		go HandleRadio(m.ctx, m.commands, item.SimpleTrack)
-		return
+		radio = func() error { return HandleRadio(ctx, c, item.SimpleTrack) }
 	case spotify.PlaylistTrack:
-		go HandleRadio(m.ctx, m.commands, item.Track.SimpleTrack)
-		return
+		radio = func() error { return HandleRadio(ctx, c, item.Track.SimpleTrack) }
 	case spotify.PlaylistItem:
-		go HandleRadio(m.ctx, m.commands, item.Track.Track.SimpleTrack)
-		return
+		if item.Track.Track != nil {
+			radio = func() error { return HandleRadio(ctx, c, item.Track.Track.SimpleTrack) }
+		}
 	case spotify.SavedTrack:
-		go HandleRadio(m.ctx, m.commands, item.SimpleTrack)
-		return
+		radio = func() error { return HandleRadio(ctx, c, item.SimpleTrack) }
+	}
+	m.SendMessage("Starting radio for "+m.list.SelectedItem().(mainItem).Title(), 2*time.Second)
+	if radio != nil {
+		m.after(background(radio))
 	}
 }
 
-func (m *mainModel) GoBack() (tea.Cmd, error) {
-	page = 1
-	switch m.mode {
-	case Main:
-		return tea.Quit, nil
-	case Albums, Artists, Tracks, Playlist, Devices, Search, Queue:
-		m.mode = Main
-		new_items, err := MainView(m.ctx, m.commands)
-		if err != nil {
-			return nil, err
-		}
-		m.list.SetItems(new_items)
-	case Album:
-		m.mode = Albums
-		new_items, err := AlbumsView(m.ctx, m.commands)
-		if err != nil {
-			return nil, err
-		}
-		m.list.SetItems(new_items)
-	case Artist:
-		m.mode = Artists
-		new_items, err := ArtistsView(m.ctx, m.commands)
-		if err != nil {
-			return nil, err
-		}
-		m.list.SetItems(new_items)
-	case ArtistAlbum:
-		m.mode = Artist
-		new_items, err := ArtistAlbumsView(m.ctx, m.artist.ID, m.commands)
-		if err != nil {
-			return nil, err
-		}
-		m.list.SetItems(new_items)
-	case SearchArtists, SearchTracks, SearchAlbums, SearchPlaylists:
-		m.mode = Search
-		items, result, err := SearchView(m.ctx, m.commands, m.search)
-		if err != nil {
-			return nil, err
-		}
-		m.searchResults = result
-		m.list.SetItems(items)
-	case SearchArtist:
-		m.mode = SearchArtists
-		new_items, err := SearchArtistsView(m.ctx, m.commands, m.searchResults.Artists)
-		if err != nil {
-			return nil, err
-		}
-		m.list.SetItems(new_items)
-	case SearchArtistAlbum:
-		m.mode = SearchArtist
-		new_items, err := ArtistAlbumsView(m.ctx, m.artist.ID, m.commands)
-		if err != nil {
-			return nil, err
-		}
-		m.list.SetItems(new_items)
-	case SearchAlbum:
-		m.mode = SearchAlbums
-		new_items, err := SearchAlbumsView(m.ctx, m.commands, m.searchResults.Albums)
-		if err != nil {
-			return nil, err
-		}
-		m.list.SetItems(new_items)
-	case SearchPlaylist:
-		m.mode = SearchPlaylists
-		new_items, err := SearchPlaylistsView(m.ctx, m.commands, m.searchResults.Playlists)
-		if err != nil {
-			return nil, err
-		}
-		m.list.SetItems(new_items)
-	default:
-		page = 0
+func (m *mainModel) GoBack() tea.Cmd {
+	if m.pick != nil {
+		m.cancelPick()
+		return nil
+	}
+	if m.visual || m.markedCount() > 0 {
+		m.ClearMarks()
+		return nil
+	}
+	if m.list.IsFiltered() {
+		m.list.ResetFilter()
+		return nil
+	}
+	if m.mode == Main && len(m.stack) == 0 {
+		return tea.Quit
 	}
-	return nil, nil
+	m.back()
+	return nil
 }
 
 type SpotifyUrl struct {
@@ -213,356 +265,691 @@ func (m *mainModel) CopyToClipboard() error {
 	item := m.list.SelectedItem().(mainItem).SpotifyItem
 	switch converted := item.(type) {
 	case spotify.SimplePlaylist:
-		go m.SendMessage("Copying link to "+m.list.SelectedItem().(mainItem).Title(), 2*time.Second)
+		m.SendMessage("Copying link to "+m.list.SelectedItem().(mainItem).Title(), 2*time.Second)
 		clipboard.WriteAll(converted.ExternalURLs["spotify"])
 	case *spotify.FullPlaylist:
-		go m.SendMessage("Copying link to "+m.list.SelectedItem().(mainItem).Title(), 2*time.Second)
+		m.SendMessage("Copying link to "+m.list.SelectedItem().(mainItem).Title(), 2*time.Second)
 		clipboard.WriteAll(converted.ExternalURLs["spotify"])
 	case spotify.SimpleAlbum:
-		go m.SendMessage("Copying link to "+m.list.SelectedItem().(mainItem).Title(), 2*time.Second)
+		m.SendMessage("Copying link to "+m.list.SelectedItem().(mainItem).Title(), 2*time.Second)
 		clipboard.WriteAll(converted.ExternalURLs["spotify"])
 	case *spotify.FullAlbum:
-		go m.SendMessage("Copying link to "+m.list.SelectedItem().(mainItem).Title(), 2*time.Second)
+		m.SendMessage("Copying link to "+m.list.SelectedItem().(mainItem).Title(), 2*time.Second)
 		clipboard.WriteAll(converted.ExternalURLs["spotify"])
 	case spotify.SimpleArtist:
-		go m.SendMessage("Copying link to "+m.list.SelectedItem().(mainItem).Title(), 2*time.Second)
+		m.SendMessage("Copying link to "+m.list.SelectedItem().(mainItem).Title(), 2*time.Second)
 		clipboard.WriteAll(converted.ExternalURLs["spotify"])
 	case *spotify.FullArtist:
-		go m.SendMessage("Copying link to "+m.list.SelectedItem().(mainItem).Title(), 2*time.Second)
+		m.SendMessage("Copying link to "+m.list.SelectedItem().(mainItem).Title(), 2*time.Second)
 		clipboard.WriteAll(converted.ExternalURLs["spotify"])
 	case spotify.SimpleTrack:
-		go m.SendMessage("Copying link to "+m.list.SelectedItem().(mainItem).Title(), 2*time.Second)
+		m.SendMessage("Copying link to "+m.list.SelectedItem().(mainItem).Title(), 2*time.Second)
 		clipboard.WriteAll(converted.ExternalURLs["spotify"])
 	case spotify.PlaylistTrack:
-		go m.SendMessage("Copying link to "+m.list.SelectedItem().(mainItem).Title(), 2*time.Second)
+		m.SendMessage("Copying link to "+m.list.SelectedItem().(mainItem).Title(), 2*time.Second)
 		clipboard.WriteAll(converted.Track.ExternalURLs["spotify"])
 	case spotify.SavedTrack:
-		go m.SendMessage("Copying link to "+m.list.SelectedItem().(mainItem).Title(), 2*time.Second)
+		m.SendMessage("Copying link to "+m.list.SelectedItem().(mainItem).Title(), 2*time.Second)
 		clipboard.WriteAll(converted.ExternalURLs["spotify"])
 	case spotify.FullTrack:
-		go m.SendMessage("Copying link to "+m.list.SelectedItem().(mainItem).Title(), 2*time.Second)
+		m.SendMessage("Copying link to "+m.list.SelectedItem().(mainItem).Title(), 2*time.Second)
 		clipboard.WriteAll(converted.ExternalURLs["spotify"])
 	}
 	return nil
 }
 
+// SendMessage shows a message below the list for a while, the now playing
+// status waits until it is gone.
 func (m *mainModel) SendMessage(msg string, duration time.Duration) {
-	showingMessage = true
-	defer func() {
-		showingMessage = false
-	}()
-	m.list.NewStatusMessage(msg)
-	time.Sleep(duration)
+	m.messageUntil = time.Now().Add(duration)
+	m.list.StatusMessageLifetime = duration
+	m.after(m.list.NewStatusMessage(msg))
 }
 
-func (m *mainModel) QueueItem() error {
-	switch item := m.list.SelectedItem().(mainItem).SpotifyItem.(type) {
-	case spotify.PlaylistTrack:
-		go m.SendMessage("Adding "+item.Track.Name+" to queue", 2*time.Second)
-		go HandleQueueItem(m.ctx, m.commands, item.Track.ID)
-	case spotify.SavedTrack:
-		go m.SendMessage("Adding "+item.Name+" to queue", 2*time.Second)
-		go HandleQueueItem(m.ctx, m.commands, item.ID)
-	case spotify.SimpleTrack:
-		go m.SendMessage("Adding "+item.Name+" to queue", 2*time.Second)
-		go HandleQueueItem(m.ctx, m.commands, item.ID)
-	case spotify.FullTrack:
-		go m.SendMessage("Adding "+item.Name+" to queue", 2*time.Second)
-		go HandleQueueItem(m.ctx, m.commands, item.ID)
-	case *spotify.FullTrack:
-		go m.SendMessage("Adding "+item.Name+" to queue", 2*time.Second)
-		go HandleQueueItem(m.ctx, m.commands, item.ID)
-	case *spotify.SimpleTrack:
-		go m.SendMessage("Adding "+item.Name+" to queue", 2*time.Second)
-		go HandleQueueItem(m.ctx, m.commands, item.ID)
-	case *spotify.SimplePlaylist:
-		go m.SendMessage("Adding "+item.Name+" to queue", 2*time.Second)
-		go HandleQueueItem(m.ctx, m.commands, item.ID)
-	}
-	if m.mode == Queue {
-		go func() {
-			new_items, err := QueueView(m.ctx, m.commands)
-			if err != nil {
-				return
-			}
-			m.list.SetItems(new_items)
-		}()
+func (m *mainModel) QueueItem(pos commands.QueuePosition) error {
+	targets := m.targets()
+	if len(targets) == 1 {
+		if _, _, _, ok := queueTarget(targets[0]); !ok {
+			return nil
+		}
+	}
+	name := targetName(targets)
+	if len(targets) == 1 {
+		_, _, name, _ = queueTarget(targets[0])
 	}
+	m.SendMessage("Adding "+name+" to queue", 2*time.Second)
+	m.ClearMarks()
+	ctx, c := m.ctx, m.commands
+	if m.mode != Queue {
+		m.after(m.loadSideQueue(func() error {
+			return queueTargets(ctx, c, targets, pos)
+		}))
+		return nil
+	}
+	m.after(m.reload(func() ([]list.Item, error) {
+		if err := queueTargets(ctx, c, targets, pos); err != nil {
+			return nil, err
+		}
+		return QueueView(ctx, c)
+	}, false))
 	return nil
 }
 
+// TogglePlayed marks the selected episode as played or unplayed.
+func (m *mainModel) TogglePlayed() {
+	if m.mode != Show && m.mode != SearchShow && m.mode != SearchEpisodes {
+		return
+	}
+	episode, ok := m.list.SelectedItem().(mainItem).SpotifyItem.(spotify.EpisodePage)
+	if !ok {
+		return
+	}
+	c, view := m.commands, m.view
+	m.after(func() tea.Msg {
+		played := !c.EpisodePlayed(&episode)
+		if err := c.MarkPlayed([]spotify.ID{episode.ID}, played); err != nil {
+			return errMsg{err}
+		}
+		msg := playedMsg{view: view, item: episodeItem(c, episode.Show, episode), notice: "Marked " + episode.Name + " as unplayed"}
+		if played {
+			msg.notice = "Marked " + episode.Name + " as played"
+		}
+		return msg
+	})
+}
+
+// setPlayed shows the new played state of an episode of the view it was
+// marked in.
+func (m *mainModel) setPlayed(msg playedMsg) {
+	m.SendMessage(msg.notice, 2*time.Second)
+	if msg.view != m.view {
+		return
+	}
+	for index, item := range m.list.Items() {
+		if item, ok := item.(mainItem); ok && item.ID == msg.item.ID {
+			m.refilter(m.list.SetItem(index, msg.item))
+		}
+	}
+}
+
+// gosptQueuePosition returns the 1-based position in the gospt queue of the
+// selected item, the gospt queue is listed after the current track.
+func (m *mainModel) gosptQueuePosition() int {
+	return queuePosition(m.list.Items(), m.itemIndex())
+}
+
+// queuePosition is the position in the gospt queue of the listed item at index.
+func queuePosition(items []list.Item, index int) int {
+	if _, ok := items[0].(mainItem).SpotifyItem.(commands.QueueItem); ok {
+		return index + 1
+	}
+	return index
+}
+
+// EditQueue removes or moves the selected track of the gospt queue, the
+// queue is fetched again afterwards. The cursor follows a moved track right
+// away.
+func (m *mainModel) EditQueue(action string) {
+	if m.mode != Queue {
+		return
+	}
+	if _, ok := m.list.SelectedItem().(mainItem).SpotifyItem.(commands.QueueItem); !ok {
+		return
+	}
+	pos := m.gosptQueuePosition()
+	ctx, c := m.ctx, m.commands
+	var edit func() error
+	switch action {
+	case "remove":
+		edit = func() error { return c.RemoveFromQueue([]int{pos}) }
+	case "up":
+		edit = func() error { return c.MoveInQueue(pos, pos-1) }
+		m.list.CursorUp()
+	case "down":
+		edit = func() error { return c.MoveInQueue(pos, pos+1) }
+		m.list.CursorDown()
+	}
+	m.after(m.reload(func() ([]list.Item, error) {
+		if err := edit(); err != nil {
+			return nil, err
+		}
+		return QueueView(ctx, c)
+	}, false))
+}
+
 func (m *mainModel) DeleteTrackFromPlaylist() error {
 	if m.mode != Playlist {
 		return nil
 	}
-	track := m.list.SelectedItem().(mainItem).SpotifyItem.(spotify.PlaylistTrack).Track
-	go m.SendMessage("Deleteing "+track.Name+" from "+m.playlist.Name, 2*time.Second)
-	go func() {
-		HandleDeleteTrackFromPlaylist(m.ctx, m.commands, track.ID, m.playlist.ID)
-		new_items, err := PlaylistView(m.ctx, m.commands, m.playlist)
-		if err != nil {
-			return
+	targets := m.targets()
+	ids := targetTracks(targets)
+	if len(ids) == 0 {
+		return nil
+	}
+	m.SendMessage("Deleting "+targetName(targets)+" from "+m.playlist.Name, 2*time.Second)
+	m.ClearMarks()
+	ctx, c, playlist := m.ctx, m.commands, m.playlist
+	m.newView()
+	m.after(m.reload(func() ([]list.Item, error) {
+		if err := HandleDeleteTracksFromPlaylist(ctx, c, ids, playlist.ID); err != nil {
+			return nil, err
 		}
-		m.list.SetItems(new_items)
-	}()
+		return PlaylistView(ctx, c, playlist)
+	}, false))
 	return nil
 }
 
 func (m *mainModel) SelectItem() error {
+	if m.visual {
+		m.ToggleMark()
+		return nil
+	}
+	ctx, c, keys := m.ctx, m.commands, m.keys
 	switch m.mode {
+	case AddToPlaylist:
+		if playlist, ok := m.list.SelectedItem().(mainItem).SpotifyItem.(spotify.SimplePlaylist); ok {
+			m.addToPlaylist(playlist)
+		}
+		return nil
 	case Queue:
-		page = 1
-		go func() {
-			HandleNextInQueue(m.ctx, m.commands, m.list.Index())
-			new_items, err := QueueView(m.ctx, m.commands)
-			if err != nil {
-				return
+		_, gospt := m.list.SelectedItem().(mainItem).SpotifyItem.(commands.QueueItem)
+		play := queueAction(ctx, c, m.list.Items(), m.itemIndex())
+		m.newView()
+		m.after(m.reload(func() ([]list.Item, error) {
+			if err := play(); err != nil {
+				return nil, err
 			}
-			m.list.SetItems(new_items)
-			m.list.ResetSelected()
-		}()
+			return QueueView(ctx, c)
+		}, !gospt))
 	case Search:
-		page = 1
+		name := m.selectedName()
 		switch item := m.list.SelectedItem().(mainItem).SpotifyItem.(type) {
 		case *spotify.FullArtistPage:
-			m.mode = SearchArtists
-			new_items, err := SearchArtistsView(m.ctx, m.commands, item)
-			if err != nil {
-				return err
-			}
-			m.list.SetItems(new_items)
-			m.list.ResetSelected()
+			m.open(name, SearchArtists, func() ([]list.Item, error) {
+				return SearchArtistsView(ctx, c, item)
+			})
 		case *spotify.SimpleAlbumPage:
-			m.mode = SearchAlbums
-			new_items, err := SearchAlbumsView(m.ctx, m.commands, item)
-			if err != nil {
-				return err
-			}
-			m.list.SetItems(new_items)
-			m.list.ResetSelected()
+			m.open(name, SearchAlbums, func() ([]list.Item, error) {
+				return SearchAlbumsView(ctx, c, item)
+			})
 		case *spotify.SimplePlaylistPage:
-			m.mode = SearchPlaylists
-			new_items, err := SearchPlaylistsView(m.ctx, m.commands, item)
-			if err != nil {
-				return err
-			}
-			m.list.SetItems(new_items)
-			m.list.ResetSelected()
+			m.open(name, SearchPlaylists, func() ([]list.Item, error) {
+				return SearchPlaylistsView(ctx, c, item)
+			})
 		case *spotify.FullTrackPage:
-			m.mode = SearchTracks
-			new_items, err := SearchTracksView(m.ctx, m.commands, item)
-			if err != nil {
-				return err
-			}
-			m.list.SetItems(new_items)
-			m.list.ResetSelected()
+			m.open(name, SearchTracks, func() ([]list.Item, error) {
+				return SearchTracksView(ctx, c, item)
+			})
+		case *spotify.SimpleShowPage:
+			m.open(name, SearchShows, func() ([]list.Item, error) {
+				return SearchShowsView(ctx, c, item)
+			})
+		case *spotify.SimpleEpisodePage:
+			m.open(name, SearchEpisodes, func() ([]list.Item, error) {
+				return SearchEpisodesView(ctx, c, item)
+			})
 		}
 	case SearchArtists:
-		page = 1
-		m.mode = SearchArtist
-		m.artist = m.list.SelectedItem().(mainItem).SpotifyItem.(spotify.SimpleArtist)
-		new_items, err := ArtistAlbumsView(m.ctx, m.artist.ID, m.commands)
-		if err != nil {
-			return err
-		}
-		m.list.SetItems(new_items)
-		m.list.ResetSelected()
-	case SearchArtist:
-		page = 1
-		m.mode = SearchArtistAlbum
-		m.album = m.list.SelectedItem().(mainItem).SpotifyItem.(spotify.SimpleAlbum)
-		new_items, err := AlbumTracksView(m.ctx, m.album.ID, m.commands)
-		if err != nil {
-			return err
-		}
-		m.list.SetItems(new_items)
-		m.list.ResetSelected()
+		m.openArtist(m.list.SelectedItem().(mainItem).SpotifyItem.(spotify.SimpleArtist), SearchArtist)
+	case SearchArtist, SearchArtistSection:
+		m.selectArtistItem(true)
 	case SearchAlbums:
-		page = 1
-		m.mode = SearchAlbum
-		m.album = m.list.SelectedItem().(mainItem).SpotifyItem.(spotify.SimpleAlbum)
-		new_items, err := AlbumTracksView(m.ctx, m.album.ID, m.commands)
-		if err != nil {
-			return err
-		}
-		m.list.SetItems(new_items)
-		m.list.ResetSelected()
+		album := m.list.SelectedItem().(mainItem).SpotifyItem.(spotify.SimpleAlbum)
+		m.open(m.selectedName(), SearchAlbum, func() ([]list.Item, error) {
+			return AlbumView(ctx, album.ID, c, keys)
+		})
+		m.album = album
 	case SearchPlaylists:
-		page = 1
-		m.mode = SearchPlaylist
 		playlist := m.list.SelectedItem().(mainItem).SpotifyItem.(spotify.SimplePlaylist)
+		m.open(m.selectedName(), SearchPlaylist, func() ([]list.Item, error) {
+			return PlaylistView(ctx, c, playlist)
+		})
 		m.playlist = playlist
-		new_items, err := PlaylistView(m.ctx, m.commands, playlist)
-		if err != nil {
-			return err
-		}
-		m.list.SetItems(new_items)
-		m.list.ResetSelected()
+	case RecentSearches:
+		return m.startSearch(string(m.list.SelectedItem().(mainItem).SpotifyItem.(searchQuery)))
 	case Main:
-		page = 1
-		switch item := m.list.SelectedItem().(mainItem).SpotifyItem.(type) {
-		case spotify.Queue:
-			m.mode = Queue
-			new_items, err := QueueView(m.ctx, m.commands)
-			if err != nil {
-				return err
-			}
-			m.list.SetItems(new_items)
+		selected := m.list.SelectedItem().(mainItem).SpotifyItem
+		if query, ok := selected.(searchQuery); ok {
+			return m.startSearch(string(query))
+		}
+		name := m.selectedName()
+		switch item := selected.(type) {
+		case recentSearches:
+			m.push(name)
+			m.mode = RecentSearches
+			m.list.SetItems(RecentSearchesView(m.searches))
 			m.list.ResetSelected()
+		case spotify.Queue:
+			m.open(name, Queue, func() ([]list.Item, error) {
+				return QueueView(ctx, c)
+			})
 		case *spotify.FullArtistCursorPage:
-			m.mode = Artists
-			new_items, err := ArtistsView(m.ctx, m.commands)
-			if err != nil {
-				return err
-			}
-			m.list.SetItems(new_items)
-			m.list.ResetSelected()
+			m.open(name, Artists, func() ([]list.Item, error) {
+				return ArtistsView(ctx, c)
+			})
+		case savedAudiobooks:
+			m.open(name, Audiobooks, func() ([]list.Item, error) {
+				return AudiobooksView(ctx, c)
+			})
+		case *spotify.SavedShowPage:
+			m.open(name, Shows, func() ([]list.Item, error) {
+				return ShowsView(ctx, c)
+			})
 		case *spotify.SavedAlbumPage:
-			m.mode = Albums
-			new_items, err := AlbumsView(m.ctx, m.commands)
-			if err != nil {
-				return err
-			}
-			m.list.SetItems(new_items)
-			m.list.ResetSelected()
+			m.open(name, Albums, func() ([]list.Item, error) {
+				return AlbumsView(ctx, c)
+			})
 		case spotify.SimplePlaylist:
-			m.mode = Playlist
+			m.open(name, Playlist, func() ([]list.Item, error) {
+				return PlaylistView(ctx, c, item)
+			})
 			m.playlist = item
-			new_items, err := PlaylistView(m.ctx, m.commands, item)
-			if err != nil {
-				return err
-			}
-			m.list.SetItems(new_items)
-			m.list.ResetSelected()
 		case *spotify.SavedTrackPage:
-			m.mode = Tracks
-			new_items, err := SavedTracksView(m.ctx, m.commands)
-			if err != nil {
-				return err
-			}
-			m.list.SetItems(new_items)
-			m.list.ResetSelected()
+			m.open(name, Tracks, func() ([]list.Item, error) {
+				return SavedTracksView(ctx, c)
+			})
 		}
 	case Albums:
-		page = 1
-		m.mode = Album
-		m.album = m.list.SelectedItem().(mainItem).SpotifyItem.(spotify.SimpleAlbum)
-		new_items, err := AlbumTracksView(m.ctx, m.album.ID, m.commands)
-		if err != nil {
-			return err
-		}
-		m.list.SetItems(new_items)
-		m.list.ResetSelected()
-	case Artist:
-		m.mode = ArtistAlbum
-		m.album = m.list.SelectedItem().(mainItem).SpotifyItem.(spotify.SimpleAlbum)
-		new_items, err := AlbumTracksView(m.ctx, m.album.ID, m.commands)
-		if err != nil {
-			return err
+		album := m.list.SelectedItem().(mainItem).SpotifyItem.(spotify.SimpleAlbum)
+		m.open(m.selectedName(), Album, func() ([]list.Item, error) {
+			return AlbumView(ctx, album.ID, c, keys)
+		})
+		m.album = album
+	case Artist, ArtistSection:
+		m.selectArtistItem(false)
+	case Shows, SearchShows:
+		mode := Show
+		if m.mode == SearchShows {
+			mode = SearchShow
 		}
-		m.list.SetItems(new_items)
-		m.list.ResetSelected()
+		show := m.list.SelectedItem().(mainItem).SpotifyItem.(spotify.SimpleShow)
+		m.open(m.selectedName(), mode, func() ([]list.Item, error) {
+			return ShowEpisodesView(ctx, c, show)
+		})
+		m.show = show
+	case Show, SearchShow, SearchEpisodes:
+		episode := m.list.SelectedItem().(mainItem).SpotifyItem.(spotify.EpisodePage)
+		m.after(background(func() error {
+			return HandleResumeEpisode(ctx, c, episode)
+		}))
+	case Audiobooks:
+		audiobook := m.list.SelectedItem().(mainItem).SpotifyItem.(commands.SimpleAudiobook)
+		m.open(m.selectedName(), Audiobook, func() ([]list.Item, error) {
+			return ChaptersView(ctx, c, audiobook.ID)
+		})
+		m.audiobook = audiobook
+	case Audiobook:
+		chapter := m.list.SelectedItem().(mainItem).SpotifyItem.(commands.Chapter)
+		book := m.audiobook.URI
+		m.after(background(func() error {
+			return HandleResumeChapter(ctx, c, chapter, book)
+		}))
 	case Artists:
-		m.mode = Artist
-		m.artist = m.list.SelectedItem().(mainItem).SpotifyItem.(spotify.SimpleArtist)
-		new_items, err := ArtistAlbumsView(m.ctx, m.artist.ID, m.commands)
-		if err != nil {
-			return err
-		}
-		m.list.SetItems(new_items)
-		m.list.ResetSelected()
+		m.openArtist(m.list.SelectedItem().(mainItem).SpotifyItem.(spotify.SimpleArtist), Artist)
 	case Album, ArtistAlbum, SearchArtistAlbum, SearchAlbum:
-		pos := m.list.Cursor() + (m.list.Paginator.Page * m.list.Paginator.TotalPages)
-		go HandlePlayWithContext(m.ctx, m.commands, &m.album.URI, &pos)
+		album := m.album.URI
+		switch item := m.list.SelectedItem().(mainItem).SpotifyItem.(type) {
+		case albumSave:
+			m.ToggleSaveAlbum()
+		case spotify.SimpleAlbum:
+			m.after(background(func() error {
+				pos := 0
+				return HandlePlayWithContext(ctx, c, &album, &pos)
+			}))
+		case spotify.SimpleTrack:
+			// tracks are played by uri, unplayable ones may be hidden
+			m.after(background(func() error {
+				return HandlePlayInContext(ctx, c, album, item.URI)
+			}))
+		}
 	case Playlist, SearchPlaylist:
-		pos := m.list.Cursor() + (m.list.Paginator.Page * m.list.Paginator.PerPage)
-		go HandlePlayWithContext(m.ctx, m.commands, &m.playlist.URI, &pos)
+		playlist, pos := m.playlist.URI, m.apiPosition()
+		m.after(background(func() error {
+			return HandlePlayWithContext(ctx, c, &playlist, &pos)
+		}))
 	case Tracks:
-		go HandlePlayLikedSong(m.ctx, m.commands, m.list.Cursor()+(m.list.Paginator.Page*m.list.Paginator.PerPage))
+		pos := m.apiPosition()
+		m.after(background(func() error {
+			return HandlePlayLikedSong(ctx, c, pos)
+		}))
 	case SearchTracks:
-		go HandlePlayTrack(m.ctx, m.commands, m.list.SelectedItem().(mainItem).SpotifyItem.(spotify.FullTrack).ID)
+		track := m.list.SelectedItem().(mainItem).SpotifyItem.(spotify.FullTrack).ID
+		m.after(background(func() error {
+			return HandlePlayTrack(ctx, c, track)
+		}))
 	case Devices:
-		go HandleSetDevice(m.ctx, m.commands, m.list.SelectedItem().(mainItem).SpotifyItem.(spotify.PlayerDevice))
-		go m.SendMessage("Setting device to "+m.list.SelectedItem().FilterValue(), 2*time.Second)
-		m.mode = "main"
-		new_items, err := MainView(m.ctx, m.commands)
-		if err != nil {
-			return err
-		}
-		m.list.SetItems(new_items)
+		device := m.list.SelectedItem().(mainItem).SpotifyItem.(spotify.PlayerDevice)
+		m.after(background(func() error {
+			return HandleSetDevice(ctx, c, device)
+		}))
+		m.SendMessage("Setting device to "+m.list.SelectedItem().(mainItem).Name, 2*time.Second)
+		m.back()
 	}
 	return nil
 }
 
-func (m *mainModel) Init() tea.Cmd {
-	main_updates = make(chan *mainModel)
-	return Tick()
+// openArtist shows the page of an artist, mode is Artist or SearchArtist.
+func (m *mainModel) openArtist(artist spotify.SimpleArtist, mode Mode) {
+	ctx, c, keys := m.ctx, m.commands, m.keys
+	m.open(artist.Name, mode, func() ([]list.Item, error) {
+		return ArtistView(ctx, c, artist, keys)
+	})
+	m.artist = artist
 }
 
-type tickMsg time.Time
+// selectArtistItem opens the selected section of an artist page, or the
+// selected album, track or related artist of a section.
+func (m *mainModel) selectArtistItem(search bool) {
+	artistMode, sectionMode, albumMode := Artist, ArtistSection, ArtistAlbum
+	if search {
+		artistMode, sectionMode, albumMode = SearchArtist, SearchArtistSection, SearchArtistAlbum
+	}
+	ctx, c, keys := m.ctx, m.commands, m.keys
+	switch item := m.list.SelectedItem().(mainItem).SpotifyItem.(type) {
+	case artistFollow:
+		m.ToggleFollow()
+	case artistSection:
+		m.open(item.Name, sectionMode, func() ([]list.Item, error) {
+			return ArtistSectionView(ctx, c, item)
+		})
+		m.section = item
+	case spotify.SimpleAlbum:
+		m.open(item.Name, albumMode, func() ([]list.Item, error) {
+			return AlbumView(ctx, item.ID, c, keys)
+		})
+		m.album = item
+	case spotify.FullArtist:
+		m.openArtist(item.SimpleArtist, artistMode)
+	case spotify.FullTrack:
+		m.after(background(func() error {
+			return HandlePlayTrack(ctx, c, item.ID)
+		}))
+	}
+}
 
-func Tick() tea.Cmd {
-	return tea.Tick(time.Second*1, func(t time.Time) tea.Msg {
-		return tickMsg(t)
-	})
+// ToggleSaveAlbum saves or unsaves the selected album, or the album whose
+// page is open.
+func (m *mainModel) ToggleSaveAlbum() {
+	album := m.album
+	selected, _ := m.list.SelectedItem().(mainItem)
+	switch item := selected.SpotifyItem.(type) {
+	case spotify.SimpleAlbum:
+		album = item
+	case albumSave:
+		album = item.Album
+	default:
+		switch m.mode {
+		case Album, ArtistAlbum, SearchArtistAlbum, SearchAlbum:
+		default:
+			return
+		}
+	}
+	ctx, c, keys := m.ctx, m.commands, m.keys
+	toggle := func() tea.Msg {
+		saved, err := c.AlbumSaved(ctx, album.ID)
+		if err == nil {
+			err = c.SetAlbumSaved(ctx, album.ID, !saved)
+		}
+		if err != nil {
+			return errMsg{err}
+		}
+		if saved {
+			return noticeMsg("Removed " + album.Name + " from library")
+		}
+		return noticeMsg("Saved " + album.Name + " to library")
+	}
+	switch m.mode {
+	case Album, ArtistAlbum, SearchArtistAlbum, SearchAlbum:
+		// the album page shows whether it is saved
+		open := m.album.ID
+		m.after(tea.Sequence(toggle, m.reload(func() ([]list.Item, error) {
+			return AlbumView(ctx, open, c, keys)
+		}, false)))
+	default:
+		m.after(toggle)
+	}
 }
 
-func (m *mainModel) TickPlayback() {
-	playing, _ := m.commands.Client().PlayerCurrentlyPlaying(m.ctx)
-	if playing != nil && playing.Playing && playing.Item != nil {
-		currentlyPlaying = playing
-		playbackContext, _ = m.getContext(playing)
-	}
-	ticker := time.NewTicker(1 * time.Second)
-	quit := make(chan struct{})
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				playing, _ := m.commands.Client().PlayerCurrentlyPlaying(m.ctx)
-				if playing != nil && playing.Playing && playing.Item != nil {
-					currentlyPlaying = playing
-					playbackContext, _ = m.getContext(playing)
+// ToggleLike likes or unlikes the selected track, album, episode or show.
+func (m *mainModel) ToggleLike() {
+	targets := m.targets()
+	ids := map[spotifyurl.Kind][]spotify.ID{}
+	for _, target := range targets {
+		if kind, id, ok := likeTarget(target); ok {
+			ids[kind] = append(ids[kind], id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	m.ClearMarks()
+	ctx, c, name := m.ctx, m.commands, targetName(targets)
+	m.after(func() tea.Msg {
+		// like everything unless all of it is liked already
+		liked := false
+		for kind, kindIDs := range ids {
+			saved, err := c.InLibrary(ctx, kind, kindIDs)
+			if err != nil {
+				return errMsg{err}
+			}
+			for _, id := range kindIDs {
+				liked = liked || !saved[id]
+			}
+		}
+		msg := likedMsg{likesMsg{saved: map[spotify.ID]bool{}}, "Unliked " + name}
+		if liked {
+			msg.notice = "Liked " + name
+		}
+		for kind, kindIDs := range ids {
+			if err := c.SetInLibrary(ctx, kind, kindIDs, liked); err != nil {
+				return errMsg{err}
+			}
+			if kind == spotifyurl.Track {
+				for _, id := range kindIDs {
+					msg.saved[id] = liked
 				}
-			case <-quit:
-				ticker.Stop()
-				return
 			}
 		}
-	}()
+		return msg
+	})
+}
+
+// likeTarget is what liking an item saves to the library.
+func likeTarget(item mainItem) (spotifyurl.Kind, spotify.ID, bool) {
+	if id, ok := trackID(item); ok {
+		return spotifyurl.Track, id, true
+	}
+	switch item := item.SpotifyItem.(type) {
+	case spotify.SimpleAlbum:
+		return spotifyurl.Album, item.ID, true
+	case albumSave:
+		return spotifyurl.Album, item.Album.ID, true
+	case spotify.EpisodePage:
+		return spotifyurl.Episode, item.ID, true
+	case spotify.SimpleShow:
+		return spotifyurl.Show, item.ID, true
+	}
+	return "", "", false
+}
+
+// ToggleFollow follows or unfollows the selected artist, or the artist whose
+// page is open.
+func (m *mainModel) ToggleFollow() {
+	artist := m.artist
+	selected, _ := m.list.SelectedItem().(mainItem)
+	switch item := selected.SpotifyItem.(type) {
+	case spotify.SimpleArtist:
+		artist = item
+	case spotify.FullArtist:
+		artist = item.SimpleArtist
+	default:
+		switch m.mode {
+		case Artist, ArtistSection, ArtistAlbum, SearchArtist, SearchArtistSection, SearchArtistAlbum:
+		default:
+			return
+		}
+	}
+	ctx, c, keys := m.ctx, m.commands, m.keys
+	toggle := func() tea.Msg {
+		following, err := c.ToggleFollowArtist(ctx, artist.ID)
+		if err != nil {
+			return errMsg{err}
+		}
+		if following {
+			return noticeMsg("Followed " + artist.Name)
+		}
+		return noticeMsg("Unfollowed " + artist.Name)
+	}
+	if m.mode == Artist || m.mode == SearchArtist {
+		// the artist page shows whether they are followed
+		open := m.artist
+		m.after(tea.Sequence(toggle, m.reload(func() ([]list.Item, error) {
+			return ArtistView(ctx, c, open, keys)
+		}, false)))
+		return
+	}
+	m.after(toggle)
+}
+
+// Init starts polling the player, the library tree is loaded once the split
+// layout is shown.
+func (m *mainModel) Init() tea.Cmd {
+	return m.pollPlayback(0)
 }
 
 func (m *mainModel) View() string {
+	if m.split {
+		view := m.splitView() + "\n"
+		if m.input.Focused() {
+			view += m.input.View()
+		}
+		if m.showPanel {
+			view += "\n" + m.nowPlayingView()
+		}
+		return lipgloss.NewStyle().Margin(DocStyle.GetMargin()).Render(view)
+	}
+	view := m.list.View() + "\n"
 	if m.input.Focused() {
-		return DocStyle.Render(m.list.View() + "\n" + m.input.View())
+		view += m.input.View()
 	}
-	return DocStyle.Render(m.list.View() + "\n")
+	if m.showPanel {
+		view += "\n" + m.nowPlayingView()
+	}
+	return DocStyle.Render(view)
 }
 
-func (m *mainModel) Typing(msg tea.KeyMsg) (bool, tea.Cmd) {
-	if msg.String() == "enter" {
-		items, result, err := SearchView(m.ctx, m.commands, m.input.Value())
-		if err != nil {
-			return false, tea.Quit
+// startSearch runs a search, shows its results and remembers it,
+// type:track,album picks the types.
+func (m *mainModel) startSearch(input string) error {
+	query, types, err := commands.ParseSearch(input, commands.AllSearchTypes)
+	if err != nil {
+		return err
+	}
+	ctx, c := m.ctx, m.commands
+	m.open("Search: "+input, Search, func() ([]list.Item, error) {
+		items, _, err := SearchView(ctx, c, query, types)
+		return items, err
+	})
+	m.searchResults = &SearchResults{Query: query}
+	m.search = input
+	m.searches.add(input)
+	m.after(background(m.searches.save))
+	return nil
+}
+
+// PinSearch pins or unpins the selected past search, or the current search
+// while looking at its results.
+func (m *mainModel) PinSearch() error {
+	query := m.search
+	selected, _ := m.list.SelectedItem().(mainItem)
+	if item, ok := selected.SpotifyItem.(searchQuery); ok {
+		query = string(item)
+	} else if m.mode == Main || m.mode == RecentSearches || query == "" {
+		return nil
+	}
+	pinned := m.searches.togglePin(query)
+	m.after(background(m.searches.save))
+	if pinned {
+		m.SendMessage("Pinned "+query, 2*time.Second)
+	} else {
+		m.SendMessage("Unpinned "+query, 2*time.Second)
+	}
+	switch m.mode {
+	case Main:
+		ctx, c, keys, searches := m.ctx, m.commands, m.keys, m.searches
+		m.after(m.reload(func() ([]list.Item, error) {
+			return MainView(ctx, c, keys, searches)
+		}, false))
+	case RecentSearches:
+		m.list.SetItems(RecentSearchesView(m.searches))
+	}
+	return nil
+}
+
+// RunPinnedSearch runs the n-th pinned search.
+func (m *mainModel) RunPinnedSearch(n int) error {
+	pinned := m.searches.pinned()
+	if n < 1 || n > len(pinned) {
+		return nil
+	}
+	return m.startSearch(pinned[n-1])
+}
+
+// recallSearch steps through the search history while typing, up goes back
+// in time and down returns to what was being typed.
+func (m *mainModel) recallSearch(older bool) {
+	recent := m.searches.history()
+	if older {
+		if m.historyIndex+1 >= len(recent) {
+			return
+		}
+		if m.historyIndex == -1 {
+			m.draft = m.input.Value()
+		}
+		m.historyIndex++
+		m.input.SetValue(recent[m.historyIndex])
+	} else {
+		if m.historyIndex == -1 {
+			return
+		}
+		m.historyIndex--
+		if m.historyIndex == -1 || m.historyIndex >= len(recent) {
+			m.historyIndex = -1
+			m.input.SetValue(m.draft)
+		} else {
+			m.input.SetValue(recent[m.historyIndex])
+		}
+	}
+	m.input.CursorEnd()
+}
+
+func (m *mainModel) Typing(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "enter":
+		if err := m.startSearch(m.input.Value()); err != nil {
+			m.SendMessage(err.Error(), 2*time.Second)
+			return nil
 		}
-		m.searchResults = result
-		m.search = m.input.Value()
-		m.list.SetItems(items)
-		m.list.ResetSelected()
 		m.input.SetValue("")
 		m.input.Blur()
-		return true, nil
-	}
-	if msg.String() == "esc" {
+		return nil
+	case "esc":
 		m.input.SetValue("")
 		m.input.Blur()
-		return false, nil
+		return nil
+	case "up":
+		m.recallSearch(true)
+		return nil
+	case "down":
+		m.recallSearch(false)
+		return nil
 	}
 	m.input, _ = m.input.Update(msg)
-	return false, nil
+	return nil
 }
 
-func (m *mainModel) getContext(playing *spotify.CurrentlyPlaying) (string, error) {
+// getContext names the album, playlist, artist, show or audiobook playing.
+func getContext(ctx *gctx.Context, c *commands.Commands, playing *spotify.CurrentlyPlaying) (string, error) {
 	context := playing.PlaybackContext
 	uri_split := strings.Split(string(context.URI), ":")
 	if len(uri_split) < 3 {
@@ -571,72 +958,159 @@ func (m *mainModel) getContext(playing *spotify.CurrentlyPlaying) (string, error
 	id := strings.Split(string(context.URI), ":")[2]
 	switch context.Type {
 	case "album":
-		album, err := m.commands.Client().GetAlbum(m.ctx, spotify.ID(id))
+		album, err := c.Client().GetAlbum(ctx, spotify.ID(id))
 		if err != nil {
 			return "", err
 		}
 		return album.Name, nil
 	case "playlist":
-		playlist, err := m.commands.Client().GetPlaylist(m.ctx, spotify.ID(id))
+		playlist, err := c.Client().GetPlaylist(ctx, spotify.ID(id))
 		if err != nil {
 			return "", err
 		}
 		return playlist.Name, nil
 	case "artist":
-		artist, err := m.commands.Client().GetArtist(m.ctx, spotify.ID(id))
+		artist, err := c.Client().GetArtist(ctx, spotify.ID(id))
 		if err != nil {
 			return "", err
 		}
 		return artist.Name, nil
+	case "show":
+		if commands.IsEpisode(playing.Item) && len(playing.Item.Artists) > 0 {
+			return playing.Item.Artists[0].Name, nil
+		}
+	case "audiobook":
+		if commands.IsChapter(playing.Item) {
+			return playing.Item.Album.Name, nil
+		}
 	}
 	return "", nil
 }
 
-func (m *mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	// Update list items from LoadMore
-	select {
-	case update := <-main_updates:
-		m.list.SetItems(update.list.Items())
-	default:
+// playingTitle names the current track and its artist, the current episode
+// and its show or the current chapter and its audiobook.
+func playingTitle(item *spotify.FullTrack) string {
+	switch {
+	case commands.IsChapter(item) && len(item.Artists) > 0:
+		return item.Name + " of " + item.Album.Name + " by " + item.Artists[0].Name
+	case commands.IsChapter(item):
+		return item.Name + " of " + item.Album.Name
+	case len(item.Artists) == 0:
+		return item.Name
+	case commands.IsEpisode(item):
+		return item.Name + " from " + item.Artists[0].Name
 	}
-	// Call for more items if needed
-	if m.list.Paginator.Page == m.list.Paginator.TotalPages-1 && m.list.Cursor() == 0 && !loading {
-		// if last request was still full request more
-		if len(m.list.Items())%50 == 0 {
-			go m.LoadMoreItems()
-		}
+	return item.Name + " by " + item.Artists[0].Name
+}
+
+// Update handles a message, then returns what the methods it called queued
+// and loads the next page once the last one is reached.
+func (m *mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	model, cmd := m.update(msg)
+	cmds := append(m.pending, cmd, m.syncSort())
+	m.pending = nil
+	m.syncFilter()
+	m.updateTitle()
+	cmds = append(cmds, m.lookupLikes(m.list.Items()), m.lookupLikes(m.side.Items()))
+	if m.list.Paginator.Page == m.list.Paginator.TotalPages-1 && m.list.Cursor() == 0 && len(m.list.Items())%pageSize == 0 {
+		cmds = append(cmds, m.loadMore())
 	}
-	// Handle user input
+	return model, tea.Batch(cmds...)
+}
+
+func (m *mainModel) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
-	case tickMsg:
-		playing := currentlyPlaying
-		if playing != nil && playing.Playing && playing.Item != nil {
-			cmd := m.progress.SetPercent(float64(playing.Progress) / float64(playing.Item.Duration))
+	case playbackMsg:
+		if msg.state != nil {
+			m.state = msg.state
+		}
+		cmds := []tea.Cmd{m.pollPlayback(time.Second), m.updateArt()}
+		if m.state != nil && m.state.Item != nil && m.state.Item.Duration > 0 {
+			cmds = append(cmds, m.progress.SetPercent(float64(m.state.Progress)/float64(m.state.Item.Duration)))
+		}
+		if m.state != nil && m.state.Item != nil && m.state.Item.ID != m.sideTrack {
+			m.sideTrack = m.state.Item.ID
+			cmds = append(cmds, m.loadSideQueue(nil))
+		}
+		cmds = append(cmds, m.updateLyrics())
+		if msg.state != nil && msg.state.Playing && msg.state.Item != nil {
+			playing := &msg.state.CurrentlyPlaying
 			m.playing = playing
-			m.playbackContext = playbackContext
+			m.contextURI, m.playbackContext = msg.contextURI, msg.context
 			if m.mode == Queue && len(m.list.Items()) != 0 {
-				if m.list.Items()[0].(mainItem).SpotifyItem.(spotify.FullTrack).Name != playing.Item.Name {
-					go func() {
-						new_items, err := QueueView(m.ctx, m.commands)
-						if err != nil {
-							return
-						}
-						m.list.SetItems(new_items)
-					}()
+				if current, ok := m.list.Items()[0].(mainItem).SpotifyItem.(spotify.FullTrack); !ok || current.Name != playing.Item.Name {
+					ctx, c := m.ctx, m.commands
+					cmds = append(cmds, m.reload(func() ([]list.Item, error) {
+						return QueueView(ctx, c)
+					}, false))
 				}
 			}
-			return m, tea.Batch(Tick(), cmd)
 		}
-		return m, Tick()
+		return m, tea.Batch(append(cmds, m.drawArtLater())...)
+
+	case pageMsg:
+		return m, m.addPage(msg)
+
+	case itemsMsg:
+		m.setItems(msg)
+		return m, nil
+
+	case errMsg:
+		m.SendMessage(msg.err.Error(), 2*time.Second)
+		return m, nil
+
+	case noticeMsg:
+		m.SendMessage(string(msg), 2*time.Second)
+		return m, nil
+
+	case likesMsg:
+		m.setLikes(msg)
+		return m, nil
+
+	case likedMsg:
+		m.setLikes(msg.likesMsg)
+		m.SendMessage(msg.notice, 2*time.Second)
+		return m, nil
+
+	case playedMsg:
+		m.setPlayed(msg)
+		return m, nil
+
+	case libraryMsg:
+		m.setLibrary(msg)
+		return m, nil
+
+	case queueMsg:
+		if msg.err != nil {
+			m.SendMessage(msg.err.Error(), 2*time.Second)
+			return m, nil
+		}
+		m.setSideQueue(msg.items)
+		return m, nil
+
+	case lyricsMsg:
+		m.setLyrics(msg)
+		return m, nil
+
+	case artMsg:
+		if msg.url == m.artURL {
+			m.setArt(msg.img)
+		}
+		return m, m.drawArtLater()
+
+	case artDrawMsg:
+		if string(msg) == m.artKey() {
+			m.drawArt()
+		}
+		return m, nil
 
 	case progress.FrameMsg:
 		progressModel, cmd := m.progress.Update(msg)
 		m.progress = progressModel.(progress.Model)
-		if !showingMessage {
+		if !m.showPanel && time.Now().After(m.messageUntil) && m.playing != nil {
 			m.list.NewStatusMessage(
-				fmt.Sprintf("Now playing %s by %s - %s %s/%s : %s",
-					m.playing.Item.Name,
-					m.playing.Item.Artists[0].Name,
+				fmt.Sprintf("Now playing %s - %s %s/%s : %s",
+					playingTitle(m.playing.Item),
 					m.progress.View(),
 					(time.Duration(m.playing.Progress) * time.Millisecond).Round(time.Second),
 					(time.Duration(m.playing.Item.Duration) * time.Millisecond).Round(time.Second),
@@ -646,95 +1120,169 @@ func (m *mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, cmd
 	case tea.KeyMsg:
 		// quit
-		if msg.String() == "ctrl+c" {
+		if m.keys.matches(msg, actionQuit) {
 			return m, tea.Quit
 		}
-		if msg.String() == "c" {
-			err := m.CopyToClipboard()
-			if err != nil {
-				return m, tea.Quit
-			}
-		}
-		if msg.String() == ">" {
-			go HandleSeek(m.ctx, m.commands, true)
-		}
-		if msg.String() == "<" {
-			go HandleSeek(m.ctx, m.commands, false)
-		}
-		if msg.String() == "+" {
-			go HandleVolume(m.ctx, m.commands, true)
-		}
-		if msg.String() == "-" {
-			go HandleVolume(m.ctx, m.commands, false)
+		// typing a filter
+		if m.list.SettingFilter() {
+			var cmd tea.Cmd
+			m.list, cmd = m.list.Update(msg)
+			return m, cmd
 		}
 		// search input
 		if m.input.Focused() {
-			search, cmd := m.Typing(msg)
-			if search {
-				m.mode = "search"
+			return m, m.Typing(msg)
+		}
+		// the tree and the side pane take the keys that move in them
+		if m.focus != paneContent {
+			if cmd, ok := m.paneKey(msg); ok {
+				return m, cmd
 			}
-			return m, cmd
 		}
+		switch {
+		case m.keys.matches(msg, actionFocusNext):
+			m.CycleFocus(true)
+			return m, nil
+		case m.keys.matches(msg, actionFocusPrev):
+			m.CycleFocus(false)
+			return m, nil
+		case m.keys.matches(msg, actionSidePane):
+			return m, tea.Batch(m.ToggleSidePane(), m.drawArtLater())
+		case m.keys.matches(msg, actionCopy):
+			err := m.CopyToClipboard()
+			if err != nil {
+				m.SendMessage(err.Error(), 2*time.Second)
+			}
+		case m.keys.matches(msg, actionSeekForward), m.keys.matches(msg, actionSeekBackward):
+			ctx, c, fwd := m.ctx, m.commands, m.keys.matches(msg, actionSeekForward)
+			m.after(background(func() error {
+				return HandleSeek(ctx, c, fwd)
+			}))
+		case m.keys.matches(msg, actionVolumeUp), m.keys.matches(msg, actionVolumeDown):
+			ctx, c, up := m.ctx, m.commands, m.keys.matches(msg, actionVolumeUp)
+			m.after(background(func() error {
+				return HandleVolume(ctx, c, up)
+			}))
+		// filter the loaded items, the list takes the key after this
+		case m.keys.matches(msg, actionFilter):
+			m.focus = paneContent
+			m.after(m.loadAll())
+		case m.keys.matches(msg, actionSort), m.keys.matches(msg, actionSortReverse):
+			m.CycleSort(m.keys.matches(msg, actionSortReverse))
 		// start search
-		if msg.String() == "s" || msg.String() == "/" {
+		case m.keys.matches(msg, actionSearch):
+			m.focus = paneContent
+			m.historyIndex = -1
+			m.draft = ""
 			m.input.Focus()
-		}
-		// enter device selection
-		if msg.String() == "d" {
-			m.mode = Devices
-			new_items, err := DeviceView(m.ctx, m.commands)
+			return m, nil
+		// pin or unpin a search
+		case m.keys.matches(msg, actionPinSearch):
+			err := m.PinSearch()
 			if err != nil {
-				return m, tea.Quit
+				m.SendMessage(err.Error(), 2*time.Second)
 			}
-			m.list.SetItems(new_items)
-			m.list.ResetSelected()
-		}
-		// go back
-		if msg.String() == "backspace" || msg.String() == "esc" || msg.String() == "q" {
-			msg, err := m.GoBack()
+		// run a pinned search
+		case m.keys.matches(msg, actionRunPinned):
+			err := m.RunPinnedSearch(m.keys.pinned(msg))
 			if err != nil {
-				return m, tea.Quit
+				m.SendMessage(err.Error(), 2*time.Second)
 			}
-			m.list.ResetSelected()
-			return m, msg
-		}
-		if msg.String() == "ctrl+d" {
+			return m, nil
+		// enter device selection
+		case m.keys.matches(msg, actionDevices):
+			ctx, c := m.ctx, m.commands
+			m.open(rootCrumbs[Devices], Devices, func() ([]list.Item, error) {
+				return DeviceView(ctx, c)
+			})
+			return m, nil
+		// go back
+		case m.keys.matches(msg, actionBack):
+			return m, m.GoBack()
+		case m.keys.matches(msg, actionDelete):
 			err := m.DeleteTrackFromPlaylist()
 			if err != nil {
-				return m, tea.Quit
+				m.SendMessage(err.Error(), 2*time.Second)
 			}
-		}
-		if msg.String() == "ctrl+@" || msg.String() == "ctrl+p" {
-			err := m.QueueItem()
+		case m.keys.matches(msg, actionQueueLast):
+			err := m.QueueItem(commands.QueueLast)
 			if err != nil {
-				return m, tea.Quit
+				m.SendMessage(err.Error(), 2*time.Second)
 			}
-		}
+		case m.keys.matches(msg, actionQueueNext):
+			err := m.QueueItem(commands.QueueNext)
+			if err != nil {
+				m.SendMessage(err.Error(), 2*time.Second)
+			}
+		case m.keys.matches(msg, actionMarkPlayed):
+			m.TogglePlayed()
+		case m.keys.matches(msg, actionQueueRemove):
+			m.EditQueue("remove")
+		case m.keys.matches(msg, actionQueueUp):
+			m.EditQueue("up")
+			return m, nil
+		case m.keys.matches(msg, actionQueueDown):
+			m.EditQueue("down")
+			return m, nil
 		// select item
-		if msg.String() == "enter" || msg.String() == " " || msg.String() == "p" {
+		case m.keys.matches(msg, actionSelect):
 			err := m.SelectItem()
 			if err != nil {
-				return m, tea.Quit
+				m.SendMessage(err.Error(), 2*time.Second)
 			}
-		}
 		// start radio
-		if msg.String() == "ctrl+r" {
+		case m.keys.matches(msg, actionRadio):
 			m.PlayRadio()
+		// save or unsave an album
+		case m.keys.matches(msg, actionSaveAlbum):
+			m.ToggleSaveAlbum()
+		// mark items
+		case m.keys.matches(msg, actionVisual):
+			m.ToggleVisual()
+			return m, nil
+		case m.keys.matches(msg, actionMarkUp):
+			m.MarkAndMove(false)
+			return m, nil
+		case m.keys.matches(msg, actionMarkDown):
+			m.MarkAndMove(true)
+			return m, nil
+		case m.keys.matches(msg, actionMarkAll):
+			m.MarkAll()
+			return m, nil
+		case m.keys.matches(msg, actionInvertMarks):
+			m.InvertMarks()
+			return m, nil
+		case m.keys.matches(msg, actionAddToPlaylist):
+			m.AddToPlaylist()
+			return m, nil
+		// like or unlike the selected item
+		case m.keys.matches(msg, actionLike):
+			m.ToggleLike()
+		// follow or unfollow an artist
+		case m.keys.matches(msg, actionFollow):
+			m.ToggleFollow()
 		}
 
 	// handle mouse
 	case tea.MouseMsg:
-		if msg.Type == 5 {
-			m.list.CursorUp()
-		}
-		if msg.Type == 6 {
-			m.list.CursorDown()
+		err := m.Mouse(msg)
+		if err != nil {
+			m.SendMessage(err.Error(), 2*time.Second)
 		}
+		return m, nil
 
 	// window size -1 to handle search bar
 	case tea.WindowSizeMsg:
-		h, v := DocStyle.GetFrameSize()
-		m.list.SetSize(msg.Width-h, msg.Height-v-1)
+		split := m.split
+		m.width, m.height = msg.Width, msg.Height
+		m.layout()
+		if m.split && !split {
+			m.sideTrack, m.lyricsID = "", ""
+			m.after(m.loadOpenSections())
+		}
+		var cmd tea.Cmd
+		m.list, cmd = m.list.Update(msg)
+		return m, tea.Batch(cmd, m.drawArtLater())
 	}
 
 	// return
@@ -744,13 +1292,28 @@ func (m *mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func InitMain(ctx *gctx.Context, c *commands.Commands, mode Mode) (tea.Model, error) {
-	prog := progress.New(progress.WithColorProfile(2), progress.WithoutPercentage())
-	var err error
-	lipgloss.SetColorProfile(2)
+	keys, err := loadKeyMap()
+	if err != nil {
+		return nil, err
+	}
+	searches := loadSearches()
+	t, err := loadTheme()
+	if err != nil {
+		return nil, err
+	}
+	art, err := artProtocolFor()
+	if err != nil {
+		return nil, err
+	}
+	side, err := sidePaneFor()
+	if err != nil {
+		return nil, err
+	}
+	DocStyle = t.docStyle()
 	items := []list.Item{}
 	switch mode {
 	case Main:
-		items, err = MainView(ctx, c)
+		items, err = MainView(ctx, c, keys, searches)
 		if err != nil {
 			return nil, err
 		}
@@ -766,48 +1329,54 @@ func InitMain(ctx *gctx.Context, c *commands.Commands, mode Mode) (tea.Model, er
 		}
 	}
 	m := &mainModel{
-		list:     list.New(items, list.NewDefaultDelegate(), 0, 0),
-		ctx:      ctx,
-		commands: c,
-		mode:     mode,
-		progress: prog,
-	}
-	m.list.Title = "GOSPT"
-	go m.TickPlayback()
-	Tick()
+		delegate:     t.delegate(),
+		ctx:          ctx,
+		commands:     c,
+		mode:         mode,
+		historyIndex: -1,
+		searches:     searches,
+		sorts:        loadSorts(),
+		keys:         keys,
+		progress:     t.progress(),
+		scrobbler:    scrobble.New(scrobble.FromConfig(), scrobble.DefaultQueuePath()),
+		feeder:       c.NewQueueFeeder(10 * time.Second),
+		theme:        t,
+		artProtocol:  art,
+		page:         1,
+		crumb:        rootCrumbs[mode],
+		sidePane:     side,
+		treeOpen:     map[string]bool{"Playlists": true},
+		library:      map[string][]treeItem{},
+		treeLoading:  map[string]bool{},
+		saved:        map[spotify.ID]bool{},
+		known:        map[spotify.ID]bool{},
+	}
+	if recorder, err := history.NewRecorder(history.DefaultPath()); err == nil {
+		m.history = recorder
+	} else {
+		ctx.Debug.Trace().Err(err).Msg("failed to open listening history")
+	}
+	m.list = list.New(items, heartDelegate{m.delegate, m.saved}, 0, 0)
+	m.updateTitle()
+	t.apply(&m.list)
 	m.list.DisableQuitKeybindings()
-	m.list.SetFilteringEnabled(false)
+	m.list.SetFilteringEnabled(true)
+	keys.apply(&m.list)
 	m.list.AdditionalShortHelpKeys = func() []key.Binding {
-		return []key.Binding{
-			key.NewBinding(key.WithKeys("q"), key.WithHelp("q", "back")),
-			key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "select")),
-			key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "search")),
-			key.NewBinding(key.WithKeys("ctrl"+"r"), key.WithHelp("ctrl+r", "radio")),
-			key.NewBinding(key.WithKeys("ctrl"+"p"), key.WithHelp("ctrl+p", "queue")),
-			key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "select device")),
-		}
-	}
-	m.list.AdditionalFullHelpKeys = func() []key.Binding {
-		return []key.Binding{
-			key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "back")),
-			key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "select")),
-			key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "search")),
-			key.NewBinding(key.WithKeys(">"), key.WithHelp(">", "seek forward")),
-			key.NewBinding(key.WithKeys("<"), key.WithHelp("<", "seek backward")),
-			key.NewBinding(key.WithKeys("+"), key.WithHelp("+", "volume up")),
-			key.NewBinding(key.WithKeys("-"), key.WithHelp("-", "volume down")),
-			key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "copy link to item")),
-			key.NewBinding(key.WithKeys("ctrl+c"), key.WithHelp("ctrl+c", "quit")),
-			key.NewBinding(key.WithKeys("ctrl"+"r"), key.WithHelp("ctrl+r", "start radio")),
-			key.NewBinding(key.WithKeys("ctrl"+"p"), key.WithHelp("ctrl+p", "queue song")),
-			key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "select device")),
-		}
+		return keys.help(shortHelp...)
 	}
+	m.list.AdditionalFullHelpKeys = keys.fullHelp
 	input := textinput.New()
 	input.Prompt = "$ "
 	input.Placeholder = "Search..."
 	input.CharLimit = 250
 	input.Width = 50
 	m.input = input
+	m.compact = t.delegate()
+	m.compact.ShowDescription = false
+	m.compact.SetSpacing(0)
+	m.tree = m.compactList("Library")
+	m.side = m.compactList("Queue")
+	m.buildTree()
 	return m, nil
 }
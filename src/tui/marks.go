@@ -0,0 +1,246 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/zmb3/spotify/v2"
+
+	"git.asdf.cafe/abs3nt/gospt/src/commands"
+	"git.asdf.cafe/abs3nt/gospt/src/gctx"
+)
+
+// playlistPick is what is added once a playlist to add it to is picked, the
+// view it was marked in waits on the stack.
+type playlistPick struct {
+	targets []mainItem
+}
+
+// targets are the marked items, or the selected item when none is marked.
+func (m *mainModel) targets() []mainItem {
+	targets := []mainItem{}
+	for _, item := range m.list.Items() {
+		if item, ok := item.(mainItem); ok && item.Marked {
+			targets = append(targets, item)
+		}
+	}
+	if len(targets) == 0 {
+		if selected, ok := m.list.SelectedItem().(mainItem); ok {
+			targets = append(targets, selected)
+		}
+	}
+	return targets
+}
+
+func (m *mainModel) setMark(index int, marked bool) {
+	if index < 0 {
+		return
+	}
+	item, ok := m.list.Items()[index].(mainItem)
+	if !ok || item.Marked == marked {
+		return
+	}
+	item.Marked = marked
+	m.refilter(m.list.SetItem(index, item))
+}
+
+// ToggleMark marks or unmarks the selected item and moves on to the next.
+func (m *mainModel) ToggleMark() {
+	if item, ok := m.list.SelectedItem().(mainItem); ok {
+		m.setMark(m.itemIndex(), !item.Marked)
+	}
+	m.list.CursorDown()
+	m.updateTitle()
+}
+
+// MarkAndMove marks the selected item and the one the cursor moves to, like
+// shift and an arrow key select in a file manager.
+func (m *mainModel) MarkAndMove(down bool) {
+	if len(m.list.Items()) == 0 {
+		return
+	}
+	m.setMark(m.itemIndex(), true)
+	if down {
+		m.list.CursorDown()
+	} else {
+		m.list.CursorUp()
+	}
+	m.setMark(m.itemIndex(), true)
+	m.updateTitle()
+}
+
+func (m *mainModel) MarkAll() {
+	for index := range m.list.Items() {
+		m.setMark(index, true)
+	}
+	m.updateTitle()
+}
+
+func (m *mainModel) InvertMarks() {
+	for index, item := range m.list.Items() {
+		if item, ok := item.(mainItem); ok {
+			m.setMark(index, !item.Marked)
+		}
+	}
+	m.updateTitle()
+}
+
+// ClearMarks unmarks everything and leaves visual mode.
+func (m *mainModel) ClearMarks() {
+	for index := range m.list.Items() {
+		m.setMark(index, false)
+	}
+	m.visual = false
+	m.updateTitle()
+}
+
+func (m *mainModel) ToggleVisual() {
+	m.visual = !m.visual
+	m.updateTitle()
+}
+
+func (m *mainModel) markedCount() int {
+	marked := 0
+	for _, item := range m.list.Items() {
+		if item, ok := item.(mainItem); ok && item.Marked {
+			marked++
+		}
+	}
+	return marked
+}
+
+// updateTitle shows the breadcrumbs, visual mode and the number of marked
+// items in the title.
+func (m *mainModel) updateTitle() {
+	marked := m.markedCount()
+	status := ""
+	switch {
+	case m.visual:
+		status = fmt.Sprintf(" -- VISUAL -- %d marked", marked)
+	case marked > 0:
+		status = fmt.Sprintf(" -- %d marked", marked)
+	}
+	width := m.list.Width() - m.list.Styles.Title.GetHorizontalFrameSize() - lipgloss.Width(status)
+	m.list.Title = m.breadcrumbs(width) + status
+}
+
+// targetName names what a batch action works on.
+func targetName(targets []mainItem) string {
+	if len(targets) == 1 {
+		return targets[0].Name
+	}
+	return fmt.Sprintf("%d items", len(targets))
+}
+
+// targetTracks are the ids of the tracks among the targets.
+func targetTracks(targets []mainItem) []spotify.ID {
+	ids := []spotify.ID{}
+	for _, target := range targets {
+		if id, ok := trackID(target); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// AddToPlaylist lists the user's playlists to add the marked tracks to.
+func (m *mainModel) AddToPlaylist() {
+	targets := m.targets()
+	if len(targets) == 0 {
+		return
+	}
+	ctx, c := m.ctx, m.commands
+	m.pick = &playlistPick{targets: targets}
+	m.open("Add to playlist", AddToPlaylist, func() ([]list.Item, error) {
+		return PlaylistPickerView(ctx, c)
+	})
+	m.visual = false
+	m.updateTitle()
+}
+
+// addToPlaylist adds the tracks picked before, albums and playlists among
+// them are added track by track.
+func (m *mainModel) addToPlaylist(playlist spotify.SimplePlaylist) {
+	targets := m.pick.targets
+	m.cancelPick()
+	m.SendMessage("Adding "+targetName(targets)+" to "+playlist.Name, 2*time.Second)
+	ctx, c := m.ctx, m.commands
+	m.after(background(func() error {
+		ids := []spotify.ID{}
+		for _, target := range targets {
+			kind, query, _, ok := queueTarget(target)
+			if !ok {
+				continue
+			}
+			if kind == "track" {
+				ids = append(ids, spotify.ID(query))
+				continue
+			}
+			tracks, err := c.CollectionTracks(ctx, kind, query)
+			if err != nil {
+				return err
+			}
+			for _, track := range tracks {
+				ids = append(ids, track.ID)
+			}
+		}
+		return HandleAddTracksToPlaylist(ctx, c, playlist.ID, ids)
+	}))
+}
+
+// cancelPick goes back to the list the playlist picker was opened from, with
+// the marks cleared.
+func (m *mainModel) cancelPick() {
+	m.pick = nil
+	m.pop()
+	m.ClearMarks()
+}
+
+// queueTarget is what the gospt queue adds for an item.
+func queueTarget(item mainItem) (kind, query, name string, ok bool) {
+	if id, ok := trackID(item); ok {
+		return "track", string(id), item.Name, true
+	}
+	switch item := item.SpotifyItem.(type) {
+	case *spotify.FullTrack:
+		return "track", string(item.ID), item.Name, true
+	case *spotify.SimpleTrack:
+		return "track", string(item.ID), item.Name, true
+	case spotify.SimplePlaylist:
+		return "playlist", string(item.URI), item.Name, true
+	case *spotify.SimplePlaylist:
+		return "playlist", string(item.URI), item.Name, true
+	case spotify.SimpleAlbum:
+		return "album", string(item.ID), item.Name, true
+	case spotify.SimpleArtist:
+		return "artist", string(item.ID), item.Name + " top tracks", true
+	case spotify.FullArtist:
+		return "artist", string(item.ID), item.Name + " top tracks", true
+	case artistSection:
+		if item.Kind == topTracksSection {
+			return "artist", string(item.Artist.ID), item.Artist.Name + " top tracks", true
+		}
+	}
+	return "", "", "", false
+}
+
+// queueTargets queues every target in order, before the rest of the gospt
+// queue or after it.
+func queueTargets(ctx *gctx.Context, c *commands.Commands, targets []mainItem, pos commands.QueuePosition) error {
+	if pos == commands.QueueNext {
+		// each one goes in front of the one before
+		for i, j := 0, len(targets)-1; i < j; i, j = i+1, j-1 {
+			targets[i], targets[j] = targets[j], targets[i]
+		}
+	}
+	for _, target := range targets {
+		if kind, query, _, ok := queueTarget(target); ok {
+			if err := HandleQueueCollection(ctx, c, kind, query, pos); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
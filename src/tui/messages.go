@@ -0,0 +1,143 @@
+package tui
+
+import (
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/zmb3/spotify/v2"
+)
+
+// Work that talks to spotify runs in commands and comes back to Update as one
+// of these messages, only Update changes the model.
+
+// pageMsg is the next page of the view it was loaded for.
+type pageMsg struct {
+	view  int
+	items []list.Item
+	err   error
+}
+
+// itemsMsg replaces the items of the view they were fetched for.
+type itemsMsg struct {
+	view  int
+	items []list.Item
+	reset bool
+	err   error
+}
+
+// playbackMsg is the state of the player, the context is the name of what is
+// playing from.
+type playbackMsg struct {
+	state      *spotify.PlayerState
+	contextURI spotify.URI
+	context    string
+}
+
+// playedMsg is an episode item after it was marked as played or unplayed.
+type playedMsg struct {
+	view   int
+	item   mainItem
+	notice string
+}
+
+// errMsg is shown below the list.
+type errMsg struct{ err error }
+
+// noticeMsg tells how an action went once it is done, it is shown below the
+// list.
+type noticeMsg string
+
+// after queues a command for Update to return, for methods that return an
+// error rather than a command.
+func (m *mainModel) after(cmd tea.Cmd) {
+	if cmd != nil {
+		m.pending = append(m.pending, cmd)
+	}
+}
+
+// newView starts a view, pages and items still on their way for the view
+// before it are dropped when they arrive.
+func (m *mainModel) newView() {
+	m.view++
+	m.page = 1
+	m.waiting = false
+	m.loading = false
+	m.loadingAll = false
+	m.lastPage = false
+}
+
+// reload runs fetch in the background and shows the items it returns, reset
+// moves the cursor back to the top.
+func (m *mainModel) reload(fetch func() ([]list.Item, error), reset bool) tea.Cmd {
+	view := m.view
+	return func() tea.Msg {
+		items, err := fetch()
+		return itemsMsg{view: view, items: items, reset: reset, err: err}
+	}
+}
+
+// background runs an action that only talks to spotify, errors are shown
+// below the list.
+func background(action func() error) tea.Cmd {
+	return func() tea.Msg {
+		if err := action(); err != nil {
+			return errMsg{err}
+		}
+		return nil
+	}
+}
+
+func (m *mainModel) setItems(msg itemsMsg) {
+	if msg.view != m.view {
+		return
+	}
+	if m.waiting {
+		m.waiting = false
+		// the filter asked for all of the view before its first items came
+		if m.loadingAll {
+			m.after(m.loadAll())
+		}
+	}
+	if msg.err != nil {
+		m.SendMessage(msg.err.Error(), 2*time.Second)
+		return
+	}
+	m.refilter(m.list.SetItems(msg.items))
+	if m.mode == Queue {
+		m.setSideQueue(msg.items)
+	}
+	if msg.reset {
+		m.list.ResetSelected()
+	}
+}
+
+// pollPlayback fetches the player state after a while and feeds it to the
+// scrobbler, the listening history and the queue feeder. The next poll starts
+// once the state arrived, so polls never overlap.
+func (m *mainModel) pollPlayback(after time.Duration) tea.Cmd {
+	ctx, c := m.ctx, m.commands
+	scrobbler, recorder, feeder := m.scrobbler, m.history, m.feeder
+	uri, name := m.contextURI, m.playbackContext
+	return tea.Tick(after, func(time.Time) tea.Msg {
+		state, err := c.PlayerState(ctx)
+		if err != nil {
+			scrobbler.Update(ctx, nil)
+			return playbackMsg{contextURI: uri, context: name}
+		}
+		recorder.Update(state)
+		feeder.Update(ctx, state)
+		scrobbler.Update(ctx, &state.CurrentlyPlaying)
+		msg := playbackMsg{state: state, contextURI: uri, context: name}
+		// the name is looked up each time the context changes, and again on
+		// the next poll when looking it up failed
+		if state.Playing && state.Item != nil && state.PlaybackContext.URI != uri {
+			context, err := getContext(ctx, c, &state.CurrentlyPlaying)
+			msg.context = context
+			if err == nil {
+				msg.contextURI = state.PlaybackContext.URI
+			}
+		}
+		return msg
+	})
+}
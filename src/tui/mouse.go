@@ -0,0 +1,115 @@
+package tui
+
+import (
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// doubleClick is how soon a second click on an item plays it.
+const doubleClick = 400 * time.Millisecond
+
+// origin is the screen cell the list starts at.
+func origin() (int, int) {
+	return DocStyle.GetMarginLeft() + DocStyle.GetBorderLeftSize() + DocStyle.GetPaddingLeft(),
+		DocStyle.GetMarginTop() + DocStyle.GetBorderTopSize() + DocStyle.GetPaddingTop()
+}
+
+// itemAt is the index of the item at a row of a list, or -1.
+func itemAt(l *list.Model, d list.DefaultDelegate, row int) int {
+	if l.ShowTitle() {
+		row -= lipgloss.Height(l.Styles.TitleBar.Render(l.Styles.Title.Render(l.Title)))
+	}
+	if l.ShowStatusBar() {
+		row -= lipgloss.Height(l.Styles.StatusBar.Render("status"))
+	}
+	size := d.Height() + d.Spacing()
+	if row < 0 || row%size >= d.Height() {
+		return -1
+	}
+	start, end := l.Paginator.GetSliceBounds(len(l.VisibleItems()))
+	index := start + row/size
+	if index >= end {
+		return -1
+	}
+	return index
+}
+
+// Mouse selects the clicked item and plays it on a double click, seeks on the
+// progress bar and changes the volume when scrolling over it. In the split
+// layout the pane under the mouse gets the focus.
+func (m *mainModel) Mouse(msg tea.MouseMsg) error {
+	left, top := m.panelOrigin()
+	x, y := msg.X-left, msg.Y-top
+	up := msg.Button == tea.MouseButtonWheelUp
+	wheel := up || msg.Button == tea.MouseButtonWheelDown
+	switch {
+	case wheel && m.showPanel && y == volumeRow && x >= artCols:
+		ctx, c := m.ctx, m.commands
+		m.after(background(func() error {
+			return HandleVolume(ctx, c, up)
+		}))
+		return nil
+	case !wheel && (msg.Button != tea.MouseButtonLeft || msg.Action != tea.MouseActionPress):
+		return nil
+	case !wheel && m.showPanel && y == artRows:
+		if m.state == nil || m.state.Item == nil || x < 0 || x >= m.progressWidth() {
+			return nil
+		}
+		pos := int(m.state.Item.Duration) * x / m.progressWidth()
+		ctx, c := m.ctx, m.commands
+		m.after(background(func() error {
+			return HandleSeekTo(ctx, c, pos)
+		}))
+		return nil
+	case !wheel && y >= -1:
+		return nil
+	}
+	p := m.paneAt(msg.X)
+	_, top = m.contentOrigin()
+	switch {
+	case p == paneTree:
+		return m.clickList(p, &m.tree, m.compact, msg.Y-top, wheel, up)
+	case p == paneSide && m.sidePane == sideLyrics:
+		if wheel {
+			m.scrollLyrics(!up)
+		}
+		m.focus = p
+		return nil
+	case p == paneSide && m.sidePane == sideQueue:
+		return m.clickList(p, &m.side, m.compact, msg.Y-top, wheel, up)
+	case p == paneContent:
+		return m.clickList(p, &m.list, m.delegate, msg.Y-top, wheel, up)
+	}
+	return nil
+}
+
+// clickList moves the cursor of the list of a pane when scrolling, or selects
+// the clicked item and selects it again on a double click.
+func (m *mainModel) clickList(p pane, l *list.Model, d list.DefaultDelegate, row int, wheel, up bool) error {
+	if m.split {
+		m.focus = p
+	}
+	if wheel {
+		if up {
+			l.CursorUp()
+		} else {
+			l.CursorDown()
+		}
+		return nil
+	}
+	index := itemAt(l, d, row)
+	if index < 0 {
+		return nil
+	}
+	double := p == m.lastClickPane && index == l.Index() && time.Since(m.lastClick) < doubleClick
+	l.Select(index)
+	m.lastClick, m.lastClickPane = time.Now(), p
+	if double {
+		m.lastClick = time.Time{}
+		return m.selectIn(p)
+	}
+	return nil
+}
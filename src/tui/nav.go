@@ -0,0 +1,133 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/zmb3/spotify/v2"
+
+	"git.asdf.cafe/abs3nt/gospt/src/commands"
+)
+
+// crumbSeparator goes between the views of the breadcrumbs in the title.
+const crumbSeparator = " › "
+
+// viewState is a view that was left for another one. The list is kept as it
+// was, so going back shows the same items, cursor, page and filter.
+type viewState struct {
+	list          list.Model
+	mode          Mode
+	crumb         string
+	page          int
+	lastPage      bool
+	playlist      spotify.SimplePlaylist
+	artist        spotify.SimpleArtist
+	section       artistSection
+	album         spotify.SimpleAlbum
+	show          spotify.SimpleShow
+	audiobook     commands.SimpleAudiobook
+	searchResults *SearchResults
+	search        string
+}
+
+// rootCrumbs name the views the TUI can start in.
+var rootCrumbs = map[Mode]string{
+	Main:    "Main",
+	Devices: "Devices",
+	Tracks:  "Saved Tracks",
+}
+
+// push keeps the current view on the stack before another one is opened,
+// crumb names the view being opened.
+func (m *mainModel) push(crumb string) {
+	m.stack = append(m.stack, viewState{
+		list:          m.list,
+		mode:          m.mode,
+		crumb:         m.crumb,
+		page:          m.page,
+		lastPage:      m.lastPage,
+		playlist:      m.playlist,
+		artist:        m.artist,
+		section:       m.section,
+		album:         m.album,
+		show:          m.show,
+		audiobook:     m.audiobook,
+		searchResults: m.searchResults,
+		search:        m.search,
+	})
+	m.crumb = crumb
+	m.newView()
+	m.list.ResetFilter()
+}
+
+// open pushes a view whose items are fetched in the background, the list is
+// empty until they arrive.
+func (m *mainModel) open(crumb string, mode Mode, fetch func() ([]list.Item, error)) {
+	m.push(crumb)
+	m.mode = mode
+	m.waiting = true
+	m.list.SetItems(nil)
+	m.after(m.reload(fetch, true))
+}
+
+// pop shows the view the current one was opened from.
+func (m *mainModel) pop() {
+	last := m.stack[len(m.stack)-1]
+	m.stack = m.stack[:len(m.stack)-1]
+	width, height := m.list.Width(), m.list.Height()
+	m.newView()
+	m.list = last.list
+	m.list.SetSize(width, height)
+	m.mode, m.crumb = last.mode, last.crumb
+	m.page, m.lastPage = last.page, last.lastPage
+	m.playlist, m.artist, m.section = last.playlist, last.artist, last.section
+	m.album, m.show, m.audiobook = last.album, last.show, last.audiobook
+	m.searchResults, m.search = last.searchResults, last.search
+	// the list kept its filter, it is not a new view to the filter
+	m.filterMode, m.filtered = m.mode, len(m.list.Items())
+	m.updateTitle()
+}
+
+// back goes to the view the current one was opened from, a view the TUI
+// started in goes back to the main view.
+func (m *mainModel) back() {
+	if len(m.stack) > 0 {
+		m.pop()
+		return
+	}
+	ctx, c, keys, searches := m.ctx, m.commands, m.keys, m.searches
+	m.newView()
+	m.mode, m.crumb = Main, rootCrumbs[Main]
+	m.waiting = true
+	m.list.ResetFilter()
+	m.list.SetItems(nil)
+	m.after(m.reload(func() ([]list.Item, error) {
+		return MainView(ctx, c, keys, searches)
+	}, true))
+	m.updateTitle()
+}
+
+// selectedName names the selected item for the breadcrumbs.
+func (m *mainModel) selectedName() string {
+	if item, ok := m.list.SelectedItem().(mainItem); ok {
+		return item.Name
+	}
+	return ""
+}
+
+// breadcrumbs is the path to the current view, the views at the start give
+// way to an ellipsis when it does not fit in width.
+func (m *mainModel) breadcrumbs(width int) string {
+	crumbs := []string{}
+	for _, view := range m.stack {
+		crumbs = append(crumbs, view.crumb)
+	}
+	crumbs = append(crumbs, m.crumb)
+	path := strings.Join(crumbs, crumbSeparator)
+	for len(crumbs) > 1 && width > 0 && lipgloss.Width(path) > width {
+		crumbs = crumbs[1:]
+		path = "…" + crumbSeparator + strings.Join(crumbs, crumbSeparator)
+	}
+	return path
+}
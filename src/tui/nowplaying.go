@@ -0,0 +1,147 @@
+package tui
+
+import (
+	"fmt"
+	"image"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// panelHeight is the album art and the progress bar below it.
+	panelHeight = artRows + 1
+	// volumeRow is the line of the panel with the device and volume.
+	volumeRow = 4
+)
+
+// panelText is what is shown next to the album art.
+func (m *mainModel) panelText() []string {
+	state := m.state
+	if state == nil || state.Item == nil {
+		return []string{"Nothing is playing"}
+	}
+	item := state.Item
+	artists := []string{}
+	for _, artist := range item.Artists {
+		artists = append(artists, artist.Name)
+	}
+	lines := []string{item.Name, strings.Join(artists, ", "), item.Album.Name, ""}
+	if m.playbackContext != "" {
+		lines[3] = "Playing from " + m.playbackContext
+	}
+	lines = append(lines, fmt.Sprintf("%s, volume %d%%", state.Device.Name, int(state.Device.Volume)))
+	status, shuffle := "Paused", "off"
+	if state.Playing {
+		status = "Playing"
+	}
+	if state.ShuffleState {
+		shuffle = "on"
+	}
+	return append(lines, fmt.Sprintf("%s, shuffle %s, repeat %s", status, shuffle, state.RepeatState))
+}
+
+func (m *mainModel) nowPlayingView() string {
+	width := m.panelWidth()
+	title := lipgloss.NewStyle().Bold(true).Foreground(themeColor(m.theme.selected)).MaxWidth(width - artCols - 2)
+	text := lipgloss.NewStyle().Foreground(themeColor(m.theme.description)).MaxWidth(width - artCols - 2)
+	lines := m.panelText()
+	rows := []string{}
+	for i := 0; i < artRows; i++ {
+		art := strings.Repeat(" ", artCols)
+		if m.artBlocks != nil {
+			art = m.artBlocks[i]
+		}
+		switch {
+		case i == 0:
+			art += "  " + title.Render(lines[i])
+		case i < len(lines) && lines[i] != "":
+			art += "  " + text.Render(lines[i])
+		}
+		rows = append(rows, art)
+	}
+	prog := m.progress
+	prog.Width = m.progressWidth()
+	return strings.Join(append(rows, prog.View()+text.Render(m.progressTimes())), "\n")
+}
+
+func (m *mainModel) progressTimes() string {
+	if m.state == nil || m.state.Item == nil {
+		return ""
+	}
+	return fmt.Sprintf(" %s/%s",
+		(time.Duration(m.state.Progress) * time.Millisecond).Round(time.Second),
+		(time.Duration(m.state.Item.Duration) * time.Millisecond).Round(time.Second))
+}
+
+// progressWidth is what is left of the panel's last line after the times.
+func (m *mainModel) progressWidth() int {
+	return max(m.panelWidth()-lipgloss.Width(m.progressTimes()), 1)
+}
+
+// updateArt fetches the art of the current album when it changed.
+func (m *mainModel) updateArt() tea.Cmd {
+	url := ""
+	if m.state != nil {
+		url = artURL(m.state.Item)
+	}
+	if url == m.artURL {
+		return nil
+	}
+	m.artURL, m.artImg, m.artBlocks, m.artSeq = url, nil, nil, ""
+	if url == "" || m.artProtocol == artNone {
+		return nil
+	}
+	return fetchArt(url)
+}
+
+func (m *mainModel) setArt(img image.Image) {
+	m.artImg = img
+	switch {
+	case img == nil:
+	case m.artProtocol == artBlocks:
+		m.artBlocks = halfBlocks(img)
+	case m.artProtocol == artKitty:
+		m.artSeq = kittyImage(img)
+	case m.artProtocol == artSixel:
+		m.artSeq = sixelImage(img)
+	}
+}
+
+// artKey changes whenever the renderer may have drawn over the art.
+func (m *mainModel) artKey() string {
+	return fmt.Sprint(m.showPanel, m.split, m.artURL, m.artSeq != "", m.panelWidth(), m.list.Height(), m.panelText())
+}
+
+// drawArtLater draws kitty and sixel art once the renderer has drawn the
+// panel, which erases sixels below it.
+func (m *mainModel) drawArtLater() tea.Cmd {
+	if m.artProtocol != artKitty && m.artProtocol != artSixel {
+		return nil
+	}
+	key := m.artKey()
+	if key == m.artDrawn {
+		return nil
+	}
+	m.artDrawn = key
+	return tea.Tick(150*time.Millisecond, func(time.Time) tea.Msg {
+		return artDrawMsg(key)
+	})
+}
+
+func (m *mainModel) drawArt() {
+	seq := m.artSeq
+	if !m.showPanel {
+		seq = ""
+	}
+	if m.artProtocol == artKitty && seq == "" {
+		seq = kittyDelete()
+	}
+	if seq == "" {
+		return
+	}
+	col, row := m.panelOrigin()
+	writeAt(seq, row, col)
+}
@@ -0,0 +1,494 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/zmb3/spotify/v2"
+
+	"git.asdf.cafe/abs3nt/gospt/src/commands"
+	"git.asdf.cafe/abs3nt/gospt/src/config"
+	"git.asdf.cafe/abs3nt/gospt/src/gctx"
+)
+
+// pane is a part of the split layout that can have the focus.
+type pane int
+
+const (
+	paneContent pane = iota
+	paneTree
+	paneSide
+)
+
+// sidePane is what the pane right of the content shows.
+type sidePane string
+
+const (
+	sideQueue  sidePane = "queue"
+	sideLyrics sidePane = "lyrics"
+	sideNone   sidePane = "none"
+)
+
+// splitWidth is how wide the terminal has to be for the split layout, it falls
+// back to the single list below that.
+const splitWidth = 100
+
+// librarySections are the sections of the library tree, in order.
+var librarySections = []string{"Playlists", "Albums", "Artists"}
+
+// sidePaneFor reads the side pane from the layout section of the config.
+func sidePaneFor() (sidePane, error) {
+	switch side := sidePane(strings.ToLower(config.Values.Layout.SidePane)); side {
+	case "":
+		return sideQueue, nil
+	case "off":
+		return sideNone, nil
+	case sideQueue, sideLyrics, sideNone:
+		return side, nil
+	}
+	return sideNone, fmt.Errorf("unknown side_pane %q, use queue, lyrics or none", config.Values.Layout.SidePane)
+}
+
+// treeItem is a section of the library tree or a playlist, album or artist in
+// one.
+type treeItem struct {
+	section string
+	header  bool
+	open    bool
+	loaded  bool
+	count   int
+	name    string
+	item    any
+}
+
+func (i treeItem) FilterValue() string { return i.name }
+func (i treeItem) Description() string { return "" }
+
+func (i treeItem) Title() string {
+	switch {
+	case i.header && !i.loaded && i.open:
+		return fmt.Sprintf("▾ %s (loading)", i.name)
+	case i.header && !i.loaded:
+		return "▸ " + i.name
+	case i.header && i.open:
+		return fmt.Sprintf("▾ %s (%d)", i.name, i.count)
+	case i.header:
+		return fmt.Sprintf("▸ %s (%d)", i.name, i.count)
+	}
+	return "  " + i.name
+}
+
+type libraryMsg struct {
+	section string
+	items   []treeItem
+	err     error
+}
+
+type queueMsg struct {
+	items []list.Item
+	err   error
+}
+
+// compactList is a list of one line items without a status bar or help, for
+// the tree and the side pane.
+func (m *mainModel) compactList(title string) list.Model {
+	l := list.New(nil, heartDelegate{m.compact, m.saved}, 0, 0)
+	l.Title = title
+	m.theme.apply(&l)
+	l.SetShowStatusBar(false)
+	l.SetShowHelp(false)
+	l.SetFilteringEnabled(false)
+	l.DisableQuitKeybindings()
+	m.keys.apply(&l)
+	return l
+}
+
+// loadLibrary loads a section of the library tree, every playlist, album or
+// followed artist of the user.
+func loadLibrary(ctx *gctx.Context, c *commands.Commands, section string) tea.Cmd {
+	return func() tea.Msg {
+		items := []treeItem{}
+		switch section {
+		case "Playlists":
+			for page := 1; ; page++ {
+				playlists, err := c.Playlists(ctx, page)
+				if err != nil {
+					return libraryMsg{section: section, err: err}
+				}
+				for _, playlist := range playlists.Playlists {
+					items = append(items, treeItem{section: section, name: playlist.Name, item: playlist})
+				}
+				if len(playlists.Playlists) < pageSize {
+					break
+				}
+			}
+		case "Albums":
+			for page := 1; ; page++ {
+				albums, err := c.UserAlbums(ctx, page)
+				if err != nil {
+					return libraryMsg{section: section, err: err}
+				}
+				for _, album := range albums.Albums {
+					items = append(items, treeItem{section: section, name: album.Name, item: album.SimpleAlbum})
+				}
+				if len(albums.Albums) < pageSize {
+					break
+				}
+			}
+		case "Artists":
+			after := spotify.ID("")
+			for {
+				artists, err := c.UserArtists(ctx, after)
+				if err != nil {
+					return libraryMsg{section: section, err: err}
+				}
+				for _, artist := range artists.Artists {
+					items = append(items, treeItem{section: section, name: artist.Name, item: artist.SimpleArtist})
+				}
+				after = spotify.ID(artists.Cursor.After)
+				if after == "" || len(artists.Artists) == 0 {
+					break
+				}
+			}
+		}
+		return libraryMsg{section: section, items: items}
+	}
+}
+
+// loadOpenSections loads the open sections of the library tree that are not
+// loaded yet. Sections are loaded when they are first shown, so a library that
+// is never looked at is never fetched.
+func (m *mainModel) loadOpenSections() tea.Cmd {
+	if !m.split {
+		return nil
+	}
+	cmds := []tea.Cmd{}
+	for _, section := range librarySections {
+		if _, loaded := m.library[section]; loaded || !m.treeOpen[section] || m.treeLoading[section] {
+			continue
+		}
+		m.treeLoading[section] = true
+		cmds = append(cmds, loadLibrary(m.ctx, m.commands, section))
+	}
+	return tea.Batch(cmds...)
+}
+
+// setLibrary shows a loaded section of the library tree.
+func (m *mainModel) setLibrary(msg libraryMsg) {
+	m.treeLoading[msg.section] = false
+	if msg.err != nil {
+		m.SendMessage(msg.err.Error(), 2*time.Second)
+		return
+	}
+	m.library[msg.section] = msg.items
+	m.buildTree()
+}
+
+// buildTree lists the sections of the library with the open ones expanded.
+func (m *mainModel) buildTree() {
+	items := []list.Item{}
+	for _, section := range librarySections {
+		open := m.treeOpen[section]
+		_, loaded := m.library[section]
+		items = append(items, treeItem{section: section, header: true, open: open, loaded: loaded, count: len(m.library[section]), name: section})
+		if open {
+			for _, item := range m.library[section] {
+				items = append(items, item)
+			}
+		}
+	}
+	index := m.tree.Index()
+	m.tree.SetItems(items)
+	m.tree.Select(min(index, len(items)-1))
+}
+
+// selectTree opens or closes the selected section, or opens the selected
+// playlist, album or artist in the content pane.
+func (m *mainModel) selectTree() {
+	item, ok := m.tree.SelectedItem().(treeItem)
+	if !ok || m.pick != nil {
+		return
+	}
+	if item.header {
+		m.treeOpen[item.section] = !m.treeOpen[item.section]
+		m.after(m.loadOpenSections())
+		m.buildTree()
+		return
+	}
+	m.focus = paneContent
+	ctx, c, keys := m.ctx, m.commands, m.keys
+	switch selected := item.item.(type) {
+	case spotify.SimplePlaylist:
+		m.open(selected.Name, Playlist, func() ([]list.Item, error) {
+			return PlaylistView(ctx, c, selected)
+		})
+		m.playlist = selected
+	case spotify.SimpleAlbum:
+		m.open(selected.Name, Album, func() ([]list.Item, error) {
+			return AlbumView(ctx, selected.ID, c, keys)
+		})
+		m.album = selected
+	case spotify.SimpleArtist:
+		m.openArtist(selected, Artist)
+	}
+}
+
+// loadSideQueue fetches the queue for the side pane, after running action
+// when there is one.
+func (m *mainModel) loadSideQueue(action func() error) tea.Cmd {
+	if !m.split || m.sidePane != sideQueue {
+		if action == nil {
+			return nil
+		}
+		return background(action)
+	}
+	ctx, c := m.ctx, m.commands
+	return func() tea.Msg {
+		if action != nil {
+			if err := action(); err != nil {
+				return queueMsg{err: err}
+			}
+		}
+		items, err := QueueView(ctx, c)
+		return queueMsg{items: items, err: err}
+	}
+}
+
+// setSideQueue shows the queue in the side pane, keeping the cursor where it
+// was.
+func (m *mainModel) setSideQueue(items []list.Item) {
+	index := m.side.Index()
+	m.side.SetItems(items)
+	m.side.Select(min(index, len(items)-1))
+}
+
+// playQueued plays the selected track of the queue pane.
+func (m *mainModel) playQueued() tea.Cmd {
+	items, index := m.side.Items(), m.side.Index()
+	if index < 0 || index >= len(items) {
+		return nil
+	}
+	return m.loadSideQueue(queueAction(m.ctx, m.commands, items, index))
+}
+
+// queueAction plays the item of a listed queue at index. A track of the gospt
+// queue is played and taken out of it, spotify's queue is skipped ahead in.
+func queueAction(ctx *gctx.Context, c *commands.Commands, items []list.Item, index int) func() error {
+	// skip over the gospt queue, it is not in spotify's queue yet
+	skip := index
+	for _, item := range items {
+		if _, ok := item.(mainItem).SpotifyItem.(commands.QueueItem); ok {
+			skip--
+		}
+	}
+	if item, ok := items[index].(mainItem).SpotifyItem.(commands.QueueItem); ok {
+		return func() error {
+			if err := HandlePlayTrack(ctx, c, item.ID); err != nil {
+				return err
+			}
+			return c.RemoveQueuedTrack(item.ID)
+		}
+	}
+	return func() error {
+		return HandleNextInQueue(ctx, c, skip)
+	}
+}
+
+// ToggleSidePane shows the queue, the lyrics or nothing right of the content.
+func (m *mainModel) ToggleSidePane() tea.Cmd {
+	if !m.split {
+		return nil
+	}
+	switch m.sidePane {
+	case sideQueue:
+		m.sidePane = sideLyrics
+	case sideLyrics:
+		m.sidePane = sideNone
+	default:
+		m.sidePane = sideQueue
+	}
+	m.lyricsID = ""
+	m.layout()
+	return tea.Batch(m.loadSideQueue(nil), m.updateLyrics())
+}
+
+// panes are the panes that can have the focus, left to right.
+func (m *mainModel) panes() []pane {
+	if !m.split {
+		return []pane{paneContent}
+	}
+	if m.sidePane == sideNone {
+		return []pane{paneTree, paneContent}
+	}
+	return []pane{paneTree, paneContent, paneSide}
+}
+
+// CycleFocus moves the focus to the next or the previous pane.
+func (m *mainModel) CycleFocus(next bool) {
+	panes := m.panes()
+	for index, p := range panes {
+		if p != m.focus {
+			continue
+		}
+		if next {
+			m.focus = panes[(index+1)%len(panes)]
+		} else {
+			m.focus = panes[(index+len(panes)-1)%len(panes)]
+		}
+		return
+	}
+	m.focus = paneContent
+}
+
+// paneKey handles the keys that move in and select from the focused tree or
+// side pane, other keys act on the content as usual.
+func (m *mainModel) paneKey(msg tea.KeyMsg) (tea.Cmd, bool) {
+	var cmd tea.Cmd
+	switch {
+	case m.keys.matches(msg, actionBack):
+		m.focus = paneContent
+		return nil, true
+	case m.keys.navigates(msg) && m.focus == paneTree:
+		m.tree, cmd = m.tree.Update(msg)
+		return cmd, true
+	case m.keys.navigates(msg) && m.sidePane == sideLyrics:
+		m.scrollLyrics(m.keys.matches(msg, actionDown) || m.keys.matches(msg, actionNextPage) || m.keys.matches(msg, actionBottom))
+		return nil, true
+	case m.keys.navigates(msg):
+		m.side, cmd = m.side.Update(msg)
+		return cmd, true
+	case m.keys.matches(msg, actionSelect):
+		if err := m.selectIn(m.focus); err != nil {
+			m.SendMessage(err.Error(), 2*time.Second)
+		}
+		return nil, true
+	}
+	return nil, false
+}
+
+// selectIn selects the selected item of a pane.
+func (m *mainModel) selectIn(p pane) error {
+	switch p {
+	case paneTree:
+		m.selectTree()
+		return nil
+	case paneSide:
+		if m.sidePane == sideQueue {
+			m.after(m.playQueued())
+		}
+		return nil
+	}
+	return m.SelectItem()
+}
+
+// paneStyle is the border around a pane of the split layout, the focused one
+// has the border of the selected item.
+func (m *mainModel) paneStyle(focused bool) lipgloss.Style {
+	style := lipgloss.NewStyle()
+	if m.theme.border == (lipgloss.Border{}) {
+		return style
+	}
+	color := m.theme.borderColor
+	if focused {
+		color = m.theme.selectedBorder
+	}
+	return style.Border(m.theme.border).BorderForeground(themeColor(color))
+}
+
+// layout sizes the list, or the panes of the split layout, to the window.
+func (m *mainModel) layout() {
+	h, v := DocStyle.GetFrameSize()
+	m.split = !config.Values.Layout.Single && m.width-h >= splitWidth
+	if !m.split {
+		m.focus = paneContent
+		height := m.height - v - 1
+		m.showPanel = !config.Values.NowPlaying.Hidden && height-panelHeight >= 8
+		if m.showPanel {
+			height -= panelHeight
+		}
+		m.list.SetSize(m.width-h, height)
+		return
+	}
+	frameW, frameV := m.paneStyle(false).GetFrameSize()
+	width := m.width - DocStyle.GetHorizontalMargins()
+	height := m.height - DocStyle.GetVerticalMargins() - 1
+	m.showPanel = !config.Values.NowPlaying.Hidden && height-frameV-panelHeight >= 8
+	if m.showPanel {
+		height -= panelHeight
+	}
+	m.treeWidth = min(max(width/5, 20), 36)
+	m.sideWidth = 0
+	if m.sidePane != sideNone {
+		m.sideWidth = min(max(width/4, 24), 48)
+	} else if m.focus == paneSide {
+		m.focus = paneContent
+	}
+	m.contentWidth = width - m.treeWidth - m.sideWidth
+	m.bodyHeight = height
+	m.tree.SetSize(m.treeWidth-frameW, height-frameV)
+	m.list.SetSize(m.contentWidth-frameW, height-frameV)
+	m.side.SetSize(max(m.sideWidth-frameW, 0), height-frameV)
+}
+
+// splitView puts the tree, the content and the side pane next to each other.
+func (m *mainModel) splitView() string {
+	frameW, frameV := m.paneStyle(false).GetFrameSize()
+	box := func(p pane, view string, width int) string {
+		return m.paneStyle(m.focus == p).Width(width - frameW).Height(m.bodyHeight - frameV).
+			MaxHeight(m.bodyHeight).Render(view)
+	}
+	panes := []string{box(paneTree, m.tree.View(), m.treeWidth), box(paneContent, m.list.View(), m.contentWidth)}
+	switch m.sidePane {
+	case sideQueue:
+		panes = append(panes, box(paneSide, m.side.View(), m.sideWidth))
+	case sideLyrics:
+		panes = append(panes, box(paneSide, m.lyricsView(m.sideWidth-frameW, m.bodyHeight-frameV), m.sideWidth))
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, panes...)
+}
+
+// paneAt is the pane at a column of the screen.
+func (m *mainModel) paneAt(x int) pane {
+	if !m.split {
+		return paneContent
+	}
+	x -= DocStyle.GetMarginLeft()
+	switch {
+	case x < m.treeWidth:
+		return paneTree
+	case x < m.treeWidth+m.contentWidth:
+		return paneContent
+	}
+	return paneSide
+}
+
+// contentOrigin is the screen cell the content list starts at.
+func (m *mainModel) contentOrigin() (int, int) {
+	if !m.split {
+		return origin()
+	}
+	style := m.paneStyle(false)
+	return DocStyle.GetMarginLeft() + m.treeWidth + style.GetBorderLeftSize(),
+		DocStyle.GetMarginTop() + style.GetBorderTopSize()
+}
+
+// panelOrigin is the screen cell the now playing panel starts at.
+func (m *mainModel) panelOrigin() (int, int) {
+	if !m.split {
+		col, row := origin()
+		return col, row + lipgloss.Height(m.list.View()) + 1
+	}
+	return DocStyle.GetMarginLeft(), DocStyle.GetMarginTop() + m.bodyHeight + 1
+}
+
+// panelWidth is the width of the now playing panel.
+func (m *mainModel) panelWidth() int {
+	if !m.split {
+		return m.list.Width()
+	}
+	return m.width - DocStyle.GetHorizontalMargins()
+}
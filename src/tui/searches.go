@@ -0,0 +1,107 @@
+package tui
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// maxSearchHistory is how many past searches are remembered.
+const maxSearchHistory = 100
+
+// searchStore keeps past and pinned searches in searches.json next to the
+// config. Pinned searches are listed in the main view. It is loaded by
+// InitMain, changed by Update and saved in a command.
+type searchStore struct {
+	mu      sync.Mutex
+	History []string `json:"history"`
+	Pinned  []string `json:"pinned"`
+}
+
+// searchQuery is a list entry that runs a past or pinned search.
+type searchQuery string
+
+// recentSearches is the main view entry for the search history.
+type recentSearches struct{}
+
+func searchesPath() string {
+	configDir, _ := os.UserConfigDir()
+	return filepath.Join(configDir, "gospt/searches.json")
+}
+
+func loadSearches() *searchStore {
+	store := &searchStore{}
+	data, err := os.ReadFile(searchesPath())
+	if err != nil {
+		return store
+	}
+	json.Unmarshal(data, store)
+	return store
+}
+
+func (s *searchStore) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(searchesPath()), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(searchesPath(), data, 0o600)
+}
+
+func without(list []string, query string) []string {
+	out := []string{}
+	for _, item := range list {
+		if item != query {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// add puts a search at the front of the history.
+func (s *searchStore) add(query string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.History = append([]string{query}, without(s.History, query)...)
+	if len(s.History) > maxSearchHistory {
+		s.History = s.History[:maxSearchHistory]
+	}
+}
+
+// togglePin pins or unpins a search and reports whether it is pinned now.
+func (s *searchStore) togglePin(query string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pinned := without(s.Pinned, query)
+	if len(pinned) == len(s.Pinned) {
+		pinned = append(pinned, query)
+	}
+	s.Pinned = pinned
+	return len(pinned) > 0 && pinned[len(pinned)-1] == query
+}
+
+func (s *searchStore) history() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.History...)
+}
+
+func (s *searchStore) pinned() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.Pinned...)
+}
+
+func (s *searchStore) isPinned(query string) bool {
+	for _, pinned := range s.pinned() {
+		if pinned == query {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,273 @@
+package tui
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/zmb3/spotify/v2"
+)
+
+// sortBy is what a list is sorted by, the empty one keeps the API order.
+type sortBy string
+
+const (
+	sortAPI        sortBy = ""
+	sortName       sortBy = "name"
+	sortArtist     sortBy = "artist"
+	sortAlbum      sortBy = "album"
+	sortAdded      sortBy = "date added"
+	sortDuration   sortBy = "duration"
+	sortPopularity sortBy = "popularity"
+	sortReleased   sortBy = "release date"
+)
+
+type sortOrder struct {
+	By   sortBy `json:"by"`
+	Desc bool   `json:"desc"`
+}
+
+func (o sortOrder) String() string {
+	if o.By == sortAPI {
+		return "spotify's order"
+	}
+	if o.Desc {
+		return string(o.By) + " descending"
+	}
+	return string(o.By) + " ascending"
+}
+
+// sortFields are the orders each view can be sorted in, in the order the sort
+// key cycles through them.
+func sortFields(mode Mode) []sortBy {
+	switch mode {
+	case Tracks, Playlist, SearchPlaylist:
+		return []sortBy{sortAPI, sortName, sortArtist, sortAlbum, sortAdded, sortDuration, sortPopularity, sortReleased}
+	case SearchTracks:
+		return []sortBy{sortAPI, sortName, sortArtist, sortAlbum, sortDuration, sortPopularity, sortReleased}
+	case Albums, SearchAlbums, ArtistSection, SearchArtistSection:
+		return []sortBy{sortAPI, sortName, sortArtist, sortReleased}
+	case SearchPlaylists:
+		return []sortBy{sortAPI, sortName}
+	}
+	return nil
+}
+
+// sortStore keeps the sort order of each view in sorts.json next to the
+// config. It is loaded by InitMain, changed by Update and saved in a command.
+type sortStore struct {
+	mu    sync.Mutex
+	Modes map[Mode]sortOrder `json:"modes"`
+}
+
+func sortsPath() string {
+	configDir, _ := os.UserConfigDir()
+	return filepath.Join(configDir, "gospt/sorts.json")
+}
+
+func loadSorts() *sortStore {
+	store := &sortStore{Modes: map[Mode]sortOrder{}}
+	data, err := os.ReadFile(sortsPath())
+	if err != nil {
+		return store
+	}
+	json.Unmarshal(data, store)
+	if store.Modes == nil {
+		store.Modes = map[Mode]sortOrder{}
+	}
+	return store
+}
+
+func (s *sortStore) get(mode Mode) sortOrder {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Modes[mode]
+}
+
+func (s *sortStore) set(mode Mode, order sortOrder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Modes[mode] = order
+}
+
+func (s *sortStore) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(sortsPath()), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(sortsPath(), data, 0o600)
+}
+
+// track is the full track behind an item, if there is one.
+func (i mainItem) track() *spotify.FullTrack {
+	switch item := i.SpotifyItem.(type) {
+	case spotify.FullTrack:
+		return &item
+	case spotify.SavedTrack:
+		return &item.FullTrack
+	case spotify.PlaylistTrack:
+		return &item.Track
+	case spotify.PlaylistItem:
+		return item.Track.Track
+	}
+	return nil
+}
+
+// sortKey is the value an item is sorted on, a string or an int.
+func (i mainItem) sortKey(by sortBy) any {
+	track := i.track()
+	switch by {
+	case sortName:
+		return strings.ToLower(i.Name)
+	case sortArtist:
+		if album, ok := i.SpotifyItem.(spotify.SimpleAlbum); ok && len(album.Artists) > 0 {
+			return strings.ToLower(album.Artists[0].Name)
+		}
+		return strings.ToLower(i.Artist.Name)
+	case sortAlbum:
+		return strings.ToLower(i.album())
+	case sortAdded:
+		switch item := i.SpotifyItem.(type) {
+		case spotify.SavedTrack:
+			return item.AddedAt
+		case spotify.PlaylistTrack:
+			return item.AddedAt
+		case spotify.PlaylistItem:
+			return item.AddedAt
+		}
+		return ""
+	case sortDuration:
+		if track != nil {
+			return int(track.Duration)
+		}
+		return 0
+	case sortPopularity:
+		if track != nil {
+			return int(track.Popularity)
+		}
+		return 0
+	case sortReleased:
+		if album, ok := i.SpotifyItem.(spotify.SimpleAlbum); ok {
+			return album.ReleaseDate
+		}
+		if track != nil {
+			return track.Album.ReleaseDate
+		}
+		return ""
+	}
+	return i.Order
+}
+
+func less(a, b any) bool {
+	switch a := a.(type) {
+	case int:
+		return a < b.(int)
+	case string:
+		return a < b.(string)
+	}
+	return false
+}
+
+// sortItems sorts a list in the given order, each item remembers where it was
+// in spotify's order so it can be played by position and put back.
+func sortItems(items []list.Item, order sortOrder) []list.Item {
+	sorted := make([]list.Item, len(items))
+	copy(sorted, items)
+	for index, item := range sorted {
+		if item, ok := item.(mainItem); ok && item.Order == 0 {
+			// items loaded since the last sort follow every item before them
+			item.Order = index + 1
+			sorted[index] = item
+		}
+	}
+	by := order.By
+	if by == sortAPI {
+		order.Desc = false
+	}
+	sort.SliceStable(sorted, func(x, y int) bool {
+		a, aok := sorted[x].(mainItem)
+		b, bok := sorted[y].(mainItem)
+		if !aok || !bok {
+			return false
+		}
+		if order.Desc {
+			return less(b.sortKey(by), a.sortKey(by))
+		}
+		return less(a.sortKey(by), b.sortKey(by))
+	})
+	return sorted
+}
+
+// CycleSort sorts the view by the next field, or flips the order.
+func (m *mainModel) CycleSort(flip bool) {
+	fields := sortFields(m.mode)
+	if len(fields) == 0 {
+		return
+	}
+	order := m.sorts.get(m.mode)
+	if flip {
+		order.Desc = !order.Desc
+	} else {
+		next := 0
+		for index, field := range fields {
+			if field == order.By {
+				next = (index + 1) % len(fields)
+			}
+		}
+		order.By = fields[next]
+	}
+	m.sorts.set(m.mode, order)
+	m.after(background(m.sorts.save))
+	m.applySort(order)
+	if order.By != sortAPI {
+		m.after(m.loadAll())
+	}
+	m.SendMessage("Sorted by "+order.String(), 2*time.Second)
+}
+
+func (m *mainModel) applySort(order sortOrder) {
+	m.sorted = len(m.list.Items())
+	m.refilter(m.list.SetItems(sortItems(m.list.Items(), order)))
+}
+
+// syncSort sorts a view when it is opened and again as more of it loads, a
+// sorted view is loaded in full so the first items are the first of all of it.
+func (m *mainModel) syncSort() tea.Cmd {
+	if m.waiting {
+		return nil
+	}
+	order := m.sorts.get(m.mode)
+	if order.By == sortAPI || sortFields(m.mode) == nil {
+		m.sortMode = m.mode
+		return nil
+	}
+	var cmd tea.Cmd
+	if m.mode != m.sortMode {
+		m.sortMode = m.mode
+		m.sorted = 0
+		cmd = m.loadAll()
+	}
+	if len(m.list.Items()) != m.sorted {
+		m.applySort(order)
+	}
+	return cmd
+}
+
+// apiPosition is where the selected item is in spotify's order.
+func (m *mainModel) apiPosition() int {
+	if item, ok := m.list.SelectedItem().(mainItem); ok && item.Order > 0 {
+		return item.Order - 1
+	}
+	return m.itemIndex()
+}
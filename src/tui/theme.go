@@ -0,0 +1,185 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/lipgloss"
+
+	"git.asdf.cafe/abs3nt/gospt/src/config"
+)
+
+// theme holds the colors of the TUI, an empty color uses the terminal's own.
+type theme struct {
+	border          lipgloss.Border
+	borderColor     string
+	title           string
+	titleBackground string
+	text            string
+	description     string
+	selected        string
+	selectedDesc    string
+	selectedBorder  string
+	selectedBold    bool
+	dimmed          string
+	status          string
+	progressStart   string
+	progressEnd     string
+	progressEmpty   string
+}
+
+var themes = map[string]theme{
+	"dark": {
+		border:          lipgloss.DoubleBorder(),
+		title:           "#FFFDF5",
+		titleBackground: "#5A56E0",
+		text:            "#DDDDDD",
+		description:     "#777777",
+		selected:        "#EE6FF8",
+		selectedDesc:    "#AD58B4",
+		selectedBorder:  "#AD58B4",
+		dimmed:          "#4D4D4D",
+		status:          "#777777",
+		progressStart:   "#5A56E0",
+		progressEnd:     "#EE6FF8",
+		progressEmpty:   "#606060",
+	},
+	"light": {
+		border:          lipgloss.DoubleBorder(),
+		title:           "#FFFDF5",
+		titleBackground: "#5A56E0",
+		text:            "#1A1A1A",
+		description:     "#A49FA5",
+		selected:        "#EE6FF8",
+		selectedDesc:    "#F793FF",
+		selectedBorder:  "#F793FF",
+		dimmed:          "#C2B8C2",
+		status:          "#A49FA5",
+		progressStart:   "#5A56E0",
+		progressEnd:     "#EE6FF8",
+		progressEmpty:   "#C2B8C2",
+	},
+}
+
+var borders = map[string]lipgloss.Border{
+	"double":  lipgloss.DoubleBorder(),
+	"rounded": lipgloss.RoundedBorder(),
+	"normal":  lipgloss.NormalBorder(),
+	"thick":   lipgloss.ThickBorder(),
+	"block":   lipgloss.BlockBorder(),
+	"hidden":  lipgloss.HiddenBorder(),
+	"none":    {},
+}
+
+// loadTheme picks the dark or light preset, by the terminal's background
+// unless one is set, and applies the colors from the theme section of the
+// config. NO_COLOR turns colors off but keeps the borders.
+func loadTheme() (theme, error) {
+	cfg := config.Values.Theme
+	preset := strings.ToLower(cfg.Preset)
+	if preset == "" || preset == "auto" {
+		preset = "light"
+		if lipgloss.HasDarkBackground() {
+			preset = "dark"
+		}
+	}
+	t, ok := themes[preset]
+	if !ok {
+		return theme{}, fmt.Errorf("unknown theme preset %q, use auto, dark or light", cfg.Preset)
+	}
+	if cfg.Border != "" {
+		border, ok := borders[strings.ToLower(cfg.Border)]
+		if !ok {
+			return theme{}, fmt.Errorf("unknown border %q, use double, rounded, normal, thick, block, hidden or none", cfg.Border)
+		}
+		t.border = border
+	}
+	for _, c := range []struct {
+		value string
+		field *string
+	}{
+		{cfg.BorderColor, &t.borderColor},
+		{cfg.Title, &t.title},
+		{cfg.TitleBackground, &t.titleBackground},
+		{cfg.Text, &t.text},
+		{cfg.Description, &t.description},
+		{cfg.Selected, &t.selected},
+		{cfg.SelectedDesc, &t.selectedDesc},
+		{cfg.SelectedBorder, &t.selectedBorder},
+		{cfg.Dimmed, &t.dimmed},
+		{cfg.Status, &t.status},
+		{cfg.ProgressStart, &t.progressStart},
+		{cfg.ProgressEnd, &t.progressEnd},
+		{cfg.ProgressEmpty, &t.progressEmpty},
+	} {
+		if c.value != "" {
+			*c.field = c.value
+		}
+	}
+	t.selectedBold = cfg.SelectedBold
+	if os.Getenv("NO_COLOR") != "" {
+		t = t.withoutColor()
+	}
+	return t, nil
+}
+
+// withoutColor drops every color, the selected item keeps its border and is
+// shown in bold so it can still be told apart.
+func (t theme) withoutColor() theme {
+	return theme{border: t.border, selectedBold: true}
+}
+
+func themeColor(c string) lipgloss.TerminalColor {
+	if c == "" {
+		return lipgloss.NoColor{}
+	}
+	return lipgloss.Color(c)
+}
+
+func (t theme) docStyle() lipgloss.Style {
+	style := lipgloss.NewStyle().Margin(0, 2)
+	if t.border == (lipgloss.Border{}) {
+		return style
+	}
+	return style.Border(t.border, true, true, true, true).BorderForeground(themeColor(t.borderColor))
+}
+
+func (t theme) delegate() list.DefaultDelegate {
+	delegate := list.NewDefaultDelegate()
+	s := &delegate.Styles
+	s.NormalTitle = s.NormalTitle.Foreground(themeColor(t.text))
+	s.NormalDesc = s.NormalDesc.Foreground(themeColor(t.description))
+	s.SelectedTitle = s.SelectedTitle.Foreground(themeColor(t.selected)).
+		BorderForeground(themeColor(t.selectedBorder)).Bold(t.selectedBold)
+	s.SelectedDesc = s.SelectedDesc.Foreground(themeColor(t.selectedDesc)).
+		BorderForeground(themeColor(t.selectedBorder))
+	s.DimmedTitle = s.DimmedTitle.Foreground(themeColor(t.description))
+	s.DimmedDesc = s.DimmedDesc.Foreground(themeColor(t.dimmed))
+	s.FilterMatch = s.FilterMatch.Bold(true)
+	return delegate
+}
+
+// apply styles the list's title and status bar.
+func (t theme) apply(l *list.Model) {
+	l.Styles.Title = l.Styles.Title.Foreground(themeColor(t.title)).Background(themeColor(t.titleBackground))
+	l.Styles.StatusBar = l.Styles.StatusBar.Foreground(themeColor(t.status))
+	l.Styles.StatusEmpty = l.Styles.StatusEmpty.Foreground(themeColor(t.status))
+	l.Styles.NoItems = l.Styles.NoItems.Foreground(themeColor(t.status))
+}
+
+func (t theme) progress() progress.Model {
+	opts := []progress.Option{progress.WithColorProfile(lipgloss.ColorProfile()), progress.WithoutPercentage()}
+	if t.progressStart != "" && t.progressEnd != "" {
+		opts = append(opts, progress.WithGradient(t.progressStart, t.progressEnd))
+	} else if t.progressStart != "" {
+		opts = append(opts, progress.WithSolidFill(t.progressStart))
+	}
+	prog := progress.New(opts...)
+	if t.progressEmpty != "" {
+		prog.EmptyColor = t.progressEmpty
+	}
+	return prog
+}
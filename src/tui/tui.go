@@ -1,6 +1,8 @@
 package tui
 
 import (
+	"os"
+
 	"git.asdf.cafe/abs3nt/gospt/src/commands"
 	"git.asdf.cafe/abs3nt/gospt/src/gctx"
 
@@ -13,9 +15,20 @@ func StartTea(ctx *gctx.Context, cmd *commands.Commands, mode string) error {
 	if err != nil {
 		return err
 	}
-	P = tea.NewProgram(m, tea.WithAltScreen())
-	if _, err := P.Run(); err != nil {
-		return err
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion())
+	_, err = p.Run()
+	if main, ok := m.(*mainModel); ok {
+		if main.artProtocol == artKitty {
+			os.Stdout.WriteString(kittyDelete())
+		}
+		main.feeder.Close()
+		if closeErr := main.scrobbler.Close(); closeErr != nil {
+			ctx.Debug.Trace().Err(closeErr).Msg("failed to close scrobbler")
+		}
+		// the listened time of the track playing at exit is only in memory
+		if closeErr := main.history.Close(); closeErr != nil {
+			ctx.Debug.Trace().Err(closeErr).Msg("failed to close listening history")
+		}
 	}
-	return nil
+	return err
 }
@@ -0,0 +1,334 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/zmb3/spotify/v2"
+
+	"git.asdf.cafe/abs3nt/gospt/src/commands"
+	"git.asdf.cafe/abs3nt/gospt/src/config"
+	"git.asdf.cafe/abs3nt/gospt/src/gctx"
+)
+
+const playingJSON = `{"is_playing":true,"progress_ms":1000,"item":{"id":"t1","name":"Airbag","type":"track","duration_ms":284000,
+	"artists":[{"id":"a1","name":"Radiohead"}],"album":{"id":"al1","name":"OK Computer"}}}`
+
+// fakeSpotify answers the player endpoints the tests use and counts the
+// requests it gets. Listing devices waits for a token on devices.
+type fakeSpotify struct {
+	mu       sync.Mutex
+	requests map[string]int
+	devices  chan struct{}
+}
+
+func (f *fakeSpotify) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests[r.Method+" "+r.URL.Path]++
+	f.mu.Unlock()
+	switch r.Method + " " + r.URL.Path {
+	case "GET /me/player/devices":
+		<-f.devices
+		w.Write([]byte(`{"devices":[{"id":"d1","is_active":true,"name":"Kitchen","type":"Speaker","volume_percent":50}]}`))
+	case "GET /me/player", "GET /me/player/currently-playing":
+		w.Write([]byte(playingJSON))
+	case "GET /me/playlists":
+		w.Write([]byte(`{"items":[{"id":"p1","name":"Mix"}],"total":1}`))
+	case "GET /me/following":
+		// followed artists are paged by cursor, offsets are ignored
+		if r.URL.Query().Get("after") == "" {
+			w.Write([]byte(`{"artists":{"items":[{"id":"a1","name":"Radiohead"},{"id":"a2","name":"Portishead"}],"cursors":{"after":"a2"},"total":3}}`))
+			return
+		}
+		w.Write([]byte(`{"artists":{"items":[{"id":"a3","name":"Massive Attack"}],"cursors":{},"total":3}}`))
+	case "PUT /me/player/seek":
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"status":404,"message":"no active device"}}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeSpotify) count(request string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[request]
+}
+
+// newTestModel starts the TUI on the device list of a fake spotify, with
+// the config and the listening history in a temporary directory.
+func newTestModel(t *testing.T) (*mainModel, *fakeSpotify) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	art := config.Values.NowPlaying.AlbumArt
+	config.Values.NowPlaying.AlbumArt = "none"
+	t.Cleanup(func() { config.Values.NowPlaying.AlbumArt = art })
+
+	fake := &fakeSpotify{requests: map[string]int{}, devices: make(chan struct{}, 1)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	client := spotify.New(server.Client(), spotify.WithBaseURL(server.URL+"/"))
+	ctx := gctx.NewContext(context.Background())
+
+	fake.devices <- struct{}{}
+	model, err := InitMain(ctx, commands.NewWithClient(ctx, client), Devices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := model.(*mainModel)
+	t.Cleanup(func() {
+		m.history.Close()
+		m.scrobbler.Close()
+		m.feeder.Close()
+	})
+	return m, fake
+}
+
+// await runs cmd and the commands it batches until one of them returns a T,
+// the others are left running.
+func await[T tea.Msg](t *testing.T, cmd tea.Cmd) T {
+	t.Helper()
+	msgs := make(chan tea.Msg, 100)
+	var run func(cmd tea.Cmd)
+	run = func(cmd tea.Cmd) {
+		if cmd == nil {
+			return
+		}
+		go func() {
+			msg := cmd()
+			if batch, ok := msg.(tea.BatchMsg); ok {
+				for _, cmd := range batch {
+					run(cmd)
+				}
+				return
+			}
+			msgs <- msg
+		}()
+	}
+	run(cmd)
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-msgs:
+			if msg, ok := msg.(T); ok {
+				return msg
+			}
+		case <-timeout:
+			var zero T
+			t.Fatalf("no %T within 5s", zero)
+			return zero
+		}
+	}
+}
+
+// finish runs cmd and the commands it batches and waits for all of them.
+func finish(t *testing.T, cmd tea.Cmd) {
+	t.Helper()
+	var wg sync.WaitGroup
+	var run func(cmd tea.Cmd)
+	run = func(cmd tea.Cmd) {
+		if cmd == nil {
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if batch, ok := cmd().(tea.BatchMsg); ok {
+				for _, cmd := range batch {
+					run(cmd)
+				}
+			}
+		}()
+	}
+	run(cmd)
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("commands still running after 5s")
+	}
+}
+
+func keyPress(keys string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(keys)}
+}
+
+func TestUpdateOpensViewInCommand(t *testing.T) {
+	m, fake := newTestModel(t)
+
+	// the device list is only fetched once the command runs, fetching it in
+	// Update would block here
+	_, cmd := m.Update(keyPress("d"))
+	if m.mode != Devices || len(m.stack) != 1 || !m.waiting || len(m.list.Items()) != 0 {
+		t.Fatalf("mode = %s, stack = %d, waiting = %t, items = %d", m.mode, len(m.stack), m.waiting, len(m.list.Items()))
+	}
+	// the empty list does not ask for more pages while it waits
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 40})
+	if m.loading {
+		t.Fatal("loading a page before the first items arrived")
+	}
+
+	fake.devices <- struct{}{}
+	msg := await[itemsMsg](t, cmd)
+	m.Update(itemsMsg{view: msg.view - 1, items: []list.Item{mainItem{Name: "stale"}}})
+	if !m.waiting || len(m.list.Items()) != 0 {
+		t.Fatal("items of an earlier view were shown")
+	}
+	m.Update(msg)
+	if m.waiting || len(m.list.Items()) != 1 || m.list.Items()[0].(mainItem).Name != "Kitchen" {
+		t.Fatalf("waiting = %t, items = %v", m.waiting, m.list.Items())
+	}
+
+	m.Update(keyPress("q"))
+	if m.mode != Devices || len(m.stack) != 0 || len(m.list.Items()) != 1 {
+		t.Fatalf("back: mode = %s, stack = %d, items = %d", m.mode, len(m.stack), len(m.list.Items()))
+	}
+}
+
+func TestUpdatePlayback(t *testing.T) {
+	m, _ := newTestModel(t)
+
+	// Update keeps changing the model while the poll runs
+	polled := make(chan tea.Msg)
+	go func() { polled <- m.pollPlayback(0)() }()
+	for i := 0; i < 5; i++ {
+		m.Update(keyPress("j"))
+		m.Update(tea.WindowSizeMsg{Width: 80 + i, Height: 40})
+	}
+	msg, ok := (<-polled).(playbackMsg)
+	if !ok || msg.state == nil {
+		t.Fatalf("poll = %+v", msg)
+	}
+	m.Update(msg)
+	if m.playing == nil || m.playing.Item.Name != "Airbag" || m.state.Progress != 1000 {
+		t.Fatalf("playing = %+v", m.playing)
+	}
+
+	// a failed poll keeps what was playing
+	m.Update(playbackMsg{})
+	if m.state == nil || m.playing == nil {
+		t.Fatal("failed poll dropped the player state")
+	}
+}
+
+func TestUpdatePages(t *testing.T) {
+	m, _ := newTestModel(t)
+	page := []list.Item{mainItem{Name: "Desk"}, mainItem{Name: "Phone"}}
+
+	m.loading = true
+	m.Update(pageMsg{view: m.view - 1, items: page})
+	if !m.loading || len(m.list.Items()) != 1 {
+		t.Fatal("page of an earlier view was added")
+	}
+	m.Update(pageMsg{view: m.view, err: errors.New("rate limited")})
+	if m.loading || m.page != 1 || len(m.list.Items()) != 1 {
+		t.Fatalf("failed page: loading = %t, page = %d", m.loading, m.page)
+	}
+	m.loading = true
+	m.Update(pageMsg{view: m.view, items: page})
+	if m.loading || m.page != 2 || !m.lastPage || len(m.list.Items()) != 3 {
+		t.Fatalf("loading = %t, page = %d, last = %t, items = %d", m.loading, m.page, m.lastPage, len(m.list.Items()))
+	}
+}
+
+func TestUpdateSeekErrorIsShown(t *testing.T) {
+	m, fake := newTestModel(t)
+
+	_, cmd := m.Update(keyPress(">"))
+	if fake.count("PUT /me/player/seek") != 0 {
+		t.Fatal("seeked in Update")
+	}
+	msg := await[errMsg](t, cmd)
+	if fake.count("PUT /me/player/seek") != 1 {
+		t.Fatal("the command did not seek")
+	}
+	m.Update(msg)
+	if !m.messageUntil.After(time.Now()) {
+		t.Fatalf("error %v was not shown", msg.err)
+	}
+}
+
+func TestUpdateWritesFilesInCommands(t *testing.T) {
+	m, _ := newTestModel(t)
+	exists := func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}
+
+	m.mode = SearchPlaylists
+	m.list.SetItems([]list.Item{mainItem{Name: "b"}, mainItem{Name: "a"}})
+	_, cmd := m.Update(keyPress("o"))
+	if m.sorts.get(SearchPlaylists).By != sortName || m.list.Items()[0].(mainItem).Name != "a" {
+		t.Fatal("the view was not sorted by name")
+	}
+	if exists(sortsPath()) {
+		t.Fatal("sorts.json was written in Update")
+	}
+	finish(t, cmd)
+	if !exists(sortsPath()) {
+		t.Fatal("sorts.json was not written")
+	}
+
+	m.mode = Show
+	episode := spotify.EpisodePage{ID: "e1", Name: "Pilot", Duration_ms: 60000}
+	m.list.SetItems([]list.Item{episodeItem(m.commands, spotify.SimpleShow{}, episode)})
+	_, cmd = m.Update(keyPress("m"))
+	msg := await[playedMsg](t, cmd)
+	m.Update(msg)
+	if !m.commands.EpisodePlayed(&episode) || !strings.HasSuffix(m.list.Items()[0].(mainItem).Desc, ", played") {
+		t.Fatalf("episode not shown as played: %+v", m.list.Items()[0])
+	}
+}
+
+func TestModelsDoNotShareState(t *testing.T) {
+	first, _ := newTestModel(t)
+	second, _ := newTestModel(t)
+	first.searches.add("radiohead")
+	first.sorts.set(Tracks, sortOrder{By: sortName})
+	if len(second.searches.history()) != 0 || second.sorts.get(Tracks).By != sortAPI {
+		t.Fatal("a second model sees the searches and sorts of the first")
+	}
+}
+
+func TestLibraryLoadsOpenSections(t *testing.T) {
+	m, fake := newTestModel(t)
+
+	// nothing is fetched for the single layout
+	finish(t, m.Init())
+	if fake.count("GET /me/playlists") != 0 {
+		t.Fatal("the library was loaded without the tree")
+	}
+
+	// the split layout loads the sections that are open
+	_, cmd := m.Update(tea.WindowSizeMsg{Width: 160, Height: 50})
+	msg := await[libraryMsg](t, cmd)
+	m.Update(msg)
+	if msg.section != "Playlists" || len(m.library["Playlists"]) != 1 || fake.count("GET /me/following") != 0 {
+		t.Fatalf("loaded %s, library %v", msg.section, m.library)
+	}
+
+	// and the others once they are opened
+	m.tree.Select(3)
+	if header, ok := m.tree.SelectedItem().(treeItem); !ok || !header.header || header.section != "Artists" {
+		t.Fatalf("selected %v", m.tree.SelectedItem())
+	}
+	m.selectTree()
+	// selectTree queues its command for the next Update to return
+	_, cmd = m.Update(nil)
+	m.Update(await[libraryMsg](t, cmd))
+	if artists := m.library["Artists"]; len(artists) != 3 || fake.count("GET /me/following") != 2 {
+		t.Fatalf("artists %v after %d requests", artists, fake.count("GET /me/following"))
+	}
+}
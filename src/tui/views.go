@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"git.asdf.cafe/abs3nt/gospt/src/commands"
+	"git.asdf.cafe/abs3nt/gospt/src/config"
 	"git.asdf.cafe/abs3nt/gospt/src/gctx"
 	"golang.org/x/sync/errgroup"
 
@@ -38,28 +39,45 @@ func QueueView(ctx *gctx.Context, commands *commands.Commands) ([]list.Item, err
 		return nil, err
 	}
 	if tracks.CurrentlyPlaying.Name != "" {
+		items = append(items, queueTrackItem(tracks.CurrentlyPlaying))
+	}
+	queued, err := commands.LoadQueue()
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range queued {
+		duration := (time.Duration(item.Duration) * time.Millisecond).Round(time.Second).String()
 		items = append(items, mainItem{
-			Name:        tracks.CurrentlyPlaying.Name,
-			Artist:      tracks.CurrentlyPlaying.Artists[0],
-			Duration:    tracks.CurrentlyPlaying.TimeDuration().Round(time.Second).String(),
-			ID:          tracks.CurrentlyPlaying.ID,
-			Desc:        tracks.CurrentlyPlaying.Artists[0].Name + " - " + tracks.CurrentlyPlaying.TimeDuration().Round(time.Second).String(),
-			SpotifyItem: tracks.CurrentlyPlaying,
+			Name:        item.Name,
+			Duration:    duration,
+			ID:          item.ID,
+			Desc:        item.Artist + " - " + duration + " - gospt queue",
+			SpotifyItem: item,
 		})
 	}
 	for _, track := range tracks.Items {
-		items = append(items, mainItem{
-			Name:        track.Name,
-			Artist:      track.Artists[0],
-			Duration:    track.TimeDuration().Round(time.Second).String(),
-			ID:          track.ID,
-			Desc:        track.Artists[0].Name + " - " + track.TimeDuration().Round(time.Second).String(),
-			SpotifyItem: track,
-		})
+		items = append(items, queueTrackItem(track))
 	}
 	return items, nil
 }
 
+// queueTrackItem lists a track of spotify's queue, episodes in the queue come
+// without artists.
+func queueTrackItem(track spotify.FullTrack) mainItem {
+	item := mainItem{
+		Name:        track.Name,
+		Duration:    track.TimeDuration().Round(time.Second).String(),
+		ID:          track.ID,
+		Desc:        track.TimeDuration().Round(time.Second).String(),
+		SpotifyItem: track,
+	}
+	if len(track.Artists) > 0 {
+		item.Artist = track.Artists[0]
+		item.Desc = track.Artists[0].Name + " - " + item.Desc
+	}
+	return item
+}
+
 func PlaylistView(ctx *gctx.Context, commands *commands.Commands, playlist spotify.SimplePlaylist) ([]list.Item, error) {
 	items := []list.Item{}
 	playlistItems, err := commands.PlaylistTracks(ctx, playlist.ID, 1)
@@ -67,21 +85,69 @@ func PlaylistView(ctx *gctx.Context, commands *commands.Commands, playlist spoti
 		return nil, err
 	}
 	for _, item := range playlistItems.Items {
-		items = append(items, mainItem{
-			Name:        item.Track.Track.Name,
-			Artist:      item.Track.Track.Artists[0],
-			Duration:    item.Track.Track.TimeDuration().Round(time.Second).String(),
-			ID:          item.Track.Track.ID,
-			Desc:        item.Track.Track.Artists[0].Name + " - " + item.Track.Track.TimeDuration().Round(time.Second).String(),
-			SpotifyItem: item,
-		})
+		items = append(items, playlistItem(item))
 	}
 	return items, nil
 }
 
+// playlistItem lists a track or an episode of a playlist. Playlists are played
+// by position, so unplayable and unavailable items stay listed.
+func playlistItem(item spotify.PlaylistItem) mainItem {
+	switch {
+	case item.Track.Track != nil:
+		track := item.Track.Track
+		listed, _ := markUnplayable(mainItem{
+			Name:        track.Name,
+			Artist:      track.Artists[0],
+			Duration:    track.TimeDuration().Round(time.Second).String(),
+			ID:          track.ID,
+			Desc:        track.Artists[0].Name + " - " + track.TimeDuration().Round(time.Second).String(),
+			SpotifyItem: item,
+		}, track.IsPlayable, false)
+		return listed
+	case item.Track.Episode != nil:
+		episode := item.Track.Episode
+		duration := (time.Duration(episode.Duration_ms) * time.Millisecond).Round(time.Second).String()
+		listed, _ := markUnplayable(mainItem{
+			Name:        episode.Name,
+			Artist:      spotify.SimpleArtist{Name: episode.Show.Name, ID: episode.Show.ID, URI: episode.Show.URI},
+			Duration:    duration,
+			ID:          episode.ID,
+			Desc:        episode.Show.Name + " - " + duration,
+			SpotifyItem: item,
+		}, &episode.IsPlayable, false)
+		return listed
+	}
+	return mainItem{Name: "<unavailable>", Desc: "Unavailable", SpotifyItem: item}
+}
+
+// PlaylistPickerView lists the playlists the user can add tracks to.
+func PlaylistPickerView(ctx *gctx.Context, commands *commands.Commands) ([]list.Item, error) {
+	items := []list.Item{}
+	for page := 1; ; page++ {
+		playlists, err := commands.Playlists(ctx, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, playlist := range playlists.Playlists {
+			if playlist.Owner.ID != commands.User() && !playlist.Collaborative {
+				continue
+			}
+			items = append(items, mainItem{
+				Name:        playlist.Name,
+				Desc:        fmt.Sprintf("Add to %s, %d tracks", playlist.Name, playlist.Tracks.Total),
+				SpotifyItem: playlist,
+			})
+		}
+		if len(playlists.Playlists) < 50 {
+			return items, nil
+		}
+	}
+}
+
 func ArtistsView(ctx *gctx.Context, commands *commands.Commands) ([]list.Item, error) {
 	items := []list.Item{}
-	artists, err := commands.UserArtists(ctx, 1)
+	artists, err := commands.UserArtists(ctx, "")
 	if err != nil {
 		return nil, err
 	}
@@ -109,29 +175,64 @@ func SearchArtistsView(ctx *gctx.Context, commands *commands.Commands, artists *
 	return items, nil
 }
 
-func SearchView(ctx *gctx.Context, commands *commands.Commands, search string) ([]list.Item, *SearchResults, error) {
+func SearchView(ctx *gctx.Context, commands *commands.Commands, query string, types spotify.SearchType) ([]list.Item, *SearchResults, error) {
 	items := []list.Item{}
 
-	result, err := commands.Search(ctx, search, 1)
+	result, err := commands.Search(ctx, query, types, 1)
 	if err != nil {
 		return nil, nil, err
 	}
-	items = append(
-		items,
-		mainItem{Name: "Tracks", Desc: "Search results", SpotifyItem: result.Tracks},
-		mainItem{Name: "Albums", Desc: "Search results", SpotifyItem: result.Albums},
-		mainItem{Name: "Artists", Desc: "Search results", SpotifyItem: result.Artists},
-		mainItem{Name: "Playlists", Desc: "Search results", SpotifyItem: result.Playlists},
-	)
+	if result.Tracks != nil {
+		items = append(items, mainItem{Name: "Tracks", Desc: fmt.Sprintf("%d results", result.Tracks.Total), SpotifyItem: result.Tracks})
+	}
+	if result.Albums != nil {
+		items = append(items, mainItem{Name: "Albums", Desc: fmt.Sprintf("%d results", result.Albums.Total), SpotifyItem: result.Albums})
+	}
+	if result.Artists != nil {
+		items = append(items, mainItem{Name: "Artists", Desc: fmt.Sprintf("%d results", result.Artists.Total), SpotifyItem: result.Artists})
+	}
+	if result.Playlists != nil {
+		items = append(items, mainItem{Name: "Playlists", Desc: fmt.Sprintf("%d results", result.Playlists.Total), SpotifyItem: result.Playlists})
+	}
+	if result.Shows != nil {
+		items = append(items, mainItem{Name: "Shows", Desc: fmt.Sprintf("%d results", result.Shows.Total), SpotifyItem: result.Shows})
+	}
+	if result.Episodes != nil {
+		items = append(items, mainItem{Name: "Episodes", Desc: fmt.Sprintf("%d results", result.Episodes.Total), SpotifyItem: result.Episodes})
+	}
 	results := &SearchResults{
+		Query:     query,
 		Tracks:    result.Tracks,
 		Playlists: result.Playlists,
 		Albums:    result.Albums,
 		Artists:   result.Artists,
+		Shows:     result.Shows,
+		Episodes:  result.Episodes,
 	}
 	return items, results, nil
 }
 
+func SearchShowsView(ctx *gctx.Context, commands *commands.Commands, shows *spotify.SimpleShowPage) ([]list.Item, error) {
+	items := []list.Item{}
+	for _, show := range shows.Shows {
+		items = append(items, mainItem{
+			Name:        show.Name,
+			ID:          show.ID,
+			Desc:        show.Publisher,
+			SpotifyItem: show.SimpleShow,
+		})
+	}
+	return items, nil
+}
+
+func SearchEpisodesView(ctx *gctx.Context, commands *commands.Commands, episodes *spotify.SimpleEpisodePage) ([]list.Item, error) {
+	items := []list.Item{}
+	for _, episode := range episodes.Episodes {
+		items = append(items, episodeItem(commands, episode.Show, episode))
+	}
+	return items, nil
+}
+
 func AlbumsView(ctx *gctx.Context, commands *commands.Commands) ([]list.Item, error) {
 	items := []list.Item{}
 	albums, err := commands.UserAlbums(ctx, 1)
@@ -174,53 +275,254 @@ func SearchAlbumsView(ctx *gctx.Context, commands *commands.Commands, albums *sp
 	return items, nil
 }
 
-func ArtistAlbumsView(ctx *gctx.Context, album spotify.ID, commands *commands.Commands) ([]list.Item, error) {
-	items := []list.Item{}
-	albums, err := commands.ArtistAlbums(ctx, album, 1)
+type sectionKind int
+
+const (
+	topTracksSection sectionKind = iota
+	albumSection
+	relatedSection
+)
+
+// artistSection is an entry of an artist page that opens the top tracks, the
+// related artists or one album type of the artist's discography.
+type artistSection struct {
+	Artist    spotify.SimpleArtist
+	Name      string
+	Kind      sectionKind
+	AlbumType spotify.AlbumType
+}
+
+// artistFollow is the artist page entry that follows or unfollows the artist.
+type artistFollow struct {
+	Artist    spotify.SimpleArtist
+	Following bool
+}
+
+func ArtistView(ctx *gctx.Context, commands *commands.Commands, artist spotify.SimpleArtist, keys keyMap) ([]list.Item, error) {
+	wg := errgroup.Group{}
+	var following bool
+	var top []spotify.FullTrack
+	var related []spotify.FullArtist
+	sections := []artistSection{
+		{Artist: artist, Name: "Albums", Kind: albumSection, AlbumType: spotify.AlbumTypeAlbum},
+		{Artist: artist, Name: "Singles and EPs", Kind: albumSection, AlbumType: spotify.AlbumTypeSingle},
+		{Artist: artist, Name: "Compilations", Kind: albumSection, AlbumType: spotify.AlbumTypeCompilation},
+		{Artist: artist, Name: "Appears On", Kind: albumSection, AlbumType: spotify.AlbumTypeAppearsOn},
+	}
+	totals := make([]int, len(sections))
+
+	wg.Go(func() (err error) {
+		following, err = commands.FollowsArtist(ctx, artist.ID)
+		return
+	})
+
+	wg.Go(func() (err error) {
+		top, err = commands.ArtistTopTracks(ctx, artist.ID)
+		return
+	})
+
+	wg.Go(func() error {
+		// related artists are not available to every app, leave them out then
+		related, _ = commands.RelatedArtists(ctx, artist.ID)
+		return nil
+	})
+
+	for idx, section := range sections {
+		idx, section := idx, section
+		wg.Go(func() error {
+			albums, err := commands.ArtistAlbumsOfType(ctx, artist.ID, section.AlbumType, 1)
+			if err != nil {
+				return err
+			}
+			totals[idx] = int(albums.Total)
+			return nil
+		})
+	}
+
+	err := wg.Wait()
 	if err != nil {
 		return nil, err
 	}
-	for _, album := range albums.Albums {
+
+	items := []list.Item{}
+	follow := mainItem{
+		Name:        "Follow " + artist.Name,
+		Desc:        "Select or press " + keys.label(actionFollow) + " to follow",
+		SpotifyItem: artistFollow{Artist: artist, Following: following},
+	}
+	if following {
+		follow.Name = "Following " + artist.Name
+		follow.Desc = "Select or press " + keys.label(actionFollow) + " to unfollow"
+	}
+	items = append(items, follow)
+	if len(top) != 0 {
 		items = append(items, mainItem{
-			Name:        album.Name,
-			ID:          album.ID,
-			Desc:        fmt.Sprintf("%s by %s", album.AlbumType, album.Artists[0].Name),
-			SpotifyItem: album,
+			Name:        "Top Tracks",
+			Desc:        fmt.Sprintf("%d tracks", len(top)),
+			SpotifyItem: artistSection{Artist: artist, Name: "Top Tracks", Kind: topTracksSection},
 		})
 	}
-	return items, err
+	for idx, section := range sections {
+		if totals[idx] == 0 {
+			continue
+		}
+		items = append(items, mainItem{
+			Name:        section.Name,
+			Desc:        fmt.Sprintf("%d releases", totals[idx]),
+			SpotifyItem: section,
+		})
+	}
+	if len(related) != 0 {
+		items = append(items, mainItem{
+			Name:        "Related Artists",
+			Desc:        fmt.Sprintf("%d artists", len(related)),
+			SpotifyItem: artistSection{Artist: artist, Name: "Related Artists", Kind: relatedSection},
+		})
+	}
+	return items, nil
 }
 
-func AlbumTracksView(ctx *gctx.Context, album spotify.ID, commands *commands.Commands) ([]list.Item, error) {
+func ArtistSectionView(ctx *gctx.Context, commands *commands.Commands, section artistSection) ([]list.Item, error) {
 	items := []list.Item{}
-	tracks, err := commands.AlbumTracks(ctx, album, 1)
+	switch section.Kind {
+	case topTracksSection:
+		tracks, err := commands.ArtistTopTracks(ctx, section.Artist.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, track := range tracks {
+			item, ok := markUnplayable(mainItem{
+				Name:        track.Name,
+				Artist:      track.Artists[0],
+				Duration:    track.TimeDuration().Round(time.Second).String(),
+				ID:          track.ID,
+				Desc:        track.Album.Name + " - " + track.TimeDuration().Round(time.Second).String(),
+				SpotifyItem: track,
+			}, track.IsPlayable, config.Values.HideUnplayable)
+			if ok {
+				items = append(items, item)
+			}
+		}
+	case relatedSection:
+		artists, err := commands.RelatedArtists(ctx, section.Artist.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, artist := range artists {
+			items = append(items, mainItem{
+				Name:        artist.Name,
+				ID:          artist.ID,
+				Desc:        fmt.Sprintf("%d followers", artist.Followers.Count),
+				SpotifyItem: artist,
+			})
+		}
+	case albumSection:
+		albums, err := commands.ArtistAlbumsOfType(ctx, section.Artist.ID, section.AlbumType, 1)
+		if err != nil {
+			return nil, err
+		}
+		for _, album := range albums.Albums {
+			items = append(items, artistAlbumItem(album))
+		}
+	}
+	return items, nil
+}
+
+func artistAlbumItem(album spotify.SimpleAlbum) mainItem {
+	desc := album.ReleaseDate
+	if len(album.Artists) > 0 {
+		desc = fmt.Sprintf("%s by %s, %s", album.AlbumType, album.Artists[0].Name, album.ReleaseDate)
+	}
+	return mainItem{
+		Name:        album.Name,
+		ID:          album.ID,
+		Desc:        desc,
+		SpotifyItem: album,
+	}
+}
+
+// albumSave is the album page entry that saves or unsaves the album.
+type albumSave struct {
+	Album spotify.SimpleAlbum
+	Saved bool
+}
+
+func AlbumView(ctx *gctx.Context, album spotify.ID, commands *commands.Commands, keys keyMap) ([]list.Item, error) {
+	wg := errgroup.Group{}
+	var saved bool
+
+	wg.Go(func() (err error) {
+		saved, err = commands.AlbumSaved(ctx, album)
+		return
+	})
+
+	details, err := commands.Album(ctx, album)
 	if err != nil {
 		return nil, err
 	}
-	for _, track := range tracks.Tracks {
+	err = wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	items := []list.Item{}
+	header := mainItem{
+		Name:        details.Name,
+		ID:          details.ID,
+		Desc:        details.Summary(),
+		SpotifyItem: details.SimpleAlbum,
+	}
+	if len(details.Artists) > 0 {
+		header.Name += " by " + details.Artists[0].Name
+	}
+	items = append(items, header)
+	save := mainItem{
+		Name:        "Save to library",
+		Desc:        "Select or press " + keys.label(actionSaveAlbum) + " to save",
+		SpotifyItem: albumSave{Album: details.SimpleAlbum, Saved: saved},
+	}
+	if saved {
+		save.Name = "Saved in library"
+		save.Desc = "Select or press " + keys.label(actionSaveAlbum) + " to unsave"
+	}
+	items = append(items, save)
+	if copyrights := details.CopyrightText(); copyrights != "" {
 		items = append(items, mainItem{
+			Name:        "Copyright",
+			Desc:        copyrights,
+			SpotifyItem: details.Copyrights,
+		})
+	}
+	for _, track := range details.Tracks.Tracks {
+		item, ok := markUnplayable(mainItem{
 			Name:        track.Name,
 			Artist:      track.Artists[0],
 			Duration:    track.TimeDuration().Round(time.Second).String(),
 			ID:          track.ID,
-			SpotifyItem: track,
+			SpotifyItem: track.SimpleTrack,
 			Desc:        track.Artists[0].Name + " - " + track.TimeDuration().Round(time.Second).String(),
-		})
+		}, track.IsPlayable, config.Values.HideUnplayable)
+		if ok {
+			items = append(items, item)
+		}
 	}
-	return items, err
+	return items, nil
 }
 
 func SearchTracksView(ctx *gctx.Context, commands *commands.Commands, tracks *spotify.FullTrackPage) ([]list.Item, error) {
 	items := []list.Item{}
 	for _, track := range tracks.Tracks {
-		items = append(items, mainItem{
+		item, ok := markUnplayable(mainItem{
 			Name:        track.Name,
 			Artist:      track.Artists[0],
 			Duration:    track.TimeDuration().Round(time.Second).String(),
 			ID:          track.ID,
 			SpotifyItem: track,
 			Desc:        track.Artists[0].Name + " - " + track.TimeDuration().Round(time.Second).String(),
-		})
+		}, track.IsPlayable, config.Values.HideUnplayable)
+		if ok {
+			items = append(items, item)
+		}
 	}
 	return items, nil
 }
@@ -232,24 +534,133 @@ func SavedTracksView(ctx *gctx.Context, commands *commands.Commands) ([]list.Ite
 		return nil, err
 	}
 	for _, track := range tracks.Tracks {
-		items = append(items, mainItem{
+		// saved tracks are played by position, so unplayable tracks stay listed
+		item, _ := markUnplayable(mainItem{
 			Name:        track.Name,
 			Artist:      track.Artists[0],
 			Duration:    track.TimeDuration().Round(time.Second).String(),
 			ID:          track.ID,
 			SpotifyItem: track,
 			Desc:        track.Artists[0].Name + " - " + track.TimeDuration().Round(time.Second).String(),
-		})
+		}, track.IsPlayable, false)
+		items = append(items, item)
 	}
 	return items, err
 }
 
-func MainView(ctx *gctx.Context, commands *commands.Commands) ([]list.Item, error) {
+// markUnplayable marks items spotify can not play in the user's market, ok is
+// false when they should be left out instead.
+func markUnplayable(item mainItem, isPlayable *bool, hide bool) (mainItem, bool) {
+	if isPlayable == nil || *isPlayable {
+		return item, true
+	}
+	if hide {
+		return item, false
+	}
+	item.Desc = "Unavailable in your market - " + item.Desc
+	return item, true
+}
+
+func ShowsView(ctx *gctx.Context, commands *commands.Commands) ([]list.Item, error) {
+	items := []list.Item{}
+	shows, err := commands.UserShows(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	for _, show := range shows.Shows {
+		items = append(items, showItem(show))
+	}
+	return items, nil
+}
+
+func showItem(show spotify.SavedShow) mainItem {
+	return mainItem{
+		Name:        show.Name,
+		ID:          show.ID,
+		Desc:        show.Publisher,
+		SpotifyItem: show.SimpleShow,
+	}
+}
+
+func ShowEpisodesView(ctx *gctx.Context, commands *commands.Commands, show spotify.SimpleShow) ([]list.Item, error) {
+	items := []list.Item{}
+	episodes, err := commands.ShowEpisodes(ctx, show.ID, 1)
+	if err != nil {
+		return nil, err
+	}
+	for _, episode := range episodes.Episodes {
+		items = append(items, episodeItem(commands, show, episode))
+	}
+	return items, nil
+}
+
+// episodeItem lists an episode, the show is filled in because episode
+// listings leave it out.
+func episodeItem(commands *commands.Commands, show spotify.SimpleShow, episode spotify.EpisodePage) mainItem {
+	episode.Show = show
+	return mainItem{
+		Name:        episode.Name,
+		ID:          episode.ID,
+		Duration:    (time.Duration(episode.Duration_ms) * time.Millisecond).Round(time.Second).String(),
+		Desc:        commands.FormatEpisode(&episode),
+		SpotifyItem: episode,
+	}
+}
+
+// savedAudiobooks is the main view entry for the saved audiobooks.
+type savedAudiobooks struct{}
+
+func AudiobooksView(ctx *gctx.Context, commands *commands.Commands) ([]list.Item, error) {
+	items := []list.Item{}
+	books, _, err := commands.UserAudiobooks(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	for _, book := range books {
+		items = append(items, audiobookItem(book))
+	}
+	return items, nil
+}
+
+func audiobookItem(book commands.SimpleAudiobook) mainItem {
+	return mainItem{
+		Name:        book.Name,
+		ID:          book.ID,
+		Desc:        fmt.Sprintf("%s, %d chapters", book.AuthorNames(), book.TotalChapters),
+		SpotifyItem: book,
+	}
+}
+
+func ChaptersView(ctx *gctx.Context, commands *commands.Commands, book spotify.ID) ([]list.Item, error) {
+	items := []list.Item{}
+	chapters, err := commands.AudiobookChapters(ctx, book, 1)
+	if err != nil {
+		return nil, err
+	}
+	for _, chapter := range chapters {
+		items = append(items, chapterItem(chapter))
+	}
+	return items, nil
+}
+
+func chapterItem(chapter commands.Chapter) mainItem {
+	return mainItem{
+		Name:        chapter.Name,
+		ID:          chapter.ID,
+		Duration:    (time.Duration(chapter.DurationMs) * time.Millisecond).Round(time.Second).String(),
+		Desc:        commands.FormatChapter(chapter),
+		SpotifyItem: chapter,
+	}
+}
+
+func MainView(ctx *gctx.Context, commands *commands.Commands, keys keyMap, searches *searchStore) ([]list.Item, error) {
 	wg := errgroup.Group{}
 	var saved_items *spotify.SavedTrackPage
 	var playlists *spotify.SimplePlaylistPage
 	var artists *spotify.FullArtistCursorPage
 	var albums *spotify.SavedAlbumPage
+	var shows *spotify.SavedShowPage
+	var audiobookTotal int
 
 	wg.Go(func() (err error) {
 		saved_items, err = commands.TrackList(ctx, 1)
@@ -262,7 +673,7 @@ func MainView(ctx *gctx.Context, commands *commands.Commands) ([]list.Item, erro
 	})
 
 	wg.Go(func() (err error) {
-		artists, err = commands.UserArtists(ctx, 1)
+		artists, err = commands.UserArtists(ctx, "")
 		return
 	})
 
@@ -271,6 +682,17 @@ func MainView(ctx *gctx.Context, commands *commands.Commands) ([]list.Item, erro
 		return
 	})
 
+	wg.Go(func() (err error) {
+		shows, err = commands.UserShows(ctx, 1)
+		return
+	})
+
+	wg.Go(func() error {
+		// audiobooks are not available in every market, leave them out then
+		_, audiobookTotal, _ = commands.UserAudiobooks(ctx, 1)
+		return nil
+	})
+
 	err := wg.Wait()
 	if err != nil {
 		return nil, err
@@ -298,11 +720,43 @@ func MainView(ctx *gctx.Context, commands *commands.Commands) ([]list.Item, erro
 			SpotifyItem: artists,
 		})
 	}
+	if shows != nil && shows.Total != 0 {
+		items = append(items, mainItem{
+			Name:        "Saved Shows",
+			Desc:        fmt.Sprintf("%d shows", shows.Total),
+			SpotifyItem: shows,
+		})
+	}
+	if audiobookTotal != 0 {
+		items = append(items, mainItem{
+			Name:        "Audiobooks",
+			Desc:        fmt.Sprintf("%d audiobooks", audiobookTotal),
+			SpotifyItem: savedAudiobooks{},
+		})
+	}
 	items = append(items, mainItem{
 		Name:        "Queue",
 		Desc:        "Your Current Queue",
 		SpotifyItem: spotify.Queue{},
 	})
+	if recent := searches.history(); len(recent) != 0 {
+		items = append(items, mainItem{
+			Name:        "Recent Searches",
+			Desc:        fmt.Sprintf("%d searches", len(recent)),
+			SpotifyItem: recentSearches{},
+		})
+	}
+	for idx, query := range searches.pinned() {
+		desc := "Pinned search"
+		if label := keys.pinnedLabel(idx + 1); label != "" {
+			desc = "Pinned search - press " + label + " to run"
+		}
+		items = append(items, mainItem{
+			Name:        query,
+			Desc:        desc,
+			SpotifyItem: searchQuery(query),
+		})
+	}
 	if playlists != nil && playlists.Total != 0 {
 		for _, playlist := range playlists.Playlists {
 			items = append(items, mainItem{
@@ -315,6 +769,22 @@ func MainView(ctx *gctx.Context, commands *commands.Commands) ([]list.Item, erro
 	return items, nil
 }
 
+func RecentSearchesView(searches *searchStore) []list.Item {
+	items := []list.Item{}
+	for _, query := range searches.history() {
+		desc := "Recent search"
+		if searches.isPinned(query) {
+			desc = "Pinned search"
+		}
+		items = append(items, mainItem{
+			Name:        query,
+			Desc:        desc,
+			SpotifyItem: searchQuery(query),
+		})
+	}
+	return items
+}
+
 func stripHtmlRegex(s string) string {
 	r := regexp.MustCompile(regex)
 	return r.ReplaceAllString(s, "")